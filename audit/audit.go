@@ -0,0 +1,92 @@
+// Package audit records client side events of credential access such as password checkout.
+// Events never contain the credential itself.
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Credential access actions
+const (
+	ActionCheckoutPassword  = "CheckoutPassword"
+	ActionCheckinPassword   = "CheckinPassword"
+	ActionExtendCheckout    = "ExtendCheckout"
+	ActionRetrieveSSHKey    = "RetrieveSSHKey"
+	ActionRetrieveAccessKey = "RetrieveAccessKey"
+	ActionCheckoutSecret    = "CheckoutSecret"
+	ActionDownloadSecret    = "DownloadSecretFile"
+	ActionRotatePassword    = "RotatePassword"
+)
+
+// Outcome of an action
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Event represents a single credential access
+type Event struct {
+	Time       time.Time `json:"time"`
+	Action     string    `json:"action"`
+	ObjectType string    `json:"objecttype"`
+	ObjectID   string    `json:"objectid,omitempty"`
+	ObjectName string    `json:"objectname,omitempty"`
+	Principal  string    `json:"principal,omitempty"`
+	Tenant     string    `json:"tenant,omitempty"`
+	COID       string    `json:"coid,omitempty"`
+	Outcome    string    `json:"outcome"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Sink receives audit events
+type Sink interface {
+	Record(e Event) error
+}
+
+// JSONLinesSink writes one JSON object per event
+type JSONLinesSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLinesSink creates sink that writes to w
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{w: w}
+}
+
+// NewJSONLinesFileSink creates sink that appends to file at path. Caller should Close it when done
+func NewJSONLinesFileSink(path string) (*JSONLinesSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLinesSink{w: f}, nil
+}
+
+// Record writes the event as a single line
+func (s *JSONLinesSink) Record(e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(data)
+	return err
+}
+
+// Close closes underlying writer if it is closable
+func (s *JSONLinesSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+// +build !windows,!plan9
+
+package audit
+
+import (
+	"encoding/json"
+	"log/syslog"
+)
+
+// SyslogSink sends each event as JSON to local syslog daemon
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink connects to local syslog daemon with facility AUTHPRIV and the given tag
+func NewSyslogSink(tag string) (*SyslogSink, error) {
+	w, err := syslog.New(syslog.LOG_AUTHPRIV|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSink{w: w}, nil
+}
+
+// Record sends the event. Failed actions are logged with warning priority
+func (s *SyslogSink) Record(e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if e.Outcome == OutcomeFailure {
+		return s.w.Warning(string(data))
+	}
+	return s.w.Info(string(data))
+}
+
+// Close closes connection to syslog daemon
+func (s *SyslogSink) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,23 @@
+// +build windows plan9
+
+package audit
+
+import "fmt"
+
+// SyslogSink isn't supported on this platform
+type SyslogSink struct{}
+
+// NewSyslogSink always returns error because syslog isn't available on this platform
+func NewSyslogSink(tag string) (*SyslogSink, error) {
+	return nil, fmt.Errorf("syslog is not supported on this platform")
+}
+
+// Record does nothing
+func (s *SyslogSink) Record(e Event) error {
+	return nil
+}
+
+// Close does nothing
+func (s *SyslogSink) Close() error {
+	return nil
+}
@@ -6,19 +6,24 @@ import (
 	"os"
 	"strings"
 	"syscall"
+	"time"
 
-	"github.com/marcozj/golang-sdk/enum/authenticationtype"
 	"github.com/marcozj/golang-sdk/utils"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
+// envKeyPassphrase is environment variable that passphrase of SSH key is read from
+const envKeyPassphrase = "CENTRIFY_KEY_PASSPHRASE"
+
 // getCmdParms parse command line argument
 func getCmdParms(c *utils.VaultClient, p *CliParameters) {
 	// Common arguments
-	authTypePtr := flag.String("auth", "oauth", "Authentication type <oauth|unpw|dmc>")
-	urlPtr := flag.String("url", "", "Centrify tenant URL (Required)")
+	authTypePtr := flag.String("auth", "", "Authentication type <oauth|unpw|dmc>. Defaults to oauth if it isn't set in environment or profile")
+	urlPtr := flag.String("url", "", "Centrify tenant URL (Required unless it is set in environment or profile)")
 	skipCertPtr := flag.Bool("skipcert", false, "Ignore certification verification")
 	debugPtr := flag.Bool("debug", false, "Trun on debug logging")
+	profilePtr := flag.String("profile", "", "Named profile in configuration file ~/.centrify/config")
+	configPtr := flag.String("config", "", "Path of configuration file. Defaults to ~/.centrify/config")
 
 	// Other arguments
 	appIDPtr := flag.String("appid", "", "OAuth2 application ID. Required if auth = oauth")
@@ -26,8 +31,13 @@ func getCmdParms(c *utils.VaultClient, p *CliParameters) {
 	tokenPtr := flag.String("token", "", "OAuth2 or DMC token. Optional if auth = oauth or dmc")
 	usernamePtr := flag.String("user", "", "Authorized user to login to tenant. Required if auth = unpw. Optional if auth = oauth")
 	passwordPtr := flag.String("password", "", "User password. You will be prompted to enter password if this isn't provided")
+	dmcCachePtr := flag.String("dmccache", "", "File to share cached DMC tokens between processes. Optional if auth = dmc")
 	credPathPtr := flag.String("credpath", "", "Path of the secret/pasword to be retrieved.")
 	saveToHomePtr := flag.Bool("savetohome", false, "Save downloaded secret file to user's home directory instead of current directory")
+	sshAgentPtr := flag.Bool("sshagent", false, "Load SSH key of account or sshkey into ssh-agent on SSH_AUTH_SOCK instead of printing it")
+	keyPassphrasePtr := flag.Bool("keypassphrase", false, "SSH key is encrypted. Passphrase is read from "+envKeyPassphrase+" or prompted for")
+	lifetimePtr := flag.Duration("lifetime", 0, "How long ssh-agent keeps the key, e.g. 30m. Account key is never kept longer than its checkout, which lasts checkout lifetime of account")
+	holdPtr := flag.Bool("hold", false, "Keep running until interrupted or key expires, then remove key from ssh-agent and check in account")
 
 	prgname := os.Args[0]
 	flag.Usage = func() {
@@ -35,6 +45,8 @@ func getCmdParms(c *utils.VaultClient, p *CliParameters) {
 		fmt.Printf("Usage: %s -auth oauth -token <oauthtoken> -url https://tenant.my.centrify.net -credpath \"secret/folder1\\folder2/secretname\"\n", prgname)
 		fmt.Printf("Usage: %s -auth oauth -url https://tenant.my.centrify.net -appid <appid> -scope <scope> -user <username> -credpath \"secret/folder1/secretname\"\n", prgname)
 		fmt.Printf("Usage: %s -auth unpw -url https://tenant.my.centrify.net -user <username> -credpath \"cloudprovider/My AWS/iamaccount/accesskeyid\"\n", prgname)
+		fmt.Printf("Usage: %s -profile <profile name> -credpath \"system/systemname/accountname\"\n", prgname)
+		fmt.Printf("Usage: %s -profile <profile name> -sshagent -lifetime 30m -credpath \"sshkey/keyname\"\n", prgname)
 		flag.PrintDefaults()
 	}
 
@@ -46,64 +58,13 @@ func getCmdParms(c *utils.VaultClient, p *CliParameters) {
 		os.Exit(1)
 	}
 
-	// Verify authTypePtr value
-	authChoices := map[string]bool{"oauth": true, "unpw": true, "dmc": true}
-	if _, validChoice := authChoices[*authTypePtr]; !validChoice {
-		flag.Usage()
-		os.Exit(1)
-	}
-	// Check required argument that do not have default value
-	if *urlPtr == "" {
-		flag.Usage()
-		os.Exit(1)
-	}
-
 	if *credPathPtr == "" {
 		fmt.Print("Missing -credpath vaule")
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	switch strings.ToLower(*authTypePtr) {
-	case authenticationtype.OAuth2.String():
-		if (*appIDPtr == "" || *scopePtr == "") && *tokenPtr == "" {
-			flag.Usage()
-			os.Exit(1)
-		}
-		// Either token or username must be provided
-		if *tokenPtr == "" && *usernamePtr == "" {
-			flag.Usage()
-			os.Exit(1)
-		}
-		// If password isn't provided, prompt for it
-		if *passwordPtr == "" && *tokenPtr == "" {
-			fmt.Print("Enter Password: ")
-			bytePassword, _ := terminal.ReadPassword(int(syscall.Stdin))
-			password := strings.TrimSpace(string(bytePassword))
-			*passwordPtr = password
-			fmt.Println()
-		}
-	case authenticationtype.UsernamePassword.String():
-		if *urlPtr == "" || *usernamePtr == "" {
-			flag.Usage()
-			os.Exit(1)
-		}
-		// If password isn't provided, prompt for it
-		if *passwordPtr == "" {
-			fmt.Print("Enter Password: ")
-			bytePassword, _ := terminal.ReadPassword(int(syscall.Stdin))
-			password := strings.TrimSpace(string(bytePassword))
-			*passwordPtr = password
-			fmt.Println()
-		}
-	case authenticationtype.DelegatedMachineCredential.String():
-		if *tokenPtr == "" && *scopePtr == "" {
-			flag.Usage()
-			os.Exit(1)
-		}
-	}
-
-	// Assign argument values to struct
+	// Assign explicit argument values to struct
 	c.AuthType = *authTypePtr
 	c.URL = *urlPtr
 	c.AppID = *appIDPtr
@@ -113,6 +74,45 @@ func getCmdParms(c *utils.VaultClient, p *CliParameters) {
 	c.Password = *passwordPtr
 	c.Skipcert = *skipCertPtr
 	c.Debug = *debugPtr
+	c.Profile = *profilePtr
+	c.ConfigFile = *configPtr
+	c.DMCCache = *dmcCachePtr
 	p.CredentialPath = *credPathPtr
 	p.SaveToHome = *saveToHomePtr
+	p.SSHAgent = *sshAgentPtr
+	p.KeyPassphrase = os.Getenv(envKeyPassphrase)
+	if *keyPassphrasePtr && p.KeyPassphrase == "" {
+		if !terminal.IsTerminal(int(syscall.Stdin)) {
+			fmt.Printf("Set %s to provide passphrase of SSH key", envKeyPassphrase)
+			os.Exit(1)
+		}
+		p.KeyPassphrase = promptPassphrase("Enter SSH Key Passphrase: ")
+	}
+	p.Lifetime = *lifetimePtr
+	p.Hold = *holdPtr
+	if p.Hold && !p.SSHAgent {
+		fmt.Print("-hold is only applicable with -sshagent")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if p.Lifetime < 0 || p.Lifetime > time.Duration(maxLifetimeSecs)*time.Second {
+		fmt.Print("Invalid -lifetime value")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// Fill in the rest from environment, profile, DMC or prompt
+	err := c.ResolveSettings()
+	if err != nil {
+		fmt.Println(err)
+		flag.Usage()
+		os.Exit(1)
+	}
+}
+
+func promptPassphrase(prompt string) string {
+	fmt.Print(prompt)
+	bytePassphrase, _ := terminal.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	return strings.TrimSpace(string(bytePassphrase))
 }
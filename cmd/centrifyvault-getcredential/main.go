@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/marcozj/golang-sdk/enum/keypairtype"
 	"github.com/marcozj/golang-sdk/enum/resourcetype"
 	"github.com/marcozj/golang-sdk/platform"
+	"github.com/marcozj/golang-sdk/sshagent"
 	"github.com/marcozj/golang-sdk/utils"
 )
 
@@ -14,6 +17,10 @@ import (
 type CliParameters struct {
 	CredentialPath string
 	SaveToHome     bool
+	SSHAgent       bool
+	KeyPassphrase  string
+	Lifetime       time.Duration
+	Hold           bool
 }
 
 type vaultObject struct {
@@ -53,6 +60,16 @@ func main() {
 		acct.User = vo.secretName
 		acct.ResourceName = vo.resourceName
 		acct.ResourceType = vo.resourceType
+		if pars.SSHAgent {
+			err = loadToAgent(pars, func(a *sshagent.Agent, opts sshagent.Options) (*sshagent.Key, error) {
+				return a.AddAccountKey(acct, pars.KeyPassphrase, opts)
+			})
+			if err != nil {
+				fmt.Println("Error: ", err)
+				os.Exit(1)
+			}
+			return
+		}
 		// Checkout password
 		pw, err := acct.CheckoutPassword(false)
 		if err != nil {
@@ -82,6 +99,28 @@ func main() {
 			os.Exit(1)
 		}
 		fmt.Print(secrettext)
+	case "sshkey":
+		key := platform.NewSSHKey(client)
+		key.Name = vo.secretName
+		key.Passphrase = pars.KeyPassphrase
+		if pars.SSHAgent {
+			err = loadToAgent(pars, func(a *sshagent.Agent, opts sshagent.Options) (*sshagent.Key, error) {
+				return a.AddSSHKey(key, opts)
+			})
+			if err != nil {
+				fmt.Println("Error: ", err)
+				os.Exit(1)
+			}
+			return
+		}
+		// Retrieve private key
+		key.KeyPairType = keypairtype.PrivateKey.String()
+		thekey, err := key.RetriveSSHKey()
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		fmt.Print(thekey)
 	}
 }
 
@@ -112,6 +151,15 @@ func getVaultObject(credPath string) (*vaultObject, error) {
 		if vo.resourceName == "" || vo.secretName == "" || vo.accesskeyID == "" {
 			return nil, fmt.Errorf("invalid credential path %s", credPath)
 		}
+	case "sshkey":
+		// Handle SSH key
+		// Credential path format should be "sshkey/keyname"
+		if splitLength > 1 {
+			vo.secretName = strings.Join(credparts[1:], "/")
+		}
+		if vo.secretName == "" {
+			return nil, fmt.Errorf("invalid credential path %s", credPath)
+		}
 	case "secret":
 		// Handle secret
 		if splitLength > 1 {
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/marcozj/golang-sdk/sshagent"
+)
+
+// maxLifetimeSecs is the largest lifetime constraint ssh-agent protocol can carry
+const maxLifetimeSecs = 1<<32 - 1
+
+// loadToAgent loads SSH key into ssh-agent with add instead of printing it. With -hold it waits until it is
+// interrupted or key expires and then removes the key and checks in account, otherwise ssh-agent drops the key when
+// lifetime ends, which is when checkout expires
+func loadToAgent(pars *CliParameters, add func(*sshagent.Agent, sshagent.Options) (*sshagent.Key, error)) error {
+	a, err := sshagent.Connect()
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	key, err := add(a, sshagent.Options{Lifetime: pars.Lifetime})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Added %s %s to ssh-agent until %s\n", key.Fingerprint(), key.Comment, key.Expires().Format(time.RFC3339))
+	if !pars.Hold {
+		return nil
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	timer := time.NewTimer(time.Until(key.Expires()))
+	defer timer.Stop()
+	select {
+	case <-sig:
+	case <-timer.C:
+	}
+
+	err = key.Remove()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s from ssh-agent\n", key.Fingerprint())
+	return nil
+}
@@ -10,6 +10,9 @@ import (
 	"reflect"
 )
 
+// defaultSocketFile is the unix socket Centrify Client service listens on
+const defaultSocketFile = "/var/centrify/cloud/daemon2"
+
 // LRPC2 represents local RPC data structure
 type LRPC2 struct {
 	unixSocketFile   string
@@ -21,11 +24,17 @@ type LRPC2 struct {
 // NewLRPC2 initiates a new local RPC client
 func NewLRPC2() *LRPC2 {
 	lrpc := LRPC2{}
-	lrpc.unixSocketFile = "/var/centrify/cloud/daemon2"
+	lrpc.unixSocketFile = defaultSocketFile
 
 	return &lrpc
 }
 
+// IsAvailable checks whether Centrify Client service socket exists on this machine
+func IsAvailable() bool {
+	fi, err := os.Stat(defaultSocketFile)
+	return err == nil && fi.Mode()&os.ModeSocket != 0
+}
+
 func (lrpc *LRPC2) reader(size int) ([]byte, error) {
 	buf := make([]byte, size)
 	_, err := lrpc.client.Read(buf[:])
@@ -5,12 +5,16 @@ package dmc
 // This file is for Windows platform
 import (
 	"fmt"
+	"os"
 	"reflect"
 
 	// npipe package only works on Windows
 	"gopkg.in/natefinch/npipe.v2"
 )
 
+// defaultNamedPipe is the named pipe Centrify Client service listens on
+const defaultNamedPipe = `\\.\pipe\cagent_admins`
+
 // LRPC2 represents local RPC data structure
 type LRPC2 struct {
 	winNamePipe      string
@@ -22,11 +26,17 @@ type LRPC2 struct {
 // NewLRPC2 initiates a new local RPC client
 func NewLRPC2() *LRPC2 {
 	lrpc := LRPC2{}
-	lrpc.winNamePipe = `\\.\pipe\cagent_admins`
+	lrpc.winNamePipe = defaultNamedPipe
 
 	return &lrpc
 }
 
+// IsAvailable checks whether Centrify Client service named pipe exists on this machine
+func IsAvailable() bool {
+	_, err := os.Stat(defaultNamedPipe)
+	return err == nil
+}
+
 func (lrpc *LRPC2) reader(size int) ([]byte, error) {
 	buf := make([]byte, size)
 	_, err := lrpc.client.Read(buf[:])
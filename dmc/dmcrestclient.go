@@ -3,6 +3,8 @@ package dmc
 import (
 	"crypto/tls"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/marcozj/golang-sdk/restapi"
 )
@@ -13,6 +15,10 @@ type DMC struct {
 	Scope          string // Delegated Machine Credential scope definition
 	Token          string // DMC Oauth token. If this is provided, then no need to make LRPC call
 	SkipCertVerify bool
+	CacheFile      string // Optional file to share cached tokens between processes. It must only be accessible by its owner
+	DisableCache   bool   // Always request a new token from Centrify Client instead of reusing cached one
+
+	tokenFromCache bool // Token was obtained by GetClient rather than provided by caller
 }
 
 // GetClient creates REST client
@@ -30,13 +36,17 @@ func (c *DMC) GetClient() (*restapi.RestClient, error) {
 		}
 	}
 
-	if c.Token == "" {
-		rpc := NewLRPC2()
-		token, err := rpc.GetToken(c.Scope)
+	// Token provided by caller is used as is. Otherwise client keeps its own token until it is about to expire or
+	// is rejected, so that Centrify Client is only asked again when needed even if cache is disabled
+	var held *heldToken
+	if c.Token == "" || c.tokenFromCache {
+		held = &heldToken{fetch: c.getToken, invalidate: c.invalidateToken}
+		token, err := held.get()
 		if err != nil {
 			return nil, err
 		}
 		c.Token = token
+		c.tokenFromCache = true
 	}
 
 	restClient, err := restapi.GetNewRestClient(c.Service, clientFactory)
@@ -45,5 +55,63 @@ func (c *DMC) GetClient() (*restapi.RestClient, error) {
 	}
 
 	restClient.Headers["Authorization"] = "Bearer " + c.Token
+	if held != nil {
+		// Token is renewed when it is about to expire or rejected so long running client keeps working
+		restClient.TokenSource = held.get
+		restClient.InvalidateToken = held.reset
+	}
+	restClient.Logger = c.Logger
+	restClient.Principal = restapi.PrincipalFromToken(c.Token)
 	return restClient, nil
 }
+
+// getToken gets DMC token from token cache or directly from Centrify Client service if cache is disabled
+func (c *DMC) getToken() (string, error) {
+	if c.DisableCache {
+		return NewLRPC2().GetToken(c.Scope)
+	}
+	return GetTokenCache(c.CacheFile).getToken(c.Scope, c.Log())
+}
+
+// invalidateToken drops rejected token from token cache
+func (c *DMC) invalidateToken() {
+	if !c.DisableCache {
+		GetTokenCache(c.CacheFile).Invalidate(c.Scope)
+	}
+}
+
+// heldToken is DMC token of a single client. It is reused until it is within DefaultRenewBefore of its expiry, or
+// until it is rejected if its expiry is unknown
+type heldToken struct {
+	fetch      func() (string, error)
+	invalidate func()
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// get returns held token, fetching a new one if there is none or it is about to expire
+func (t *heldToken) get() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && (t.expiry.IsZero() || time.Now().Add(DefaultRenewBefore).Before(t.expiry)) {
+		return t.token, nil
+	}
+	token, err := t.fetch()
+	if err != nil {
+		return "", err
+	}
+	t.token = token
+	t.expiry, _ = tokenExpiry(token)
+	return token, nil
+}
+
+// reset drops held token after tenant rejected it so that next get fetches a new one
+func (t *heldToken) reset() {
+	t.mu.Lock()
+	t.token = ""
+	t.mu.Unlock()
+	t.invalidate()
+}
@@ -0,0 +1,238 @@
+package dmc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	logger "github.com/marcozj/golang-sdk/logging"
+)
+
+// DefaultRenewBefore is how long before expiry a cached token is considered stale and renewed
+const DefaultRenewBefore = 5 * time.Minute
+
+var (
+	cachesMu sync.Mutex
+	caches   = make(map[string]*TokenCache)
+)
+
+// TokenCache caches DMC tokens per scope so that they are reused until they are close to expiry.
+// Tokens are always kept in memory. If FilePath is set, they are also persisted into that file so that
+// other processes running as the same user can reuse them.
+type TokenCache struct {
+	FilePath    string        // Optional cache file. It must be owned by current user and not accessible by anyone else
+	RenewBefore time.Duration // Token is renewed when it expires within this duration
+
+	mu     sync.Mutex
+	tokens map[string]cachedToken
+	fetch  func(scope string) (string, error)
+}
+
+// cachedToken represents a single cache entry
+type cachedToken struct {
+	Token  string    `json:"token"`
+	Expiry time.Time `json:"expiry"`
+}
+
+// NewTokenCache creates a token cache. filePath is optional
+func NewTokenCache(filePath string) *TokenCache {
+	tc := TokenCache{}
+	tc.FilePath = filePath
+	tc.RenewBefore = DefaultRenewBefore
+	tc.tokens = make(map[string]cachedToken)
+	tc.fetch = func(scope string) (string, error) {
+		return NewLRPC2().GetToken(scope)
+	}
+
+	return &tc
+}
+
+// GetTokenCache returns the process wide token cache for the given cache file. Empty filePath means memory only cache
+func GetTokenCache(filePath string) *TokenCache {
+	cachesMu.Lock()
+	defer cachesMu.Unlock()
+
+	tc, ok := caches[filePath]
+	if !ok {
+		tc = NewTokenCache(filePath)
+		caches[filePath] = tc
+	}
+	return tc
+}
+
+// GetToken returns a valid token for the scope, either from cache or newly requested from Centrify Client service
+func (tc *TokenCache) GetToken(scope string) (string, error) {
+	return tc.getToken(scope, logger.Printer{Handler: logger.Default()})
+}
+
+// getToken is GetToken that logs to log, which is logger of the client asking for the token
+func (tc *TokenCache) getToken(scope string, log logger.Printer) (string, error) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if t, ok := tc.tokens[scope]; ok && tc.isValid(t) {
+		log.Debugf("Reusing cached DMC token for scope %s that expires at %s", scope, t.Expiry)
+		return t.Token, nil
+	}
+
+	if tc.FilePath != "" {
+		tokens, err := tc.load()
+		if err != nil {
+			log.Errorf("Ignoring DMC token cache file: %v", err)
+		} else if t, ok := tokens[scope]; ok && tc.isValid(t) {
+			log.Debugf("Reusing DMC token for scope %s from cache file %s", scope, tc.FilePath)
+			tc.tokens[scope] = t
+			return t.Token, nil
+		}
+	}
+
+	token, err := tc.fetch(scope)
+	if err != nil {
+		return "", err
+	}
+
+	expiry, err := tokenExpiry(token)
+	if err != nil {
+		// Still hand out the token but don't cache something whose lifetime is unknown
+		log.Errorf("Unable to cache DMC token: %v", err)
+		return token, nil
+	}
+	t := cachedToken{Token: token, Expiry: expiry}
+	tc.tokens[scope] = t
+	log.Debugf("Obtained new DMC token for scope %s that expires at %s", scope, expiry)
+
+	if tc.FilePath != "" {
+		err = tc.save(scope, t)
+		if err != nil {
+			log.Errorf("Failed to save DMC token cache file: %v", err)
+		}
+	}
+
+	return token, nil
+}
+
+// Invalidate removes token of the scope from cache so that the next GetToken call requests a new one
+func (tc *TokenCache) Invalidate(scope string) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	delete(tc.tokens, scope)
+	if tc.FilePath != "" {
+		tokens, err := tc.load()
+		if err != nil {
+			return
+		}
+		if _, ok := tokens[scope]; ok {
+			delete(tokens, scope)
+			tc.write(tokens)
+		}
+	}
+}
+
+func (tc *TokenCache) isValid(t cachedToken) bool {
+	return t.Token != "" && time.Now().Add(tc.RenewBefore).Before(t.Expiry)
+}
+
+// load reads all cached tokens from cache file
+func (tc *TokenCache) load() (map[string]cachedToken, error) {
+	var tokens = make(map[string]cachedToken)
+	fi, err := os.Stat(tc.FilePath)
+	if os.IsNotExist(err) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = checkCacheFile(fi)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", tc.FilePath, err)
+	}
+
+	data, err := ioutil.ReadFile(tc.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return tokens, nil
+	}
+	err = json.Unmarshal(data, &tokens)
+	if err != nil {
+		return nil, fmt.Errorf("error demarshalling token cache file '%s': %s", tc.FilePath, err)
+	}
+
+	return tokens, nil
+}
+
+// save merges token into cache file. Expired entries are dropped along the way
+func (tc *TokenCache) save(scope string, t cachedToken) error {
+	tokens, err := tc.load()
+	if err != nil {
+		return err
+	}
+	for k, v := range tokens {
+		if time.Now().After(v.Expiry) {
+			delete(tokens, k)
+		}
+	}
+	tokens[scope] = t
+
+	return tc.write(tokens)
+}
+
+// write replaces cache file atomically. The file is created with 0600 permission
+func (tc *TokenCache) write(tokens map[string]cachedToken) error {
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(tc.FilePath), filepath.Base(tc.FilePath)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = tmp.Chmod(0600)
+	if err == nil {
+		_, err = tmp.Write(data)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), tc.FilePath)
+}
+
+// tokenExpiry decodes exp claim of JWT token. Signature isn't verified since the token comes from local Centrify Client
+func tokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid JWT payload encoding: %v", err)
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid JWT payload: %v", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, fmt.Errorf("JWT doesn't contain exp claim")
+	}
+
+	return time.Unix(claims.Exp, 0), nil
+}
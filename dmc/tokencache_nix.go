@@ -0,0 +1,23 @@
+// +build !windows
+
+package dmc
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkCacheFile makes sure token cache file is owned by current user and isn't accessible by group or others
+func checkCacheFile(fi os.FileInfo) error {
+	if fi.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("permission %v is too open, it must be 0600", fi.Mode().Perm())
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		if int(st.Uid) != os.Geteuid() {
+			return fmt.Errorf("file is owned by uid %d instead of %d", st.Uid, os.Geteuid())
+		}
+	}
+
+	return nil
+}
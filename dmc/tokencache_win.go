@@ -0,0 +1,12 @@
+// +build windows
+
+package dmc
+
+import (
+	"os"
+)
+
+// checkCacheFile is a no-op on Windows. Access to token cache file relies on ACL of its parent folder
+func checkCacheFile(fi os.FileInfo) error {
+	return nil
+}
@@ -29,3 +29,12 @@ func (r AuthenticationMechanism) String() string {
 
 	return names[r]
 }
+
+// Values returns string value of every AuthenticationMechanism
+func Values() []string {
+	var values []string
+	for r := Password; r <= SecurityQuestions; r++ {
+		values = append(values, r.String())
+	}
+	return values
+}
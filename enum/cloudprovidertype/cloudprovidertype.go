@@ -13,3 +13,12 @@ func (r CloudProviderType) String() string {
 
 	return names[r]
 }
+
+// Values returns string value of every CloudProviderType
+func Values() []string {
+	var values []string
+	for r := AWS; r <= AWS; r++ {
+		values = append(values, r.String())
+	}
+	return values
+}
@@ -39,3 +39,12 @@ func (r ComputerClass) String() string {
 
 	return names[r]
 }
+
+// Values returns string value of every ComputerClass
+func Values() []string {
+	var values []string
+	for r := Windows; r <= CustomSSH; r++ {
+		values = append(values, r.String())
+	}
+	return values
+}
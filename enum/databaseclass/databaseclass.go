@@ -17,3 +17,12 @@ func (r DatabaseClass) String() string {
 
 	return names[r]
 }
+
+// Values returns string value of every DatabaseClass
+func Values() []string {
+	var values []string
+	for r := SQLServer; r <= SAPASE; r++ {
+		values = append(values, r.String())
+	}
+	return values
+}
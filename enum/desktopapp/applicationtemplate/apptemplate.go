@@ -19,3 +19,12 @@ func (r ApplicationTemplate) String() string {
 
 	return names[r]
 }
+
+// Values returns string value of every ApplicationTemplate
+func Values() []string {
+	var values []string
+	for r := Generic; r <= VSphereClient; r++ {
+		values = append(values, r.String())
+	}
+	return values
+}
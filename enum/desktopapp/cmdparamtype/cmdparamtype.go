@@ -41,3 +41,12 @@ func (r CmdParmType) String() string {
 
 	return names[r]
 }
+
+// Values returns string value of every CmdParmType
+func Values() []string {
+	var values []string
+	for r := Integer; r <= User; r++ {
+		values = append(values, r.String())
+	}
+	return values
+}
@@ -19,3 +19,12 @@ func (r HostLoginCredentials) String() string {
 
 	return names[r]
 }
+
+// Values returns string value of every HostLoginCredentials
+func Values() []string {
+	var values []string
+	for r := UserADCredential; r <= SharedAccount; r++ {
+		values = append(values, r.String())
+	}
+	return values
+}
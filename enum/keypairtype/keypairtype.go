@@ -22,3 +22,12 @@ func (keytype KeyPairType) String() string {
 		return "PrivateKey"
 	}
 }
+
+// Values returns string value of every KeyPairType
+func Values() []string {
+	var values []string
+	for r := PublicKey; r <= PuTTY; r++ {
+		values = append(values, r.String())
+	}
+	return values
+}
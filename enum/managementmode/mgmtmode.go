@@ -23,3 +23,13 @@ func (r ManagementMode) String() string {
 
 	return names[r]
 }
+
+// Values returns string value of every ManagementMode. Unknown is included since tenant reports it for systems
+// whose management mode isn't set
+func Values() []string {
+	var values []string
+	for r := Unknown; r <= Disabled; r++ {
+		values = append(values, r.String())
+	}
+	return values
+}
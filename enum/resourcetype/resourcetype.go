@@ -19,3 +19,12 @@ func (r ResourceType) String() string {
 
 	return names[r]
 }
+
+// Values returns string value of every ResourceType
+func Values() []string {
+	var values []string
+	for r := System; r <= CloudProvider; r++ {
+		values = append(values, r.String())
+	}
+	return values
+}
@@ -15,3 +15,12 @@ func (r SecretType) String() string {
 
 	return names[r]
 }
+
+// Values returns string value of every SecretType
+func Values() []string {
+	var values []string
+	for r := Text; r <= File; r++ {
+		values = append(values, r.String())
+	}
+	return values
+}
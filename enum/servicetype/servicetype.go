@@ -17,3 +17,12 @@ func (r WindowsServiceType) String() string {
 
 	return names[r]
 }
+
+// Values returns string value of every WindowsServiceType
+func Values() []string {
+	var values []string
+	for r := WindowsService; r <= IISApplicationPool; r++ {
+		values = append(values, r.String())
+	}
+	return values
+}
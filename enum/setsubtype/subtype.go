@@ -15,3 +15,12 @@ func (r SetSubtype) String() string {
 
 	return names[r]
 }
+
+// Values returns string value of every SetSubtype
+func Values() []string {
+	var values []string
+	for r := Web; r <= Desktop; r++ {
+		values = append(values, r.String())
+	}
+	return values
+}
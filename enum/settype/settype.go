@@ -31,3 +31,12 @@ func (r SetType) String() string {
 
 	return names[r]
 }
+
+// Values returns string value of every SetType
+func Values() []string {
+	var values []string
+	for r := System; r <= CloudProvider; r++ {
+		values = append(values, r.String())
+	}
+	return values
+}
@@ -19,3 +19,12 @@ func (r AccountMapping) String() string {
 
 	return names[r]
 }
+
+// Values returns string value of every AccountMapping
+func Values() []string {
+	var values []string
+	for r := ADAttribute; r <= SetByUser; r++ {
+		values = append(values, r.String())
+	}
+	return values
+}
@@ -16,3 +16,12 @@ func (r TokenType) String() string {
 
 	return names[r]
 }
+
+// Values returns string value of every TokenType
+func Values() []string {
+	var values []string
+	for r := JwtRS256; r <= Opaque; r++ {
+		values = append(values, r.String())
+	}
+	return values
+}
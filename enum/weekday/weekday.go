@@ -29,3 +29,12 @@ func (day Weekday) String() string {
 	// return the name of a Weekday constant from the names array above.
 	return names[day]
 }
+
+// Values returns string value of every Weekday
+func Values() []string {
+	var values []string
+	for r := Sunday; r <= Saturday; r++ {
+		values = append(values, r.String())
+	}
+	return values
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/marcozj/golang-sdk/examples"
+	"github.com/marcozj/golang-sdk/platform"
+)
+
+// Usage: drift <bundle.yaml>
+// Exits with 0 if tenant matches the definitions, 1 if anything drifted and 2 on error
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println("Usage: drift <bundle.yaml>")
+		os.Exit(platform.DriftExitError)
+	}
+
+	// Authenticate and returns authenticated REST client
+	client, err := examples.GetClient()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(platform.DriftExitError)
+	}
+
+	f, err := os.Open(os.Args[1])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(platform.DriftExitError)
+	}
+	defer f.Close()
+	bundle, err := platform.ReadBundle(f)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(platform.DriftExitError)
+	}
+
+	// Set membership is checked separately
+	opts := platform.DriftOptions{
+		Ignore: []string{"sets"},
+	}
+	report := platform.CheckBundleDrift(client, bundle, opts)
+	fmt.Print(report)
+	os.Exit(report.ExitCode())
+}
@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// StreamLogger writes log records to an io.Writer in either text or JSON format
+type StreamLogger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	level  Level
+	format func(t time.Time, level Level, msg string, keyvals []interface{}) []byte
+}
+
+// NewTextLogger creates logger that writes records such as
+//
+//	2021-09-07T10:00:00Z [ERROR] Query returns 0 object caller="helper.go:230 queryVaultObject()"
+func NewTextLogger(w io.Writer, level Level) *StreamLogger {
+	return &StreamLogger{w: w, level: level, format: formatText}
+}
+
+// NewJSONLogger creates logger that writes one JSON object per record such as
+//
+//	{"time":"2021-09-07T10:00:00Z","level":"ERROR","msg":"Query returns 0 object","caller":"helper.go:230 queryVaultObject()"}
+func NewJSONLogger(w io.Writer, level Level) *StreamLogger {
+	return &StreamLogger{w: w, level: level, format: formatJSON}
+}
+
+// SetLevel sets log level, any log level less than it will not log
+func (l *StreamLogger) SetLevel(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
+}
+
+// SetOutput changes log destination
+func (l *StreamLogger) SetOutput(w io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w = w
+}
+
+// Debug records the log with debug level
+func (l *StreamLogger) Debug(msg string, keyvals ...interface{}) {
+	l.output(LevelDebug, msg, keyvals)
+}
+
+// Info records the log with info level
+func (l *StreamLogger) Info(msg string, keyvals ...interface{}) {
+	l.output(LevelInfo, msg, keyvals)
+}
+
+// Warn records the log with warning level
+func (l *StreamLogger) Warn(msg string, keyvals ...interface{}) {
+	l.output(LevelWarn, msg, keyvals)
+}
+
+// Error records the log with error level
+func (l *StreamLogger) Error(msg string, keyvals ...interface{}) {
+	l.output(LevelError, msg, keyvals)
+}
+
+func (l *StreamLogger) output(level Level, msg string, keyvals []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.level.enabled(level) || l.w == nil {
+		return
+	}
+	l.w.Write(l.format(time.Now(), level, msg, keyvals))
+}
+
+func formatText(t time.Time, level Level, msg string, keyvals []interface{}) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(t.Format(time.RFC3339))
+	buf.WriteString(" [")
+	buf.WriteString(level.String())
+	buf.WriteString("] ")
+	buf.WriteString(msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		key, value := keyValue(keyvals, i)
+		buf.WriteString(" ")
+		buf.WriteString(key)
+		buf.WriteString("=")
+		str := fmt.Sprintf("%+v", value)
+		if str == "" || bytes.ContainsAny([]byte(str), " =\"\n\t") {
+			str = strconv.Quote(str)
+		}
+		buf.WriteString(str)
+	}
+	buf.WriteString("\n")
+
+	return buf.Bytes()
+}
+
+func formatJSON(t time.Time, level Level, msg string, keyvals []interface{}) []byte {
+	record := make(map[string]interface{})
+	for i := 0; i < len(keyvals); i += 2 {
+		key, value := keyValue(keyvals, i)
+		switch v := value.(type) {
+		case error:
+			record[key] = v.Error()
+		case fmt.Stringer:
+			record[key] = v.String()
+		default:
+			record[key] = v
+		}
+	}
+	record["time"] = t.Format(time.RFC3339)
+	record["level"] = level.String()
+	record["msg"] = msg
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		// Value isn't JSON friendly so fall back to its string representation
+		for k, v := range record {
+			record[k] = fmt.Sprintf("%+v", v)
+		}
+		data, _ = json.Marshal(record)
+	}
+
+	return append(data, '\n')
+}
+
+// keyValue returns key value pair at position i. A dangling key gets "(MISSING)" value
+func keyValue(keyvals []interface{}, i int) (string, interface{}) {
+	key := fmt.Sprintf("%v", keyvals[i])
+	if i+1 < len(keyvals) {
+		return key, keyvals[i+1]
+	}
+	return key, "(MISSING)"
+}
+
+// nopLogger discards everything
+type nopLogger struct{}
+
+// NewNopLogger creates logger that discards all records
+func NewNopLogger() Handler {
+	return nopLogger{}
+}
+
+func (nopLogger) Debug(msg string, keyvals ...interface{}) {}
+func (nopLogger) Info(msg string, keyvals ...interface{})  {}
+func (nopLogger) Warn(msg string, keyvals ...interface{})  {}
+func (nopLogger) Error(msg string, keyvals ...interface{}) {}
@@ -0,0 +1,142 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Logger represents logging object of earlier releases. It writes text records to stderr or to log file set by
+// SetLogPath, which is opened at first record and kept open. It implements Handler so it can still be passed to
+// clients or SetDefault.
+//
+// Deprecated: Use a Handler such as NewTextLogger or NewJSONLogger, and Printer for printf style methods
+type Logger struct {
+	mu              sync.Mutex
+	level           Level
+	logpath         string
+	logfile         *os.File
+	errorstacktrace bool
+}
+
+// NewLogger creates default logger
+//
+// Deprecated: Use NewTextLogger
+func NewLogger() *Logger {
+	var l = new(Logger)
+	l.level = LevelError
+
+	return l
+}
+
+// SetLevel sets log level, any log level less than it will not log
+func (l *Logger) SetLevel(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
+}
+
+// SetLogPath sets log file name. File of previous path is closed
+func (l *Logger) SetLogPath(logfile string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.logfile != nil {
+		l.logfile.Close()
+		l.logfile = nil
+	}
+	l.logpath = logfile
+}
+
+// EnableErrorStackTrace enables stack trace for ErrorTracef method
+func (l *Logger) EnableErrorStackTrace() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errorstacktrace = true
+}
+
+// Errorf records the log with error level
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.Output(LevelError, fmt.Sprintf(format, args...))
+}
+
+// Fatalf records the log with fatal level and exits
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.Output(LevelFatal, fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+// Infof records the log with info level
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.Output(LevelInfo, fmt.Sprintf(format, args...))
+}
+
+// Debugf records the log with debug level
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.Output(LevelDebug, fmt.Sprintf(format, args...))
+}
+
+// ErrorTracef records the log with stack trace in error level
+func (l *Logger) ErrorTracef(format string, args ...interface{}) {
+	l.mu.Lock()
+	trace := l.errorstacktrace
+	l.mu.Unlock()
+
+	if trace {
+		strace := errors.New(fmt.Sprintf(format, args...))
+		l.Output(LevelError, fmt.Sprintf("%+v", strace))
+	} else {
+		l.Output(LevelError, fmt.Sprintf(format, args...))
+	}
+}
+
+// Output records the log with special callstack depth and log level.
+func (l *Logger) Output(level Level, msg string) {
+	l.write(level, msg, []interface{}{"caller", caller(3)})
+}
+
+// Debug records the log with debug level
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.write(LevelDebug, msg, keyvals)
+}
+
+// Info records the log with info level
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.write(LevelInfo, msg, keyvals)
+}
+
+// Warn records the log with warning level
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.write(LevelWarn, msg, keyvals)
+}
+
+// Error records the log with error level
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.write(LevelError, msg, keyvals)
+}
+
+func (l *Logger) write(level Level, msg string, keyvals []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.level.enabled(level) {
+		return
+	}
+
+	var w io.Writer = os.Stderr
+	if l.logpath != "" && l.logfile == nil {
+		f, err := os.OpenFile(l.logpath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			// Try again at next record
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			l.logfile = f
+		}
+	}
+	if l.logfile != nil {
+		w = l.logfile
+	}
+	w.Write(formatText(time.Now(), level, msg, keyvals))
+}
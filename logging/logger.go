@@ -1,13 +1,13 @@
 package logger
 
 import (
-	//"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 )
@@ -15,133 +15,254 @@ import (
 // Level type
 type Level int
 
-// Log level, from low to high, more high means more serious
+// Log level, from low to high, more high means more serious. LevelWarn is appended so that values of earlier
+// levels don't change. Its severity is between LevelError and LevelInfo
 const (
 	LevelFatal Level = iota
 	LevelError
 	LevelInfo
 	LevelDebug
+	LevelWarn
 )
 
-// LogLevel sets log level
-//var LogLevel = LevelError
+// String returns level name
+func (level Level) String() string {
+	switch level {
+	case LevelFatal:
+		return "FATAL"
+	case LevelError:
+		return "ERROR"
+	case LevelWarn:
+		return "WARN"
+	case LevelInfo:
+		return "INFO"
+	case LevelDebug:
+		return "DEBUG"
+	default:
+		return "UNKNOWN"
+	}
+}
 
-// LogPath set log file path
-//var LogPath string
-var logger = NewLogger()
+// severity returns position of level in the order of verbosity, from fatal to debug
+func (level Level) severity() int {
+	switch level {
+	case LevelFatal:
+		return 0
+	case LevelError:
+		return 1
+	case LevelWarn:
+		return 2
+	case LevelInfo:
+		return 3
+	case LevelDebug:
+		return 4
+	default:
+		return int(level)
+	}
+}
 
-// Logger represents logging object
-type Logger struct {
-	level           Level
-	logpath         string
-	errorstacktrace bool
+// enabled returns true if record of level record is logged by logger of this level
+func (level Level) enabled(record Level) bool {
+	return record.severity() <= level.severity()
 }
 
-// NewLogger creates default logger
-func NewLogger() *Logger {
-	var l = new(Logger)
-	l.level = LevelError
+// Handler is a leveled structured logger. keyvals are alternating key and value pairs such as
+//
+//	h.Error("Request failed", "method", "/RedRock/query", "status", 500)
+//
+// Each client in the SDK accepts its own Handler. Package level functions write to Default().
+type Handler interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
 
-	return l
+// LevelSetter is implemented by loggers whose level can be changed by SetLevel
+type LevelSetter interface {
+	SetLevel(level Level)
 }
 
-// SetLevel changes the logger level
-func SetLevel(level Level) {
-	//LogLevel = level
-	logger.SetLevel(level)
+// OutputSetter is implemented by loggers whose destination can be changed by SetLogPath
+type OutputSetter interface {
+	SetOutput(w io.Writer)
 }
 
-// SetLevel sets log level, any log level less than it will not log
-func (l *Logger) SetLevel(level Level) {
-	l.level = level
+var (
+	mu              sync.RWMutex
+	defaultLogger   Handler = NewTextLogger(os.Stderr, LevelError)
+	logFile         *os.File
+	errorStackTrace bool
+)
+
+// Default returns the logger used by package level functions and by clients that don't have their own logger
+func Default() Handler {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultLogger
 }
 
-// SetLogPath sets log file name
-func SetLogPath(logfile string) {
-	//LogPath = logfile
-	logger.SetLogPath(logfile)
+// SetDefault replaces the default logger. nil disables logging
+func SetDefault(l Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	if l == nil {
+		l = NewNopLogger()
+	}
+	defaultLogger = l
 }
 
-// SetLogPath sets log file name
-func (l *Logger) SetLogPath(logfile string) {
-	l.logpath = logfile
+// SetLevel changes level of the default logger, any log level less than it will not log
+func SetLevel(level Level) {
+	if l, ok := Default().(LevelSetter); ok {
+		l.SetLevel(level)
+	}
 }
 
-// EnableErrorStackTrace enables stack trace for ErrorTracef method
-func EnableErrorStackTrace() {
-	logger.EnableErrorStackTrace()
+// SetLogPath sets log file of the default logger. The file is opened once in append mode and stays open
+// until SetLogPath is called again or Close is called.
+func SetLogPath(logfile string) error {
+	l, ok := Default().(OutputSetter)
+	if !ok {
+		return fmt.Errorf("default logger doesn't support changing output")
+	}
+
+	f, err := os.OpenFile(logfile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	l.SetOutput(f)
+
+	mu.Lock()
+	previous := logFile
+	logFile = f
+	mu.Unlock()
+	if previous != nil {
+		previous.Close()
+	}
+
+	return nil
+}
+
+// Close closes log file opened by SetLogPath and sends default logger output back to stderr
+func Close() error {
+	mu.Lock()
+	f := logFile
+	logFile = nil
+	mu.Unlock()
+
+	if f == nil {
+		return nil
+	}
+	if l, ok := Default().(OutputSetter); ok {
+		l.SetOutput(os.Stderr)
+	}
+	return f.Close()
 }
 
 // EnableErrorStackTrace enables stack trace for ErrorTracef method
-func (l *Logger) EnableErrorStackTrace() {
-	l.errorstacktrace = true
+func EnableErrorStackTrace() {
+	mu.Lock()
+	defer mu.Unlock()
+	errorStackTrace = true
 }
 
 // Fatalf records the log with fatal level and exits
 func Fatalf(format string, args ...interface{}) {
-	logger.Output(LevelFatal, fmt.Sprintf(format, args...))
+	logf(Default(), LevelFatal, format, args)
 	os.Exit(1)
 }
 
 // Errorf records the log with error level
 func Errorf(format string, args ...interface{}) {
-	logger.Output(LevelError, fmt.Sprintf(format, args...))
+	logf(Default(), LevelError, format, args)
+}
+
+// Warnf records the log with warning level
+func Warnf(format string, args ...interface{}) {
+	logf(Default(), LevelWarn, format, args)
 }
 
 // Infof records the log with info level
 func Infof(format string, args ...interface{}) {
-	logger.Output(LevelInfo, fmt.Sprintf(format, args...))
+	logf(Default(), LevelInfo, format, args)
 }
 
 // Debugf records the log with debug level
 func Debugf(format string, args ...interface{}) {
-	logger.Output(LevelDebug, fmt.Sprintf(format, args...))
+	logf(Default(), LevelDebug, format, args)
+}
+
+// ErrorTracef records the log with stack trace in error level
+func ErrorTracef(format string, args ...interface{}) {
+	logTracef(Default(), format, args)
+}
+
+// Printer adds printf style methods to Handler. Like package level functions, caller is added to every record
+type Printer struct {
+	Handler
 }
 
 // Errorf records the log with error level
-func (l *Logger) Errorf(format string, args ...interface{}) {
-	l.Output(LevelError, fmt.Sprintf(format, args...))
+func (p Printer) Errorf(format string, args ...interface{}) {
+	logf(p.Handler, LevelError, format, args)
 }
 
-// Fatalf records the log with fatal level and exits
-func (l *Logger) Fatalf(format string, args ...interface{}) {
-	l.Output(LevelFatal, fmt.Sprintf(format, args...))
-	os.Exit(1)
+// Warnf records the log with warning level
+func (p Printer) Warnf(format string, args ...interface{}) {
+	logf(p.Handler, LevelWarn, format, args)
 }
 
 // Infof records the log with info level
-func (l *Logger) Infof(format string, args ...interface{}) {
-	l.Output(LevelInfo, fmt.Sprintf(format, args...))
+func (p Printer) Infof(format string, args ...interface{}) {
+	logf(p.Handler, LevelInfo, format, args)
 }
 
 // Debugf records the log with debug level
-func (l *Logger) Debugf(format string, args ...interface{}) {
-	l.Output(LevelDebug, fmt.Sprintf(format, args...))
+func (p Printer) Debugf(format string, args ...interface{}) {
+	logf(p.Handler, LevelDebug, format, args)
 }
 
 // ErrorTracef records the log with stack trace in error level
-func ErrorTracef(format string, args ...interface{}) {
-	logger.ErrorTracef(format, args...)
+func (p Printer) ErrorTracef(format string, args ...interface{}) {
+	logTracef(p.Handler, format, args)
 }
 
-// ErrorTracef records the log with stack trace in error level
-func (l *Logger) ErrorTracef(format string, args ...interface{}) {
-	if l.errorstacktrace {
-		strace := errors.New(fmt.Sprintf(format, args...))
-		l.Output(LevelError, fmt.Sprintf("%+v", strace))
-	} else {
-		l.Output(LevelError, fmt.Sprintf(format, args...))
+// logf formats record and sends it to h. It must be called directly by the exported printf style functions so
+// that caller is the code that logs
+func logf(h Handler, level Level, format string, args []interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	switch level {
+	case LevelFatal:
+		h.Error(msg, "fatal", true, "caller", caller(3))
+	case LevelError:
+		h.Error(msg, "caller", caller(3))
+	case LevelWarn:
+		h.Warn(msg, "caller", caller(3))
+	case LevelInfo:
+		h.Info(msg, "caller", caller(3))
+	default:
+		h.Debug(msg, "caller", caller(3))
 	}
 }
 
-// Output records the log with special callstack depth and log level.
-func (l *Logger) Output(level Level, msg string) {
-	//if l.level < level && LogLevel < level {
-	if l.level < level {
-		return
+// logTracef is logf of error level that adds stack trace if it is enabled by EnableErrorStackTrace
+func logTracef(h Handler, format string, args []interface{}) {
+	mu.RLock()
+	trace := errorStackTrace
+	mu.RUnlock()
+
+	msg := fmt.Sprintf(format, args...)
+	if trace {
+		h.Error(msg, "caller", caller(3), "stack", fmt.Sprintf("%+v", errors.New(msg)))
+	} else {
+		h.Error(msg, "caller", caller(3))
 	}
+}
 
-	pc, file, line, ok := runtime.Caller(2)
+// caller returns "file:line function()" of the caller skip frames up
+func caller(skip int) string {
+	pc, file, line, ok := runtime.Caller(skip)
 	if !ok {
 		file = "?"
 		line = 0
@@ -156,26 +277,5 @@ func (l *Logger) Output(level Level, msg string) {
 		fnName = strings.TrimLeft(dotName, ".") + "()"
 	}
 
-	if l.logpath != "" {
-		logf, err := os.OpenFile(l.logpath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
-			log.Println(err)
-		}
-		defer logf.Close()
-		log.SetOutput(logf)
-	}
-
-	var logLevelStr = "[UNKNOWN]"
-	switch level {
-	case LevelFatal:
-		logLevelStr = "[FATAL]"
-	case LevelError:
-		logLevelStr = "[ERROR]"
-	case LevelInfo:
-		logLevelStr = "[INFO ]"
-	case LevelDebug:
-		logLevelStr = "[DEBUG]"
-	}
-
-	log.Printf("%s %s:%d %s: %s", logLevelStr, filepath.Base(file), line, fnName, msg)
+	return fmt.Sprintf("%s:%d %s", filepath.Base(file), line, fnName)
 }
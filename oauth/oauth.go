@@ -10,6 +10,8 @@ import (
 	"net/url"
 	"os"
 	"strings"
+
+	logger "github.com/marcozj/golang-sdk/logging"
 )
 
 type HttpClientFactory func() *http.Client
@@ -41,6 +43,7 @@ type OauthClient struct {
 	Scope           string
 	Token           string
 	SkipCertVerify  bool
+	Logger          logger.Handler // Logger passed to the REST client. logger.Default() is used if it is nil
 }
 
 // OauthConfig represents configuration used to create Oauth clients
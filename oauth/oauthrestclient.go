@@ -95,5 +95,10 @@ func (c *OauthClient) GetRestClient(token *TokenResponse) (*restapi.RestClient,
 	}
 
 	restClient.Headers["Authorization"] = token.TokenType + " " + token.AccessToken
+	restClient.Logger = c.Logger
+	restClient.Principal = restapi.PrincipalFromToken(token.AccessToken)
+	if restClient.Principal == "" {
+		restClient.Principal = c.ClientID
+	}
 	return restClient, nil
 }
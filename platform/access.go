@@ -0,0 +1,367 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/marcozj/golang-sdk/platform/query"
+	"github.com/marcozj/golang-sdk/restapi"
+)
+
+// AccessGrant is one way through which a principal obtains a right
+type AccessGrant struct {
+	Right string   `json:"right"`
+	Scope string   `json:"scope"` // Object type the right is granted on. It is parent system, domain or database for rights that come from account's resource
+	Path  []string `json:"path"`  // Where right is granted followed by every role it passes through
+}
+
+// AccessEntry is effective access of one user or group
+type AccessEntry struct {
+	PrincipalID   string        `json:"principal_id"`
+	PrincipalName string        `json:"principal_name"`
+	PrincipalType string        `json:"principal_type"`
+	Rights        []string      `json:"rights"` // Effective rights on the object itself
+	Grants        []AccessGrant `json:"grants"`
+}
+
+// AccessReport lists every user and group that has access to an object
+type AccessReport struct {
+	Kind    string        `json:"kind"`
+	Name    string        `json:"name"`
+	ID      string        `json:"id"`
+	Entries []AccessEntry `json:"entries"`
+
+	valid map[string]string // ValidPermissions of the object so that rights can be looked up by API name too
+}
+
+// WithRight returns principals that have right on the object. Right can be given either by name such as "Checkout"
+// or by API name such as "Naked"
+func (r *AccessReport) WithRight(right string) []AccessEntry {
+	if name, ok := rightNameOf(right, r.valid); ok {
+		right = name
+	}
+	var entries []AccessEntry
+	for _, e := range r.Entries {
+		for _, have := range e.Rights {
+			if strings.EqualFold(have, right) {
+				entries = append(entries, e)
+				break
+			}
+		}
+	}
+	return entries
+}
+
+// WriteJSON writes report in JSON format
+func (r *AccessReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// String prints each principal with its rights and the path of every grant
+func (r *AccessReport) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Effective access of %s %q\n", r.Kind, r.Name)
+	for _, e := range r.Entries {
+		fmt.Fprintf(&buf, "%s %q: %s\n", e.PrincipalType, e.PrincipalName, strings.Join(e.Rights, ","))
+		for _, g := range e.Grants {
+			fmt.Fprintf(&buf, "      %s on %s via %s\n", g.Right, g.Scope, strings.Join(g.Path, " -> "))
+		}
+	}
+	return buf.String()
+}
+
+// accessSource is a place where permissions of an object are granted
+type accessSource struct {
+	api    string
+	rowKey string
+	table  string
+	scope  string
+	path   string
+	valid  map[string]string
+}
+
+type accessAnalyzer struct {
+	client  *restapi.RestClient
+	report  *AccessReport
+	entries map[string]*AccessEntry
+	roles   map[string][]RoleMember // Members of roles that are already expanded
+}
+
+// EffectiveAccess reports every user and group that has access to Account, Secret, SSHKey or System together with
+// its effective rights. Permissions granted directly, through member permissions of manual and dynamic sets the object
+// belongs to and of every secret folder above it and, for account, on its system, domain or database are collected
+// and roles are expanded to their members. Sysadmin role isn't reported since it has access to everything
+func EffectiveAccess(o Object) (*AccessReport, error) {
+	if o.GetID() == "" {
+		_, err := o.GetIDByName()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var base *vaultObject
+	var parent *vaultObject
+	var parentKind string
+	var folderID string
+	switch v := o.(type) {
+	case *Account:
+		if v.Host == "" && v.DomainID == "" && v.DatabaseID == "" && v.CloudProviderID == "" {
+			// Account looked up by ID or name only doesn't know its resource yet
+			if err := v.Read(); err != nil {
+				return nil, err
+			}
+		}
+		base = &v.vaultObject
+		switch {
+		case v.Host != "":
+			parent, parentKind = &NewSystem(v.client).vaultObject, "System"
+			parent.ValidPermissions = ValidPermissionMap.WinNix
+			parent.ID = v.Host
+		case v.DomainID != "":
+			parent, parentKind = &NewDomain(v.client).vaultObject, "Domain"
+			parent.ID = v.DomainID
+		case v.DatabaseID != "":
+			parent, parentKind = &NewDatabase(v.client).vaultObject, "Database"
+			parent.ID = v.DatabaseID
+		case v.CloudProviderID != "":
+			parent, parentKind = &NewCloudProvider(v.client).vaultObject, "CloudProvider"
+			parent.ID = v.CloudProviderID
+		}
+	case *Secret:
+		base = &v.vaultObject
+		folderID = v.FolderID
+	case *SSHKey:
+		base = &v.vaultObject
+	case *System:
+		// Computer class may not be known so use rights of Windows and Unix, which include all system rights
+		vo := v.vaultObject
+		vo.ValidPermissions = ValidPermissionMap.WinNix
+		base = &vo
+	default:
+		return nil, fmt.Errorf("Effective access of %s isn't supported", GetVarType(o))
+	}
+	kind := GetVarType(o)
+	a := &accessAnalyzer{
+		client:  base.client,
+		report:  &AccessReport{Kind: kind, Name: o.GetName(), ID: base.ID, valid: base.ValidPermissions},
+		entries: make(map[string]*AccessEntry),
+		roles:   make(map[string][]RoleMember),
+	}
+	sources, err := a.sources(base, kind, "")
+	if err != nil {
+		return nil, err
+	}
+	// Member permissions of every folder up to the top one apply to secret
+	visited := make(map[string]bool)
+	for folderID != "" && !visited[folderID] {
+		visited[folderID] = true
+		folder := NewSecretFolder(base.client)
+		folder.ID = folderID
+		err = folder.Read()
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, accessSource{api: "/Acl/GetCollectionAces", rowKey: folderID, table: base.aclTable,
+			scope: kind, path: fmt.Sprintf("folder '%s' member permission", folder.Name), valid: base.ValidPermissions})
+		folderID = folder.ParentID
+	}
+	if parent != nil {
+		more, err := a.sources(parent, parentKind, parentKind)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, more...)
+	}
+
+	for _, src := range sources {
+		perms, err := readAces(a.client, src.api, src.rowKey, src.table)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range perms {
+			p = toRightNames(p, src.valid)
+			err = a.grant(p, src.scope, []string{src.path}, make(map[string]bool))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, e := range a.entries {
+		sort.Strings(e.Rights)
+		a.report.Entries = append(a.report.Entries, *e)
+	}
+	sort.Slice(a.report.Entries, func(i, j int) bool {
+		if a.report.Entries[i].PrincipalType != a.report.Entries[j].PrincipalType {
+			return a.report.Entries[i].PrincipalType > a.report.Entries[j].PrincipalType
+		}
+		return strings.ToLower(a.report.Entries[i].PrincipalName) < strings.ToLower(a.report.Entries[j].PrincipalName)
+	})
+	return a.report, nil
+}
+
+// sources returns direct permissions of object and member permissions of every set it belongs to.
+// Parent is the object type of account's resource or empty for the object itself
+func (a *accessAnalyzer) sources(o *vaultObject, kind string, parent string) ([]accessSource, error) {
+	scope, direct := kind, "direct permission"
+	if parent != "" {
+		direct = fmt.Sprintf("%s '%s' permission", strings.ToLower(kind), a.objectName(o))
+	}
+	sources := []accessSource{{api: o.apiGetPermissions, rowKey: o.ID, table: o.aclTable, scope: scope, path: direct, valid: o.ValidPermissions}}
+
+	sets, err := objectSets(a.client, o.ID, o.aclTable)
+	if err != nil {
+		return nil, err
+	}
+	for _, set := range sets {
+		path := fmt.Sprintf("set '%s' member permission", set.Name)
+		if parent != "" {
+			path = fmt.Sprintf("%s set '%s' member permission", strings.ToLower(kind), set.Name)
+		}
+		sources = append(sources, accessSource{api: "/Acl/GetCollectionAces", rowKey: set.ID, table: o.aclTable, scope: scope, path: path, valid: o.ValidPermissions})
+	}
+	return sources, nil
+}
+
+// objectName returns name of account's resource for report path
+func (a *accessAnalyzer) objectName(o *vaultObject) string {
+	row, err := queryVaultObject(a.client, query.Select(o.aclTable, "Name").Where(query.Eq("ID", o.ID)))
+	if err == nil {
+		if name, ok := row["Name"].(string); ok {
+			return name
+		}
+	}
+	return o.ID
+}
+
+// grant records permission of principal. Role is expanded to its members, visited holds roles on the current path
+// to prevent loop of nested roles. Like path it is copied per branch so that a role reached through two sibling
+// roles is expanded on both paths
+func (a *accessAnalyzer) grant(p Permission, scope string, path []string, visited map[string]bool) error {
+	if strings.EqualFold(p.PrincipalType, "Role") {
+		if visited[p.PrincipalID] {
+			return nil
+		}
+		members, err := a.roleMembers(p.PrincipalID)
+		if err != nil {
+			return err
+		}
+		rolePath := append(append([]string{}, path...), fmt.Sprintf("role '%s'", p.PrincipalName))
+		roleVisited := make(map[string]bool, len(visited)+1)
+		for id := range visited {
+			roleVisited[id] = true
+		}
+		roleVisited[p.PrincipalID] = true
+		for _, m := range members {
+			member := Permission{PrincipalID: m.MemberID, PrincipalName: m.MemberName, PrincipalType: m.MemberType, RightList: p.RightList}
+			err = a.grant(member, scope, rolePath, roleVisited)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	key := strings.ToLower(p.PrincipalType + "/" + p.PrincipalID)
+	e, ok := a.entries[key]
+	if !ok {
+		e = &AccessEntry{PrincipalID: p.PrincipalID, PrincipalName: p.PrincipalName, PrincipalType: p.PrincipalType}
+		a.entries[key] = e
+	}
+	for _, right := range p.RightList {
+		e.Grants = append(e.Grants, AccessGrant{Right: right, Scope: scope, Path: path})
+		if scope == a.report.Kind && !containsString(e.Rights, right) {
+			e.Rights = append(e.Rights, right)
+		}
+	}
+	return nil
+}
+
+func (a *accessAnalyzer) roleMembers(id string) ([]RoleMember, error) {
+	if members, ok := a.roles[id]; ok {
+		return members, nil
+	}
+	role := NewRole(a.client)
+	role.ID = id
+	members, err := role.getMembers()
+	if err != nil {
+		a.client.Log().Errorf(err.Error())
+		return nil, fmt.Errorf("Error retrieving members of role %s: %s", id, err)
+	}
+	a.roles[id] = members
+	return members, nil
+}
+
+// setRef identifies a set
+type setRef struct {
+	ID   string
+	Name string
+}
+
+// objectSets returns manual and dynamic sets that object in table belongs to
+func objectSets(c *restapi.RestClient, id string, table string) ([]setRef, error) {
+	var sets []setRef
+	for _, collectionType := range []string{"ManualBucket", "SqlDynamic"} {
+		var queryArg = make(map[string]interface{})
+		queryArg["ID"] = id
+		queryArg["ObjectType"] = table
+		queryArg["CollectionType"] = collectionType
+		resp, err := c.CallGenericMapAPI("/Collection/GetObjectCollectionsAndFilters", queryArg)
+		if err != nil {
+			c.Log().Errorf(err.Error())
+			return nil, err
+		}
+		if !resp.Success {
+			errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
+			c.Log().Errorf(errmsg)
+			return nil, fmt.Errorf(errmsg)
+		}
+
+		results, _ := resp.Result["Results"].([]interface{})
+		for _, v := range results {
+			item, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			row, ok := item["Row"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			setID, ok := row["ID"].(string)
+			if !ok {
+				continue
+			}
+			set := setRef{ID: setID, Name: setID}
+			if name, ok := row["Name"].(string); ok {
+				set.Name = name
+			}
+			sets = append(sets, set)
+		}
+	}
+	return sets, nil
+}
+
+// rightNameOf returns ValidPermissions name of API right such as "Naked"
+func rightNameOf(right string, valid map[string]string) (string, bool) {
+	for name, api := range valid {
+		if strings.EqualFold(api, right) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package platform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGrantNestedRoles(t *testing.T) {
+	role := func(id string) RoleMember { return RoleMember{MemberID: id, MemberName: id, MemberType: "Role"} }
+	a := &accessAnalyzer{
+		report:  &AccessReport{Kind: "Account"},
+		entries: make(map[string]*AccessEntry),
+		roles: map[string][]RoleMember{
+			"admins": {role("dba"), role("ops")},
+			"dba":    {role("oncall")},
+			"ops":    {role("oncall"), role("admins")},
+			"oncall": {{MemberID: "u1", MemberName: "alice", MemberType: "User"}},
+		},
+	}
+
+	p := Permission{PrincipalID: "admins", PrincipalName: "admins", PrincipalType: "Role", RightList: []string{"View"}}
+	err := a.grant(p, "Account", []string{"direct permission"}, make(map[string]bool))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, ok := a.entries["user/u1"]
+	if !ok {
+		t.Fatalf("user wasn't granted: %v", a.entries)
+	}
+	var paths [][]string
+	for _, g := range e.Grants {
+		paths = append(paths, g.Path)
+	}
+	want := [][]string{
+		{"direct permission", "role 'admins'", "role 'dba'", "role 'oncall'"},
+		{"direct permission", "role 'admins'", "role 'ops'", "role 'oncall'"},
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+	if !reflect.DeepEqual(e.Rights, []string{"View"}) {
+		t.Errorf("rights = %v, want [View]", e.Rights)
+	}
+}
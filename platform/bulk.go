@@ -0,0 +1,224 @@
+package platform
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/marcozj/golang-sdk/restapi"
+)
+
+// BulkAction is the operation run by BulkExecute on each object
+type BulkAction string
+
+// Supported bulk actions
+const (
+	BulkCreate BulkAction = "create"
+	BulkUpdate BulkAction = "update"
+	BulkDelete BulkAction = "delete"
+	BulkRotate BulkAction = "rotate" // Rotate password of Account
+)
+
+// DefaultBulkWorkers is number of concurrent workers used when BulkOptions.Workers isn't set
+const DefaultBulkWorkers = 4
+
+// BulkOptions controls how BulkExecute runs
+type BulkOptions struct {
+	Workers   int                // Number of concurrent workers. Defaults to DefaultBulkWorkers
+	RateLimit float64            // Maximum number of actions started per second across all workers. 0 means no limit
+	After     func(Object) error // Optional step run after successful action, such as AddToSetsByName or SetPermissions
+}
+
+// BulkResult is outcome of action on a single object
+type BulkResult struct {
+	Index    int           `json:"index"` // Position of object in input slice
+	Type     string        `json:"type"`
+	Name     string        `json:"name"`
+	ID       string        `json:"id"`
+	Action   BulkAction    `json:"action"`
+	Success  bool          `json:"success"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// BulkReport contains result of every object in the order of input
+type BulkReport struct {
+	Results   []BulkResult `json:"results"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+}
+
+// BulkExecute runs action on every object with a bounded worker pool. Failure of one object doesn't stop the others
+func BulkExecute(action BulkAction, objects []Object, opts BulkOptions) (*BulkReport, error) {
+	var run func(Object) error
+	switch action {
+	case BulkCreate:
+		run = func(o Object) error {
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			_, err := o.Create()
+			return err
+		}
+	case BulkUpdate:
+		run = func(o Object) error {
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			_, err := o.Update()
+			return err
+		}
+	case BulkDelete:
+		run = func(o Object) error { _, err := o.Delete(); return err }
+	case BulkRotate:
+		run = func(o Object) error {
+			acct, ok := o.(*Account)
+			if !ok {
+				return fmt.Errorf("Password of %s can't be rotated", GetVarType(o))
+			}
+			_, err := acct.RotatePassword()
+			return err
+		}
+	default:
+		return nil, fmt.Errorf("Invalid bulk action '%s'", action)
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultBulkWorkers
+	}
+	var throttle <-chan time.Time
+	if opts.RateLimit > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.RateLimit))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	report := &BulkReport{Results: make([]BulkResult, len(objects))}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				report.Results[i] = bulkRun(i, objects[i], action, run, opts.After)
+			}
+		}()
+	}
+	for i := range objects {
+		if throttle != nil {
+			<-throttle
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, r := range report.Results {
+		if r.Success {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+
+	return report, nil
+}
+
+// RotateAccounts rotates password of every account that matches filter and reports result of each account.
+// Accounts whose password isn't managed are reported as failed. Filter must have a criterion or All set so that
+// an empty filter doesn't rotate every account by accident
+func RotateAccounts(c *restapi.RestClient, filter AccountFilter, opts BulkOptions) (*BulkReport, error) {
+	if !filter.hasCriteria() && !filter.All {
+		errmsg := "Account filter has no criterion. Set All to rotate every account"
+		c.Log().Errorf(errmsg)
+		return nil, fmt.Errorf(errmsg)
+	}
+
+	var accounts []Object
+	it := ListAccounts(c, filter)
+	for it.Next() {
+		accounts = append(accounts, it.Value())
+	}
+	if err := it.Err(); err != nil {
+		c.Log().Errorf(err.Error())
+		return nil, err
+	}
+
+	return BulkExecute(BulkRotate, accounts, opts)
+}
+
+func bulkRun(index int, o Object, action BulkAction, run func(Object) error, after func(Object) error) BulkResult {
+	start := time.Now()
+	err := bulkCall(o, run, after)
+
+	result := BulkResult{
+		Index:    index,
+		Type:     GetVarType(o),
+		Name:     o.GetName(),
+		ID:       o.GetID(),
+		Action:   action,
+		Success:  err == nil,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		logOf(o).Errorf("Bulk %s of %s '%s' failed: %v", action, result.Type, result.Name, err)
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// bulkCall runs action and optional step after it on o. Panic is recovered and returned as error of o so that
+// one bad object doesn't stop the whole batch
+func bulkCall(o Object, run func(Object) error, after func(Object) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	err = run(o)
+	if err == nil && after != nil {
+		err = after(o)
+	}
+	return err
+}
+
+// WriteJSON writes the report as JSON
+func (r *BulkReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes one line per object with a header line
+func (r *BulkReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"index", "type", "name", "id", "action", "success", "error", "duration"})
+	if err != nil {
+		return err
+	}
+	for _, v := range r.Results {
+		err = cw.Write([]string{
+			strconv.Itoa(v.Index),
+			v.Type,
+			v.Name,
+			v.ID,
+			string(v.Action),
+			strconv.FormatBool(v.Success),
+			v.Error,
+			v.Duration.String(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
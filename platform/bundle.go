@@ -0,0 +1,736 @@
+package platform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/marcozj/golang-sdk/enum/workflowtype"
+	"github.com/marcozj/golang-sdk/platform/query"
+	"github.com/marcozj/golang-sdk/restapi"
+	"gopkg.in/yaml.v2"
+)
+
+// BundleVersion is version of bundle format written by Export
+const BundleVersion = 1
+
+// Kinds of object in a bundle
+const (
+	KindPasswordProfile = "password_profiles"
+	KindAuthProfile     = "authentication_profiles"
+	KindDomain          = "domains"
+	KindSystem          = "systems"
+	KindDatabase        = "databases"
+	KindAccount         = "accounts"
+	KindSecretFolder    = "secret_folders"
+	KindSet             = "sets"
+	KindDynamicSet      = "dynamic_sets"
+	KindRole            = "roles"
+	KindDesktopApp      = "desktop_apps"
+	KindGenericWebApp   = "generic_webapps"
+	KindSamlWebApp      = "saml_webapps"
+	KindOauthWebApp     = "oauth_webapps"
+	KindOidcWebApp      = "oidc_webapps"
+	KindPolicy          = "policies"
+	KindPolicyLinks     = "policy_links"
+	KindGlobalWorkflow  = "global_workflows"
+)
+
+// BundleKinds lists all kinds in import order. Objects of a kind may only refer to objects of kinds before it
+var BundleKinds = []string{
+	KindPasswordProfile,
+	KindAuthProfile,
+	KindDomain,
+	KindSystem,
+	KindDatabase,
+	KindAccount,
+	KindSecretFolder,
+	KindRole,
+	KindSet,
+	KindDynamicSet,
+	KindDesktopApp,
+	KindGenericWebApp,
+	KindSamlWebApp,
+	KindOauthWebApp,
+	KindOidcWebApp,
+	KindPolicy,
+	KindPolicyLinks,
+	KindGlobalWorkflow,
+}
+
+// secretAttributes are never written to bundle
+var secretAttributes = map[string]bool{
+	"password":                        true,
+	"confirm_password":                true,
+	"admin_user_password":             true,
+	"administrative_account_password": true,
+	"proxyuser_password":              true,
+	"client_secret":                   true,
+	"private_key":                     true,
+	"passphrase":                      true,
+	"secret_text":                     true,
+	"secret_access_key":               true,
+}
+
+// setMembersAttribute holds member IDs of a set in bundle
+const setMembersAttribute = "members"
+
+// Bundle is a versioned snapshot of tenant configuration. Each object is stored as its schema map
+type Bundle struct {
+	Version int                                 `json:"version" yaml:"version"`
+	Tenant  string                              `json:"tenant,omitempty" yaml:"tenant,omitempty"`
+	Created time.Time                           `json:"created" yaml:"created"`
+	Objects map[string][]map[string]interface{} `json:"objects" yaml:"objects"`
+}
+
+// bundleKind describes how to list and instantiate objects of a kind
+type bundleKind struct {
+	newObject func(c *restapi.RestClient) Object
+	listIDs   func(c *restapi.RestClient) ([]string, error)
+}
+
+func tableIDs(table string, conditions ...query.Condition) func(c *restapi.RestClient) ([]string, error) {
+	return func(c *restapi.RestClient) ([]string, error) {
+		q := query.Select(table, "ID").Where(conditions...)
+		it := newIterator(c, q, ListOptions{})
+		var ids []string
+		for it.Next() {
+			if id, ok := it.Row()["ID"].(string); ok {
+				ids = append(ids, id)
+			}
+		}
+		return ids, it.Err()
+	}
+}
+
+var bundleKinds = map[string]bundleKind{
+	KindPasswordProfile: {
+		newObject: func(c *restapi.RestClient) Object { return NewPasswordProfile(c) },
+		listIDs: func(c *restapi.RestClient) ([]string, error) {
+			var queryArg = make(map[string]interface{})
+			queryArg["ProfileTypes"] = "All"
+			queryArg["Args"] = subArgs
+			resp, err := c.CallGenericMapAPI("/ServerManage/GetPasswordProfiles", queryArg)
+			if err != nil {
+				return nil, err
+			}
+			if !resp.Success {
+				return nil, fmt.Errorf("%s %s", resp.Message, resp.Exception)
+			}
+			return rowIDs(resp.Result["Results"], "ID"), nil
+		},
+	},
+	KindAuthProfile: {
+		newObject: func(c *restapi.RestClient) Object { return NewAuthenticationProfile(c) },
+		listIDs: func(c *restapi.RestClient) ([]string, error) {
+			var queryArg = make(map[string]interface{})
+			queryArg["Args"] = subArgs
+			resp, err := c.CallSliceAPI("/AuthProfile/GetProfileList", queryArg)
+			if err != nil {
+				return nil, err
+			}
+			if !resp.Success {
+				return nil, fmt.Errorf("%s %s", resp.Message, resp.Exception)
+			}
+			var ids []string
+			for _, v := range resp.Result {
+				if item, ok := v.(map[string]interface{}); ok {
+					if id, ok := item["Uuid"].(string); ok {
+						ids = append(ids, id)
+					}
+				}
+			}
+			return ids, nil
+		},
+	},
+	KindDomain: {
+		newObject: func(c *restapi.RestClient) Object { return NewDomain(c) },
+		listIDs:   tableIDs("VaultDomain"),
+	},
+	KindSystem: {
+		newObject: func(c *restapi.RestClient) Object { return NewSystem(c) },
+		listIDs:   tableIDs("Server"),
+	},
+	KindDatabase: {
+		newObject: func(c *restapi.RestClient) Object { return NewDatabase(c) },
+		listIDs:   tableIDs("VaultDatabase"),
+	},
+	KindAccount: {
+		newObject: func(c *restapi.RestClient) Object { return NewAccount(c) },
+		listIDs:   tableIDs("VaultAccount"),
+	},
+	KindSecretFolder: {
+		newObject: func(c *restapi.RestClient) Object { return NewSecretFolder(c) },
+		listIDs:   tableIDs("Sets", query.Eq("ObjectType", "DataVault"), query.Eq("CollectionType", "Phantom")),
+	},
+	KindRole: {
+		newObject: func(c *restapi.RestClient) Object { return NewRole(c) },
+		listIDs:   tableIDs("Role"),
+	},
+	KindSet: {
+		newObject: func(c *restapi.RestClient) Object { return NewManualSet(c) },
+		listIDs:   tableIDs("Sets", query.Eq("CollectionType", "ManualBucket")),
+	},
+	KindDynamicSet: {
+		newObject: func(c *restapi.RestClient) Object { return NewDynamicSet(c) },
+		listIDs:   tableIDs("Sets", query.Eq("CollectionType", "SqlDynamic")),
+	},
+	KindDesktopApp: {
+		newObject: func(c *restapi.RestClient) Object { return NewDesktopApp(c) },
+		listIDs:   tableIDs("Application", query.Eq("AppType", "Desktop")),
+	},
+	KindGenericWebApp: {
+		newObject: func(c *restapi.RestClient) Object { return NewGenericWebApp(c) },
+		listIDs:   tableIDs("Application", query.Eq("AppType", "Web"), query.Eq("WebAppType", "UsernamePassword")),
+	},
+	KindSamlWebApp: {
+		newObject: func(c *restapi.RestClient) Object { return NewSamlWebApp(c) },
+		listIDs:   tableIDs("Application", query.Eq("AppType", "Web"), query.Eq("WebAppType", "Saml")),
+	},
+	KindOauthWebApp: {
+		newObject: func(c *restapi.RestClient) Object { return NewOauthWebApp(c) },
+		listIDs:   tableIDs("Application", query.Eq("AppType", "Web"), query.Eq("WebAppType", "OAuth")),
+	},
+	KindOidcWebApp: {
+		newObject: func(c *restapi.RestClient) Object { return NewOidcWebApp(c) },
+		listIDs:   tableIDs("Application", query.Eq("AppType", "Web"), query.Eq("WebAppType", "OpenIDConnect")),
+	},
+	KindPolicy: {
+		newObject: func(c *restapi.RestClient) Object { return NewPolicy(c) },
+		listIDs: func(c *restapi.RestClient) ([]string, error) {
+			plinks, _, err := NewPolicyLinks(c).GetPlinks()
+			if err != nil {
+				return nil, err
+			}
+			var ids []string
+			for _, v := range plinks {
+				if id, ok := v["ID"].(string); ok {
+					ids = append(ids, id)
+				}
+			}
+			return ids, nil
+		},
+	},
+}
+
+// rowIDs extracts column of each row in RedRock style results
+func rowIDs(results interface{}, column string) []string {
+	var ids []string
+	rows, _ := results.([]interface{})
+	for _, v := range rows {
+		item, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		row, ok := item["Row"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if id, ok := row[column].(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ExportOptions controls what Export writes
+type ExportOptions struct {
+	Kinds []string // Kinds to export. Empty means all BundleKinds
+}
+
+// Export reads tenant configuration into a bundle. Secret attributes such as passwords are never exported. Neither are
+// permissions, including member permissions of manual and dynamic sets, since principals aren't part of bundle.
+// Members of manual sets are exported as ID list while dynamic sets only carry their query
+func Export(c *restapi.RestClient, opts ExportOptions) (*Bundle, error) {
+	kinds := opts.Kinds
+	if len(kinds) == 0 {
+		kinds = BundleKinds
+	}
+
+	b := &Bundle{
+		Version: BundleVersion,
+		Tenant:  c.Service,
+		Created: time.Now().UTC(),
+		Objects: make(map[string][]map[string]interface{}),
+	}
+	for _, kind := range kinds {
+		var err error
+		switch kind {
+		case KindPolicyLinks:
+			err = b.exportPolicyLinks(c)
+		case KindGlobalWorkflow:
+			err = b.exportGlobalWorkflows(c)
+		default:
+			err = b.exportObjects(c, kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed to export %s. %v", kind, err)
+		}
+	}
+
+	return b, nil
+}
+
+func (b *Bundle) exportObjects(c *restapi.RestClient, kind string) error {
+	k, ok := bundleKinds[kind]
+	if !ok {
+		return fmt.Errorf("Unknown kind '%s'", kind)
+	}
+	ids, err := k.listIDs(c)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		obj := k.newObject(c)
+		obj.(idSetter).setID(id)
+		err = obj.Read()
+		if err != nil {
+			return fmt.Errorf("Failed to read %s %s. %v", GetVarType(obj), id, err)
+		}
+		m, err := GenerateSchemaMap(obj)
+		if err != nil {
+			return err
+		}
+		m["id"] = id
+		removeSecrets(m)
+		if kind == KindSet {
+			members, err := getSetMemberIDs(c, id)
+			if err != nil {
+				return err
+			}
+			m[setMembersAttribute] = members
+		}
+		b.Objects[kind] = append(b.Objects[kind], m)
+		c.Log().Debugf("Exported %s %s", kind, obj.GetName())
+	}
+
+	return nil
+}
+
+func (b *Bundle) exportPolicyLinks(c *restapi.RestClient) error {
+	obj := NewPolicyLinks(c)
+	err := obj.Read()
+	if err != nil {
+		return err
+	}
+	m, err := GenerateSchemaMap(obj)
+	if err != nil {
+		return err
+	}
+	b.Objects[KindPolicyLinks] = []map[string]interface{}{m}
+
+	return nil
+}
+
+func (b *Bundle) exportGlobalWorkflows(c *restapi.RestClient) error {
+	types := []workflowtype.WorkflowType{workflowtype.AccountWorkflow, workflowtype.AgentAuthWorkflow, workflowtype.SecretsWorkflow, workflowtype.PrivilegeElevationWorkflow}
+	for _, t := range types {
+		obj, err := NewGlobalWorkflow(c, t.String())
+		if err != nil {
+			return err
+		}
+		err = obj.Read()
+		if err != nil {
+			return err
+		}
+		m, err := GenerateSchemaMap(obj)
+		if err != nil {
+			return err
+		}
+		b.Objects[KindGlobalWorkflow] = append(b.Objects[KindGlobalWorkflow], m)
+	}
+
+	return nil
+}
+
+// removeSecrets deletes secret attributes at any level of m
+func removeSecrets(m map[string]interface{}) {
+	for k, v := range m {
+		if secretAttributes[k] {
+			delete(m, k)
+			continue
+		}
+		switch v := v.(type) {
+		case map[string]interface{}:
+			removeSecrets(v)
+		case []interface{}:
+			for _, item := range v {
+				if im, ok := item.(map[string]interface{}); ok {
+					removeSecrets(im)
+				}
+			}
+		}
+	}
+}
+
+// WriteJSON writes bundle in JSON format
+func (b *Bundle) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// WriteYAML writes bundle in YAML format
+func (b *Bundle) WriteYAML(w io.Writer) error {
+	data, err := yaml.Marshal(b)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// ReadBundle reads bundle written by WriteJSON or WriteYAML
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	data, err := ioutil.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		err = json.Unmarshal(data, b)
+	} else {
+		err = yaml.Unmarshal(data, b)
+		for kind, objects := range b.Objects {
+			for i, m := range objects {
+				b.Objects[kind][i] = normalizeYAML(m).(map[string]interface{})
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode bundle. %v", err)
+	}
+	if b.Version == 0 || b.Version > BundleVersion {
+		return nil, fmt.Errorf("Unsupported bundle version %d", b.Version)
+	}
+
+	return b, nil
+}
+
+// normalizeYAML converts map[interface{}]interface{} decoded by yaml into map[string]interface{}
+func normalizeYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{})
+		for k, item := range v {
+			m[fmt.Sprintf("%v", k)] = normalizeYAML(item)
+		}
+		return m
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = normalizeYAML(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeYAML(item)
+		}
+		return v
+	}
+	return v
+}
+
+// ImportOptions controls how Import recreates objects
+type ImportOptions struct {
+	Kinds          []string                          // Kinds to import. Empty means all kinds in bundle
+	UpdateExisting bool                              // Update objects that already exist by name instead of leaving them unchanged
+	BeforeCreate   func(kind string, o Object) error // Optional hook to fill attributes that aren't exported such as account password
+}
+
+// Import results
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportSkipped = "skipped"
+	ImportFailed  = "failed"
+)
+
+// ImportResult is outcome of importing a single object
+type ImportResult struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	OldID  string `json:"old_id,omitempty"`
+	NewID  string `json:"new_id,omitempty"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport contains result of every imported object
+type ImportReport struct {
+	Results []ImportResult    `json:"results"`
+	IDMap   map[string]string `json:"id_map"` // Old ID to new ID
+	Failed  int               `json:"failed"`
+}
+
+// Import recreates objects of bundle in tenant of c. Objects are matched by name and references between
+// objects are remapped from old to new IDs. Failure of one object doesn't stop the others
+func Import(c *restapi.RestClient, b *Bundle, opts ImportOptions) (*ImportReport, error) {
+	if b.Version == 0 || b.Version > BundleVersion {
+		return nil, fmt.Errorf("Unsupported bundle version %d", b.Version)
+	}
+	kinds := opts.Kinds
+	if len(kinds) == 0 {
+		kinds = BundleKinds
+	}
+	report := &ImportReport{IDMap: make(map[string]string)}
+	// Old ID of objects that failed or were skipped because they refer to failed object
+	failed := make(map[string]bool)
+
+	for _, kind := range kinds {
+		for _, m := range b.Objects[kind] {
+			var result ImportResult
+			if parent := failedReference(m, failed); parent != "" {
+				result = ImportResult{Kind: kind, Name: fmt.Sprintf("%v", m["name"]), Result: ImportSkipped,
+					Error: fmt.Sprintf("Refers to object %s that failed to import", parent)}
+				result.OldID, _ = m["id"].(string)
+				if result.OldID != "" {
+					failed[result.OldID] = true
+				}
+				c.Log().Errorf("Skipped import of %s %s: %s", kind, result.Name, result.Error)
+				report.Results = append(report.Results, result)
+				continue
+			}
+
+			switch kind {
+			case KindPolicyLinks:
+				result = importPolicyLinks(c, remapIDs(copyMap(m), report.IDMap).(map[string]interface{}))
+			case KindGlobalWorkflow:
+				result = importGlobalWorkflow(c, remapIDs(copyMap(m), report.IDMap).(map[string]interface{}))
+			default:
+				result = importObject(c, kind, m, report.IDMap, opts)
+			}
+			if result.Result == ImportFailed {
+				report.Failed++
+				if result.OldID != "" {
+					failed[result.OldID] = true
+				}
+				c.Log().Errorf("Failed to import %s %s: %s", kind, result.Name, result.Error)
+			}
+			report.Results = append(report.Results, result)
+		}
+	}
+
+	// Set members can only be added once all other objects exist
+	if containsKind(kinds, KindSet) {
+		for _, m := range b.Objects[KindSet] {
+			oldID, _ := m["id"].(string)
+			newID, ok := report.IDMap[oldID]
+			if !ok {
+				continue
+			}
+			err := importSetMembers(c, newID, m, report.IDMap)
+			if err != nil {
+				report.Failed++
+				report.Results = append(report.Results, ImportResult{Kind: KindSet, Name: fmt.Sprintf("%v", m["name"]), OldID: oldID, NewID: newID, Result: ImportFailed, Error: err.Error()})
+			}
+		}
+	}
+
+	return report, nil
+}
+
+func importObject(c *restapi.RestClient, kind string, m map[string]interface{}, ids map[string]string, opts ImportOptions) ImportResult {
+	result := ImportResult{Kind: kind, Result: ImportFailed}
+	k, ok := bundleKinds[kind]
+	if !ok {
+		result.Error = fmt.Sprintf("Unknown kind '%s'", kind)
+		return result
+	}
+	result.OldID, _ = m["id"].(string)
+
+	data := remapIDs(copyMap(m), ids).(map[string]interface{})
+	delete(data, "id")
+	delete(data, "uuid")
+	delete(data, setMembersAttribute)
+	if script, ok := data["query"].(string); ok && kind == KindDynamicSet {
+		data["query"] = remapQueryIDs(script, ids)
+	}
+
+	obj := k.newObject(c)
+	err := fillFromSchemaMap(obj, data)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Name = obj.GetName()
+
+	_, err = obj.Query()
+	switch {
+	case err == nil:
+		// Object exists. Map to its ID
+		_, err = obj.GetIDByName()
+		if err == nil && opts.UpdateExisting {
+			_, err = obj.Update()
+			result.Result = ImportUpdated
+		} else {
+			result.Result = ImportSkipped
+		}
+	case IsNotFound(err):
+		if opts.BeforeCreate != nil {
+			err = opts.BeforeCreate(kind, obj)
+		}
+		if err == nil {
+			_, err = obj.Create()
+		}
+		result.Result = ImportCreated
+	}
+	if err != nil {
+		result.Result = ImportFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	result.NewID = obj.GetID()
+	if result.OldID != "" && result.NewID != "" {
+		ids[result.OldID] = result.NewID
+	}
+	return result
+}
+
+func importSetMembers(c *restapi.RestClient, setID string, m map[string]interface{}, ids map[string]string) error {
+	members, _ := m[setMembersAttribute].([]interface{})
+	var newIDs []string
+	for _, v := range members {
+		if id, ok := ids[fmt.Sprintf("%v", v)]; ok {
+			newIDs = append(newIDs, id)
+		}
+	}
+	if len(newIDs) == 0 {
+		return nil
+	}
+
+	set := NewManualSet(c)
+	set.ID = setID
+	set.ObjectType, _ = m["type"].(string)
+	_, err := set.UpdateSetMembers(newIDs, "add")
+	return err
+}
+
+func importPolicyLinks(c *restapi.RestClient, m map[string]interface{}) ImportResult {
+	result := ImportResult{Kind: KindPolicyLinks, Name: KindPolicyLinks, Result: ImportUpdated}
+	obj := NewPolicyLinks(c)
+	err := fillFromSchemaMap(obj, m)
+	if err == nil {
+		_, err = obj.Update()
+	}
+	if err != nil {
+		result.Result = ImportFailed
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func importGlobalWorkflow(c *restapi.RestClient, m map[string]interface{}) ImportResult {
+	wfType, _ := m["type"].(string)
+	result := ImportResult{Kind: KindGlobalWorkflow, Name: wfType, Result: ImportUpdated}
+	obj, err := NewGlobalWorkflow(c, wfType)
+	if err == nil {
+		err = fillFromSchemaMap(obj, m)
+	}
+	if err == nil {
+		_, err = obj.Update()
+	}
+	if err != nil {
+		result.Result = ImportFailed
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// remapIDs replaces every string value that is an old ID with its new ID
+func remapIDs(v interface{}, ids map[string]string) interface{} {
+	switch v := v.(type) {
+	case string:
+		if id, ok := ids[v]; ok {
+			return id
+		}
+		return v
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = remapIDs(item, ids)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = remapIDs(item, ids)
+		}
+		return v
+	}
+	return v
+}
+
+// remapQueryIDs replaces old IDs quoted in query of dynamic set with new IDs
+func remapQueryIDs(script string, ids map[string]string) string {
+	for oldID, newID := range ids {
+		script = strings.Replace(script, "'"+oldID+"'", "'"+newID+"'", -1)
+	}
+	return script
+}
+
+// failedReference returns the first old ID in attributes of m that is in failed. ID of m itself isn't a reference and
+// neither are set members, which are added after all objects are imported
+func failedReference(m map[string]interface{}, failed map[string]bool) string {
+	if len(failed) == 0 {
+		return ""
+	}
+	for k, v := range m {
+		if k == "id" || k == "uuid" || k == setMembersAttribute {
+			continue
+		}
+		if id := findID(v, failed); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+func findID(v interface{}, ids map[string]bool) string {
+	switch v := v.(type) {
+	case string:
+		if ids[v] {
+			return v
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			if id := findID(item, ids); id != "" {
+				return id
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if id := findID(item, ids); id != "" {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// copyMap returns deep copy of m so that bundle isn't changed by remapping
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return m
+	}
+	var c map[string]interface{}
+	if err = json.Unmarshal(data, &c); err != nil {
+		return m
+	}
+	return c
+}
+
+func containsKind(kinds []string, kind string) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
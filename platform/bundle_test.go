@@ -0,0 +1,121 @@
+package platform
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/marcozj/golang-sdk/restapi"
+)
+
+// testAPIError returned by handler of newTestClient is sent as unsuccessful response with the message
+type testAPIError string
+
+// testHTTPStatus returned by handler of newTestClient is sent as HTTP status without body
+type testHTTPStatus int
+
+// newTestClient returns client of a TLS test server that answers each API path with result returned by handler.
+// Caller closes the server
+func newTestClient(t *testing.T, handler func(path string, args map[string]interface{}) interface{}) (*restapi.RestClient, *httptest.Server) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		args := make(map[string]interface{})
+		json.NewDecoder(r.Body).Decode(&args)
+		result := handler(r.URL.Path, args)
+		if status, ok := result.(testHTTPStatus); ok {
+			w.WriteHeader(int(status))
+			return
+		}
+		if msg, ok := result.(testAPIError); ok {
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "Message": string(msg)})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "Result": result})
+	}))
+	c, err := restapi.GetNewRestClient(srv.URL, func() *http.Client { return srv.Client() })
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	return c, srv
+}
+
+func redRockRows(rows ...map[string]interface{}) map[string]interface{} {
+	var results []interface{}
+	for _, row := range rows {
+		results = append(results, map[string]interface{}{"Row": row})
+	}
+	return map[string]interface{}{"Results": results, "FullCount": len(rows)}
+}
+
+func TestExportSets(t *testing.T) {
+	c, srv := newTestClient(t, func(path string, args map[string]interface{}) interface{} {
+		switch path {
+		case "/RedRock/query":
+			script, _ := args["Script"].(string)
+			switch {
+			case strings.Contains(script, "'ManualBucket'"):
+				return redRockRows(map[string]interface{}{"ID": "ms1"})
+			case strings.Contains(script, "'SqlDynamic'"):
+				return redRockRows(map[string]interface{}{"ID": "ds1"})
+			}
+		case "/Collection/GetCollection":
+			if args["ID"] == "ds1" {
+				return map[string]interface{}{"ID": "ds1", "Name": "Prod", "ObjectType": "Server", "CollectionType": "SqlDynamic",
+					"Filters": "SELECT ID FROM Server WHERE DomainID = 'dom1'"}
+			}
+			return map[string]interface{}{"ID": "ms1", "Name": "Web", "ObjectType": "Server", "CollectionType": "ManualBucket"}
+		case "/Collection/GetMembers":
+			if args["ID"] == "ds1" {
+				t.Error("members of dynamic set must not be exported")
+			}
+			return []interface{}{map[string]interface{}{"Key": "srv1"}}
+		}
+		t.Errorf("unexpected call to %s", path)
+		return nil
+	})
+	defer srv.Close()
+
+	b, err := Export(c, ExportOptions{Kinds: []string{KindSet, KindDynamicSet}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(b.Objects[KindSet]); n != 1 {
+		t.Fatalf("got %d manual sets, want 1", n)
+	}
+	manual := b.Objects[KindSet][0]
+	if members, _ := manual[setMembersAttribute].([]string); len(members) != 1 || members[0] != "srv1" {
+		t.Errorf("manual set members = %v, want [srv1]", manual[setMembersAttribute])
+	}
+
+	if n := len(b.Objects[KindDynamicSet]); n != 1 {
+		t.Fatalf("got %d dynamic sets, want 1", n)
+	}
+	dynamic := b.Objects[KindDynamicSet][0]
+	if dynamic["name"] != "Prod" || dynamic["collection_type"] != "SqlDynamic" || dynamic["query"] != "SELECT ID FROM Server WHERE DomainID = 'dom1'" {
+		t.Errorf("unexpected dynamic set %v", dynamic)
+	}
+	if _, ok := dynamic[setMembersAttribute]; ok {
+		t.Errorf("dynamic set has members %v", dynamic[setMembersAttribute])
+	}
+
+	// Permissions aren't part of bundle
+	for _, m := range []map[string]interface{}{manual, dynamic} {
+		for k := range m {
+			if strings.Contains(k, "permission") {
+				t.Errorf("set %v exported %s", m["name"], k)
+			}
+		}
+	}
+}
+
+func TestRemapQueryIDs(t *testing.T) {
+	ids := map[string]string{"dom1": "dom2", "a": "b"}
+	got := remapQueryIDs("SELECT ID FROM Server WHERE DomainID = 'dom1' AND Name = 'dom1x' AND Description = 'a'", ids)
+	want := "SELECT ID FROM Server WHERE DomainID = 'dom2' AND Name = 'dom1x' AND Description = 'b'"
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
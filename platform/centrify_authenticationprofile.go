@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"strings"
 
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/enum/authmechanism"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -48,7 +48,7 @@ type sliceAPIResponse struct {
 func (o *AuthenticationProfile) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -57,12 +57,12 @@ func (o *AuthenticationProfile) Read() error {
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -74,53 +74,54 @@ func (o *AuthenticationProfile) Read() error {
 }
 
 // Delete function deletes an authentication profile and returns a map that contains deletion result
-func (o *AuthenticationProfile) Delete() (*restapi.GenericMapResponse, error) {
+func (o *AuthenticationProfile) Delete() (*restapi.BaseAPIResponse, error) {
 	return o.deleteObjectMapAPI("uuid")
 }
 
 // Create function creates an authentication profile and returns a map that contains update result
-func (o *AuthenticationProfile) Create() (*restapi.GenericMapResponse, error) {
+func (o *AuthenticationProfile) Create() (*restapi.BaseAPIResponse, error) {
 	var queryArg = make(map[string]interface{})
 
 	// Flatten chanllenges data first
 	err := o.flattenChallenges()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	// Flatten NumberOfQuestions
 	o.falttenNumberOfQuestions()
 	settings, err := generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	queryArg["settings"] = settings
 
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
 
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallGenericMapAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	// Assign ID after successful creation so that the same object can be used for subsequent update operation
 	o.ID = resp.Result["Uuid"].(string)
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Update function updates an existing authentication profile and returns a map that contains update result
-func (o *AuthenticationProfile) Update() (*restapi.GenericMapResponse, error) {
+func (o *AuthenticationProfile) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -128,32 +129,62 @@ func (o *AuthenticationProfile) Update() (*restapi.GenericMapResponse, error) {
 	// Flatten chanllenges data first
 	err := o.flattenChallenges()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	// Flatten NumberOfQuestions
 	o.falttenNumberOfQuestions()
 	settings, err := generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	queryArg["settings"] = settings
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
 
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
+}
+
+// Validate checks authentication profile attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *AuthenticationProfile) Validate() error {
+	v := &validator{}
+	v.required("name", o.Name)
+	v.check(o.DurationInMinutes >= 0, "pass_through_duration", "can't be negative")
+
+	// Challenges are given either as comma separated string or as Challenge1 and Challenge2 lists
+	challenges := o.Challenges
+	if len(challenges) == 0 && len(o.Challenge1) > 0 {
+		challenges = []string{strings.Join(o.Challenge1, ",")}
+		if len(o.Challenge2) > 0 {
+			challenges = append(challenges, strings.Join(o.Challenge2, ","))
+		}
+	}
+	if len(challenges) == 0 || len(challenges) > 2 {
+		v.add("challenges", "one or two challenges are required")
+	}
+	for i, c := range challenges {
+		for _, m := range strings.Split(c, ",") {
+			v.oneOf(fmt.Sprintf("challenges[%d]", i), m, authmechanism.Values())
+		}
+	}
+	if o.AdditionalData != nil {
+		v.check(o.AdditionalData.NumberOfQuestions >= 0, "additional_data.number_of_questions", "can't be negative")
+	}
+
+	return v.err(o, o.Name)
 }
 
 // Query function returns a single authentication profile object
@@ -166,18 +197,18 @@ func (o *AuthenticationProfile) Query() (map[string]interface{}, error) {
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallRawAPI("/AuthProfile/GetProfileList", queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	reply := &sliceAPIResponse{}
 	err = json.Unmarshal(resp, &reply)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, fmt.Errorf("Failed to unmarshal sliceAPIResponse from HTTP response: %v", err)
 	}
 	if !reply.Success {
-		logger.Errorf(reply.Message)
+		o.client.Log().Errorf(reply.Message)
 		return nil, fmt.Errorf(reply.Message)
 	}
 
@@ -192,7 +223,7 @@ func (o *AuthenticationProfile) Query() (map[string]interface{}, error) {
 
 	err = queryError(len(autheProfs))
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
@@ -205,11 +236,17 @@ func (o *AuthenticationProfile) GetIDByName() (string, error) {
 		return "", fmt.Errorf("Authentication profile name must be provided")
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
 		return "", fmt.Errorf("Error retrieving authentication profile: %s", err)
 	}
 	o.ID = result["Uuid"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, "", o.ID)
 
 	return o.ID, nil
 }
@@ -225,14 +262,14 @@ func (o *AuthenticationProfile) GetByName() error {
 
 	err := o.Read()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	return nil
 }
 
 // DeleteByName deletes a authentication profile by name
-func (o *AuthenticationProfile) DeleteByName() (*restapi.GenericMapResponse, error) {
+func (o *AuthenticationProfile) DeleteByName() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
@@ -241,7 +278,7 @@ func (o *AuthenticationProfile) DeleteByName() (*restapi.GenericMapResponse, err
 	}
 	resp, err := o.Delete()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
@@ -3,8 +3,9 @@ package platform
 import (
 	"fmt"
 
+	"github.com/marcozj/golang-sdk/enum/cloudprovidertype"
 	"github.com/marcozj/golang-sdk/enum/settype"
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -35,6 +36,8 @@ func NewCloudProvider(c *restapi.RestClient) *CloudProvider {
 	s.apiDelete = "/CloudProvider/DeleteCloudProviders"
 	s.apiUpdate = "/CloudProvider/UpdateCloudProvider"
 	s.apiPermissions = "/CloudProvider/SetCloudProviderPermissions"
+	s.apiGetPermissions = "/Acl/GetRowAces"
+	s.aclTable = "CloudProviders"
 	s.apiAddAccount = "/ServerManage/AddAccount"
 	s.apiSetAccountPermissions = "ServerManage/SetAccountPermissions"
 
@@ -45,7 +48,7 @@ func NewCloudProvider(c *restapi.RestClient) *CloudProvider {
 func (o *CloudProvider) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -55,12 +58,12 @@ func (o *CloudProvider) Read() error {
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -70,69 +73,71 @@ func (o *CloudProvider) Read() error {
 }
 
 // Create function creates a new CloudProvider and returns a map that contains creation result
-func (o *CloudProvider) Create() (*restapi.StringResponse, error) {
+func (o *CloudProvider) Create() (*restapi.BaseAPIResponse, error) {
 	var queryArg = make(map[string]interface{})
 	queryArg, err := generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
 
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallStringAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	// Assign ID after successful creation so that the same object can be used for subsequent update operation
 	o.ID = resp.Result
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Update function updates a existing CloudProvider and returns a map that contains update result
-func (o *CloudProvider) Update() (*restapi.StringResponse, error) {
+func (o *CloudProvider) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
 	queryArg, err := generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
 
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallStringAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Delete function deletes a CloudProvider and returns a string result that contains deletion result
-func (o *CloudProvider) Delete() (*restapi.StringResponse, error) {
+func (o *CloudProvider) Delete() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -142,30 +147,46 @@ func (o *CloudProvider) Delete() (*restapi.StringResponse, error) {
 
 	resp, err := o.client.CallStringAPI(o.apiDelete, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
+	o.resolver().InvalidateID(o.ID)
 
-	return resp, nil
+	return resp.ToBase(), nil
+}
+
+// Validate checks cloud provider attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *CloudProvider) Validate() error {
+	v := &validator{}
+	v.required("name", o.Name)
+	v.required("cloud_account_id", o.CloudAccountID)
+	v.oneOf("type", o.Type, cloudprovidertype.Values())
+	if o.EnableUnmanagedPasswordRotationReminder {
+		v.check(o.UnmanagedPasswordRotationReminderDuration > 0, "password_rotation_reminder_duration", "is required when enable_password_rotation_reminders is enabled")
+	}
+	v.inRange("password_rotation_reminder_duration", o.UnmanagedPasswordRotationReminderDuration, 1, maxInt32)
+	v.challengeRules("challenge_rule", o.ChallengeRules)
+
+	return v.err(o, o.Name)
 }
 
 // Query function returns a single CloudProvider object in map format
 func (o *CloudProvider) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM CloudProviders WHERE 1=1"
+	q := query.Select("CloudProviders")
 	if o.Name != "" {
-		query += " AND Name='" + o.Name + "'"
+		q.Where(query.Eq("Name", o.Name))
 	}
 	if o.CloudAccountID != "" {
-		query += " AND CloudAccountId='" + o.CloudAccountID + "'"
+		q.Where(query.Eq("CloudAccountId", o.CloudAccountID))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 // GetIDByName returns CloudProvider ID by name
@@ -177,13 +198,19 @@ func (o *CloudProvider) GetIDByName() (string, error) {
 		return "", fmt.Errorf("CloudProvider account id must be provided")
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, o.CloudAccountID); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
 		errmsg := fmt.Sprintf("Error retrieving cloud provider: %s", err)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return "", fmt.Errorf(errmsg)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, o.CloudAccountID, o.ID)
 
 	return o.ID, nil
 }
@@ -199,14 +226,14 @@ func (o *CloudProvider) GetByName() error {
 
 	err := o.Read()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	return nil
 }
 
 // DeleteByName deletes a CloudProvider by name
-func (o *CloudProvider) DeleteByName() (*restapi.StringResponse, error) {
+func (o *CloudProvider) DeleteByName() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
@@ -215,7 +242,7 @@ func (o *CloudProvider) DeleteByName() (*restapi.StringResponse, error) {
 	}
 	resp, err := o.Delete()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
@@ -454,3 +481,39 @@ Delete Cloud Provider
 	}
 
 */
+
+// CloudProviderFilter selects cloud providers returned by ListCloudProviders. Empty fields are ignored
+type CloudProviderFilter struct {
+	ListOptions
+	Name           string // Substring of cloud provider name
+	CloudAccountID string
+	SetID          string // Only cloud providers in this set
+}
+
+// CloudProviderIterator iterates over cloud providers returned by ListCloudProviders
+type CloudProviderIterator struct {
+	*Iterator
+}
+
+// Value returns current cloud provider. Only attributes returned by query are filled, call Read() for the rest
+func (it *CloudProviderIterator) Value() *CloudProvider {
+	obj := NewCloudProvider(it.client)
+	it.decode(obj)
+	return obj
+}
+
+// ListCloudProviders returns iterator over cloud providers that match filter. Pages are fetched as iteration goes
+func ListCloudProviders(c *restapi.RestClient, filter CloudProviderFilter) *CloudProviderIterator {
+	q, err := listQuery(c, "CloudProviders", filter.SetID)
+	if err != nil {
+		return &CloudProviderIterator{errIterator(err)}
+	}
+	if filter.Name != "" {
+		q.Where(query.Contains("Name", filter.Name))
+	}
+	if filter.CloudAccountID != "" {
+		q.Where(query.Eq("CloudAccountId", filter.CloudAccountID))
+	}
+
+	return &CloudProviderIterator{newIterator(c, q, filter.ListOptions)}
+}
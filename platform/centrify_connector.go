@@ -3,7 +3,7 @@ package platform
 import (
 	"fmt"
 
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -42,49 +42,62 @@ func (o *Connector) Read() error {
 	return nil
 }
 
+// Create isn't supported for Connector. Connector is registered by installing it on a host
+func (o *Connector) Create() (*restapi.BaseAPIResponse, error) {
+	return nil, fmt.Errorf("%s can't be created through API", GetVarType(o))
+}
+
 // Delete function deletes a Connector and returns a map that contains deletion result
-func (o *Connector) Delete() (*restapi.GenericMapResponse, error) {
+func (o *Connector) Delete() (*restapi.BaseAPIResponse, error) {
 	return o.deleteObjectMapAPI("")
 }
 
 // Update function updates an existing Connector and returns a map that contains update result
-func (o *Connector) Update() (*restapi.GenericMapResponse, error) {
+func (o *Connector) Update() (*restapi.BaseAPIResponse, error) {
 	return nil, nil
 }
 
+// Validate checks connector attributes. Connector is registered by installer so only name is checked
+func (o *Connector) Validate() error {
+	v := &validator{}
+	v.required("name", o.Name)
+
+	return v.err(o, o.Name)
+}
+
 // Query function returns a single Connector object in map format
 func (o *Connector) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM Proxy WHERE 1=1"
+	q := query.Select("Proxy")
 	if o.Name != "" {
-		query += " AND Name='" + o.Name + "'"
+		q.Where(query.Eq("Name", o.Name))
 	}
 	if o.Status != "" {
 		if o.Status == "Active" {
-			query += " AND Online=1"
+			q.Where(query.Eq("Online", true))
 		} else {
-			query += " AND Online=0"
+			q.Where(query.Eq("Online", false))
 		}
 	}
 	if o.Version != "" {
-		query += " AND Version='" + o.Version + "'"
+		q.Where(query.Eq("Version", o.Version))
 	}
 	if o.VpcIdentifier != "" {
-		query += " AND VpcIdentifier='" + o.VpcIdentifier + "'"
+		q.Where(query.Eq("VpcIdentifier", o.VpcIdentifier))
 	}
-	if o.VpcIdentifier != "" {
-		query += " AND VmIdentifier='" + o.VmIdentifier + "'"
+	if o.VmIdentifier != "" {
+		q.Where(query.Eq("VmIdentifier", o.VmIdentifier))
 	}
 	if o.MachineName != "" {
-		query += " AND MachineName='" + o.MachineName + "'"
+		q.Where(query.Eq("MachineName", o.MachineName))
 	}
 	if o.DnsHostName != "" {
-		query += " AND DnsHostName='" + o.DnsHostName + "'"
+		q.Where(query.Eq("DnsHostName", o.DnsHostName))
 	}
 	if o.Forest != "" {
-		query += " AND Forest='" + o.Forest + "'"
+		q.Where(query.Eq("Forest", o.Forest))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 // GetIDByName returns vault object ID by name
@@ -93,12 +106,18 @@ func (o *Connector) GetIDByName() (string, error) {
 		return "", fmt.Errorf("%s name must be provided", GetVarType(o))
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("error retrieving %s: %s", GetVarType(o), err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, "", o.ID)
 
 	return o.ID, nil
 }
@@ -111,7 +130,7 @@ func (o *Connector) GetByName() error {
 
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return fmt.Errorf("error retrieving %s: %s", GetVarType(o), err)
 	}
 	mapToStruct(o, result)
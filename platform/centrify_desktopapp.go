@@ -5,10 +5,12 @@ import (
 	"strings"
 
 	"github.com/marcozj/golang-sdk/enum/computerclass"
+	"github.com/marcozj/golang-sdk/enum/desktopapp/applicationtemplate"
 	"github.com/marcozj/golang-sdk/enum/desktopapp/cmdparamtype"
+	"github.com/marcozj/golang-sdk/enum/desktopapp/logincredential"
 	"github.com/marcozj/golang-sdk/enum/resourcetype"
 	"github.com/marcozj/golang-sdk/enum/settype"
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -55,6 +57,8 @@ func NewDesktopApp(c *restapi.RestClient) *DesktopApp {
 	s.apiDelete = "/SaasManage/DeleteApplication"
 	s.apiUpdate = "/SaasManage/UpdateApplicationDE"
 	s.apiPermissions = "/SaasManage/SetApplicationPermissions"
+	s.apiGetPermissions = "/Acl/GetRowAces"
+	s.aclTable = "Application"
 
 	return &s
 }
@@ -63,7 +67,7 @@ func NewDesktopApp(c *restapi.RestClient) *DesktopApp {
 func (o *DesktopApp) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -73,13 +77,13 @@ func (o *DesktopApp) Read() error {
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -89,7 +93,7 @@ func (o *DesktopApp) Read() error {
 }
 
 // Create function creates a new DesktopApp and returns a map that contains creation result
-func (o *DesktopApp) Create() (*restapi.SliceResponse, error) {
+func (o *DesktopApp) Create() (*restapi.BaseAPIResponse, error) {
 	// Resolve DesktopAppRunHostID, DesktopAppRunAccountID and TargetObjectID of parameters
 	err := o.resolveIDs()
 	if err != nil {
@@ -98,36 +102,37 @@ func (o *DesktopApp) Create() (*restapi.SliceResponse, error) {
 
 	err = o.processWorkflow()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	var queryArg = make(map[string]interface{})
 	queryArg["ID"] = []string{o.TemplateName}
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
 
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallSliceAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	// Assign ID after successful creation so that the same object can be used for subsequent update operation
 	o.ID = resp.Result[0].(map[string]interface{})["_RowKey"].(string)
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Update function updates an existing DesktopApp and returns a map that contains update result
-func (o *DesktopApp) Update() (*restapi.GenericMapResponse, error) {
+func (o *DesktopApp) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
@@ -139,39 +144,40 @@ func (o *DesktopApp) Update() (*restapi.GenericMapResponse, error) {
 
 	err = o.processWorkflow()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	var queryArg = make(map[string]interface{})
 	queryArg, err = generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	queryArg["_RowKey"] = o.ID
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
 
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Delete function deletes a DesktopApp and returns a map that contains deletion result
-func (o *DesktopApp) Delete() (*restapi.SliceResponse, error) {
+func (o *DesktopApp) Delete() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -179,26 +185,53 @@ func (o *DesktopApp) Delete() (*restapi.SliceResponse, error) {
 
 	resp, err := o.client.CallSliceAPI(o.apiDelete, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
-	return resp, nil
+	o.resolver().InvalidateID(o.ID)
+
+	return resp.ToBase(), nil
+}
+
+// Validate checks desktop app attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *DesktopApp) Validate() error {
+	v := &validator{}
+	v.required("name", o.Name)
+	v.required("template_name", o.TemplateName)
+	v.oneOf("template_name", o.TemplateName, applicationtemplate.Values())
+	v.check(o.DesktopAppRunHostID != "" || o.DesktopAppRunHostName != "", "application_host_id", "is required")
+	v.oneOf("login_credential_type", o.DesktopAppRunAccountType, logincredential.Values())
+	switch o.DesktopAppRunAccountType {
+	case logincredential.SelectAlternativeAccount.String(), logincredential.SharedAccount.String():
+		v.check(o.DesktopAppRunAccountID != "" || o.DesktopAppRunAccountName != "", "application_account_id",
+			"is required when login_credential_type is %s", o.DesktopAppRunAccountType)
+	}
+	for i, p := range o.DesktopAppParams {
+		path := fmt.Sprintf("command_parameter[%d]", i)
+		v.required(path+".name", p.ParamName)
+		v.required(path+".type", p.ParamType)
+		v.oneOf(path+".type", p.ParamType, cmdparamtype.Values())
+	}
+	v.challengeRules("challenge_rule", o.ChallengeRules)
+	v.approvers("workflow_approver", o.WorkflowEnabled && o.WorkflowSettings == "", o.WorkflowApproverList)
+
+	return v.err(o, o.Name)
 }
 
 // Query function returns a single DesktopApp object in map format
 func (o *DesktopApp) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM Application WHERE 1=1 AND AppType='Desktop'"
+	q := query.Select("Application").Where(query.Eq("AppType", "Desktop"))
 	if o.Name != "" {
-		query += " AND Name='" + o.Name + "'"
+		q.Where(query.Eq("Name", o.Name))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 // GetIDByName returns vault object ID by name
@@ -207,12 +240,18 @@ func (o *DesktopApp) GetIDByName() (string, error) {
 		return "", fmt.Errorf("%s name must be provided", GetVarType(o))
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("error retrieving %s: %s", GetVarType(o), err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, "", o.ID)
 
 	return o.ID, nil
 }
@@ -222,7 +261,7 @@ func (o *DesktopApp) GetByName() error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf("failed to find ID of %s %s. %v", GetVarType(o), o.Name, err)
 		}
 	}
@@ -235,11 +274,11 @@ func (o *DesktopApp) GetByName() error {
 }
 
 // DeleteByName deletes a DesktopApp by name
-func (o *DesktopApp) DeleteByName() (*restapi.SliceResponse, error) {
+func (o *DesktopApp) DeleteByName() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, fmt.Errorf("failed to find ID of DesktopApp %s. %v", o.Name, err)
 		}
 	}
@@ -255,19 +294,19 @@ func (o *DesktopApp) resolveIDs() error {
 	var err error
 	err = o.resolveApplicationHostID()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
 	err = o.resolveApplicationRunAccountID()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
 	err = o.resolveTargetObjectID()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
@@ -282,7 +321,7 @@ func (o *DesktopApp) resolveApplicationHostID() error {
 		var err error
 		o.DesktopAppRunHostID, err = system.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf(err.Error())
 		}
 	}
@@ -313,7 +352,7 @@ func (o *DesktopApp) resolveApplicationRunAccountID() error {
 
 		o.DesktopAppRunAccountID, err = account.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf(err.Error())
 		}
 	}
@@ -371,7 +410,7 @@ func (o *DesktopApp) resolveTargetObjectID() error {
 			}
 
 			if err != nil {
-				logger.Errorf(err.Error())
+				o.client.Log().Errorf(err.Error())
 				return fmt.Errorf(err.Error())
 			}
 
@@ -609,3 +648,36 @@ https://developer.centrify.com/reference#post_saasmanage-deleteapplication
 		"InnerExceptions": null
 	}
 */
+
+// DesktopAppFilter selects desktop apps returned by ListDesktopApps. Empty fields are ignored
+type DesktopAppFilter struct {
+	ListOptions
+	Name  string // Substring of application name
+	SetID string // Only desktop apps in this set
+}
+
+// DesktopAppIterator iterates over desktop apps returned by ListDesktopApps
+type DesktopAppIterator struct {
+	*Iterator
+}
+
+// Value returns current desktop app. Only attributes returned by query are filled, call Read() for the rest
+func (it *DesktopAppIterator) Value() *DesktopApp {
+	obj := NewDesktopApp(it.client)
+	it.decode(obj)
+	return obj
+}
+
+// ListDesktopApps returns iterator over desktop apps that match filter. Pages are fetched as iteration goes
+func ListDesktopApps(c *restapi.RestClient, filter DesktopAppFilter) *DesktopAppIterator {
+	q, err := listQuery(c, "Application", filter.SetID)
+	if err != nil {
+		return &DesktopAppIterator{errIterator(err)}
+	}
+	q.Where(query.Eq("AppType", "Desktop"))
+	if filter.Name != "" {
+		q.Where(query.Contains("Name", filter.Name))
+	}
+
+	return &DesktopAppIterator{newIterator(c, q, filter.ListOptions)}
+}
@@ -2,7 +2,9 @@ package platform
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -45,11 +47,11 @@ func (o *DirectoryObjects) Read() error {
 	queryArg["directoryServices"] = o.DirectoryServices
 	switch o.ObjectType {
 	case "User":
-		queryArg["user"] = "{\"_and\":[{\"SystemName\":{\"_like\":\"" + o.QueryName + "\"}},{\"ObjectType\":\"User\"}]}"
+		queryArg["user"] = query.FilterAnd(query.FilterLike("SystemName", o.QueryName), query.FilterEq("ObjectType", "User")).String()
 	case "Group":
-		queryArg["group"] = "{\"SystemName\":{\"_like\":\"" + o.QueryName + "\"}}"
+		queryArg["group"] = query.FilterLike("SystemName", o.QueryName).String()
 	case "Role":
-		queryArg["roles"] = "{\"Name\":{\"_like\":\"" + o.QueryName + "\"}}"
+		queryArg["roles"] = query.FilterLike("Name", o.QueryName).String()
 	}
 
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
@@ -94,7 +96,21 @@ func (o *DirectoryObjects) GetByName(objType string, name string, dir DirectoryS
 		return nil, fmt.Errorf("query returns 0 object for directory object %s", name)
 	}
 	if len(o.DirectoryObjects) > 1 {
-		return nil, fmt.Errorf("search directory object %s, but returns too many objects (found %d, expected 1)", name, len(o.DirectoryObjects))
+		// Search is substring match so prefer the object whose name is exactly the same
+		var exact []DirectoryObject
+		for _, obj := range o.DirectoryObjects {
+			if strings.EqualFold(obj.SystemName, name) || strings.EqualFold(obj.Name, name) {
+				exact = append(exact, obj)
+			}
+		}
+		switch len(exact) {
+		case 1:
+			return &exact[0], nil
+		case 0:
+			return nil, fmt.Errorf("search directory object %s returns %d objects but none of them matches exactly", name, len(o.DirectoryObjects))
+		default:
+			return nil, fmt.Errorf("search directory object %s, but returns too many objects (found %d, expected 1)", name, len(exact))
+		}
 	}
 
 	return &o.DirectoryObjects[0], nil
@@ -0,0 +1,172 @@
+package platform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/marcozj/golang-sdk/enum/setsubtype"
+	"github.com/marcozj/golang-sdk/enum/settype"
+	"github.com/marcozj/golang-sdk/platform/query"
+	"github.com/marcozj/golang-sdk/restapi"
+)
+
+// DynamicSet - Encapsulates a query based set whose members are objects matched by its query
+type DynamicSet struct {
+	ManualSet
+
+	Filters string `json:"Filters,omitempty" schema:"query,omitempty"` // SQL query that selects ID of members
+}
+
+// NewDynamicSet is a DynamicSet constructor
+func NewDynamicSet(c *restapi.RestClient) *DynamicSet {
+	s := DynamicSet{}
+	s.ManualSet = *NewManualSet(c)
+	s.CollectionType = "SqlDynamic"
+	s.apiCreate = "/Collection/CreateDynamicCollection"
+
+	return &s
+}
+
+// NewDynamicSetWithType is another DynamicSet constructor that initialise memberpermissions api endpiont
+func NewDynamicSetWithType(c *restapi.RestClient, setType string) (*DynamicSet, error) {
+	s := NewDynamicSet(c)
+	s.ObjectType = setType
+	err := s.ResolveValidMemberPerms()
+	if err != nil {
+		c.Log().Errorf(err.Error())
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SetQuery sets query of the set from conditions. Objects of set type that satisfy all conditions become members, e.g.
+//
+//	s.SetQuery(query.Eq("ComputerClass", "Unix"), query.Contains("FQDN", ".prod.example.com"))
+func (o *DynamicSet) SetQuery(conditions ...query.Condition) error {
+	if o.ObjectType == "" {
+		return fmt.Errorf("Set type must be provided")
+	}
+	if len(conditions) == 0 {
+		return fmt.Errorf("At least one condition is required for dynamic set")
+	}
+	script, err := query.Select(o.ObjectType, "ID").Where(conditions...).Build()
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return err
+	}
+	o.Filters = script
+
+	return nil
+}
+
+// Preview returns ID of objects that currently match query of the set. It can be called before the set is saved
+func (o *DynamicSet) Preview() ([]string, error) {
+	if o.Filters == "" {
+		return nil, fmt.Errorf("Dynamic set query is empty")
+	}
+	results, err := RedRockQuery(o.client, o.Filters, nil)
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return nil, err
+	}
+
+	return rowIDs(results, "ID"), nil
+}
+
+// Read function fetches a DynamicSet from source, including its query. Returns error if any
+func (o *DynamicSet) Read() error {
+	return o.read(o)
+}
+
+// Create function creates a new Dynamic Set and returns a map that contains creation result
+func (o *DynamicSet) Create() (*restapi.BaseAPIResponse, error) {
+	return o.create(o)
+}
+
+// Update function updates an existing Dynamic Set including its query and returns a map that contains update result
+func (o *DynamicSet) Update() (*restapi.BaseAPIResponse, error) {
+	return o.update(o)
+}
+
+// Validate checks set attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *DynamicSet) Validate() error {
+	v := &validator{}
+	v.required("name", o.Name)
+	v.required("type", o.ObjectType)
+	v.oneOf("type", o.ObjectType, settype.Values())
+	if o.SubObjectType != "" {
+		v.check(o.ObjectType == settype.Application.String(), "subtype", "is only applicable to Application set")
+		v.oneOf("subtype", o.SubObjectType, setsubtype.Values())
+	}
+	v.required("query", o.Filters)
+	if o.Filters != "" && o.ObjectType != "" {
+		v.check(strings.Contains(strings.ToLower(o.Filters), " from "+strings.ToLower(o.ObjectType)), "query", "must select from %s", o.ObjectType)
+	}
+
+	return v.err(o, o.Name)
+}
+
+// GetByName retrieves set from tenant by name
+func (o *DynamicSet) GetByName() error {
+	return o.getByName(o)
+}
+
+// UpdateSetMembers always fails since members of dynamic set are determined by its query
+func (o *DynamicSet) UpdateSetMembers(ids []string, action string) (*restapi.StringResponse, error) {
+	return nil, fmt.Errorf("Members of dynamic set %s are determined by its query and can't be updated", o.Name)
+}
+
+// SyncMembers always fails since members of dynamic set are determined by its query
+func (o *DynamicSet) SyncMembers(desired []string) (*SetMembersDiff, error) {
+	return nil, fmt.Errorf("Members of dynamic set %s are determined by its query and can't be updated", o.Name)
+}
+
+// ResolveSetIDs returns ID of sets of setType by name. Both manual and dynamic sets are found so the IDs can be used
+// in policy links, e.g. Policy.Plink.Params of "Collection" link type
+func ResolveSetIDs(c *restapi.RestClient, setType string, names []string) ([]string, error) {
+	var ids []string
+	for _, name := range names {
+		set := NewManualSet(c)
+		set.Name = name
+		set.ObjectType = setType
+		set.CollectionType = "" // Match any type of set
+		id, err := set.GetIDByName()
+		if err != nil || id == "" {
+			return nil, fmt.Errorf("%s type Set %s doesn't exist", setType, name)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+/*
+	API to manage dynamic set
+
+	Create Dynamic Set
+
+		Request body format
+		{
+			"ObjectType": "Server",
+			"Name": "Prod Unix Systems",
+			"Description": "All Unix systems in prod domain",
+			"CollectionType": "SqlDynamic",
+			"Filters": "SELECT ID FROM Server WHERE ComputerClass = 'Unix' AND FQDN LIKE '%.prod.example.com' ESCAPE '\\'"
+		}
+
+		Respond result
+		{
+			"success": true,
+			"Result": "xxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxx",
+			"Message": null,
+			"MessageID": null,
+			"Exception": null,
+			"ErrorID": null,
+			"ErrorCode": null,
+			"IsSoftError": false,
+			"InnerExceptions": null
+		}
+
+	Get, Update and Delete use the same API as manual set. Filters attribute contains the query
+*/
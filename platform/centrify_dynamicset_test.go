@@ -0,0 +1,62 @@
+package platform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/marcozj/golang-sdk/restapi"
+)
+
+func TestDynamicSetLookup(t *testing.T) {
+	queries := 0
+	c, srv := newTestClient(t, func(path string, args map[string]interface{}) interface{} {
+		switch path {
+		case "/RedRock/query":
+			queries++
+			script, _ := args["Script"].(string)
+			if strings.Contains(script, "'SqlDynamic'") {
+				return redRockRows(map[string]interface{}{"ID": "ds1", "Name": "Prod"})
+			}
+			return redRockRows(map[string]interface{}{"ID": "ms1", "Name": "Prod"})
+		case "/Collection/GetCollection":
+			return map[string]interface{}{"ID": args["ID"], "Name": "Prod", "ObjectType": "Server", "CollectionType": "SqlDynamic",
+				"Filters": "SELECT ID FROM Server WHERE ComputerClass = 'Unix'"}
+		}
+		t.Errorf("unexpected call to %s", path)
+		return nil
+	})
+	defer srv.Close()
+	c.Resolver = restapi.NewResolverCache(0)
+
+	// Manual and dynamic set of the same name and type are cached separately
+	manual := NewManualSet(c)
+	manual.Name = "Prod"
+	manual.ObjectType = "Server"
+	if id, err := manual.GetIDByName(); err != nil || id != "ms1" {
+		t.Fatalf("manual set ID = %s, %v, want ms1", id, err)
+	}
+
+	dynamic := NewDynamicSet(c)
+	dynamic.Name = "Prod"
+	dynamic.ObjectType = "Server"
+	err := dynamic.GetByName()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dynamic.ID != "ds1" {
+		t.Errorf("dynamic set ID = %s, want ds1", dynamic.ID)
+	}
+	if dynamic.Filters != "SELECT ID FROM Server WHERE ComputerClass = 'Unix'" {
+		t.Errorf("query wasn't read: %q", dynamic.Filters)
+	}
+
+	again := NewDynamicSet(c)
+	again.Name = "Prod"
+	again.ObjectType = "Server"
+	if id, err := again.GetIDByName(); err != nil || id != "ds1" {
+		t.Errorf("cached dynamic set ID = %s, %v, want ds1", id, err)
+	}
+	if queries != 2 {
+		t.Errorf("got %d queries, want 2", queries)
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"fmt"
 
 	"github.com/marcozj/golang-sdk/enum/directoryservice"
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -38,14 +38,14 @@ func NewFederatedGroup(c *restapi.RestClient) *FederatedGroup {
 func (o *FederatedGroup) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	// Get Federated Directory Service
 	ds := NewDirectoryServices(o.client)
 	theds, err := ds.GetByName(directoryservice.FederatedDirectory.String(), "Federated Directory Service")
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
@@ -53,16 +53,16 @@ func (o *FederatedGroup) Read() error {
 	var queryArg = make(map[string]interface{})
 	queryArg["Args"] = subArgs
 	queryArg["directoryServices"] = []string{theds.ID}
-	queryArg["group"] = "{\"InternalName\":{\"_like\":\"" + o.ID + "\"}}"
+	queryArg["group"] = query.FilterLike("InternalName", o.ID).String()
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -102,7 +102,7 @@ func (o *FederatedGroup) Create() (string, error) {
 	fedgrp.Name = o.Name
 	id, err := fedgrp.GetIDByName()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", err
 	}
 	/*
@@ -125,12 +125,16 @@ func (o *FederatedGroup) GetIDByName() (string, error) {
 	if o.Name == "" {
 		return "", fmt.Errorf("group name must be provided")
 	}
+	if id, ok := o.client.Resolver.Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
 
 	// Get Federated Directory Service
 	ds := NewDirectoryServices(o.client)
 	theds, err := ds.GetByName(directoryservice.FederatedDirectory.String(), "Federated Directory Service")
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", err
 	}
 
@@ -138,16 +142,16 @@ func (o *FederatedGroup) GetIDByName() (string, error) {
 	var queryArg = make(map[string]interface{})
 	queryArg["Args"] = subArgs
 	queryArg["directoryServices"] = []string{theds.ID}
-	queryArg["group"] = "{\"SystemName\":{\"_like\":\"" + o.Name + "\"}}"
+	queryArg["group"] = query.FilterLike("SystemName", o.Name).String()
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return "", fmt.Errorf(errmsg)
 	}
 
@@ -162,6 +166,7 @@ func (o *FederatedGroup) GetIDByName() (string, error) {
 		row = result.(map[string]interface{})["Row"].(map[string]interface{})
 		if row["SystemName"] == o.Name {
 			o.ID = row["InternalName"].(string)
+			o.client.Resolver.Set(GetVarType(o), o.Name, "", o.ID)
 			return o.ID, nil
 		}
 	}
@@ -2,8 +2,10 @@ package platform
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 
-	logger "github.com/marcozj/golang-sdk/logging"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -23,20 +25,26 @@ type vaultObject struct {
 	Permissions []Permission `json:"-"`
 
 	// API endpoints
-	apiRead        string //`json:"-"` // Ignoring this JSON field
-	apiCreate      string //`json:"-"` // Ignoring this JSON field
-	apiDelete      string //`json:"-"` // Ignoring this JSON field
-	apiUpdate      string //`json:"-"` // Ignoring this JSON field
-	apiPermissions string //`json:"-"`
+	apiRead           string //`json:"-"` // Ignoring this JSON field
+	apiCreate         string //`json:"-"` // Ignoring this JSON field
+	apiDelete         string //`json:"-"` // Ignoring this JSON field
+	apiUpdate         string //`json:"-"` // Ignoring this JSON field
+	apiPermissions    string //`json:"-"`
+	apiGetPermissions string // API to read permissions
+	aclTable          string // Table of the object used by apiGetPermissions
 }
 
 // Permission represents object permission
 type Permission struct {
 	PrincipalID   string   `json:"PrincipalId,omitempty" schema:"principal_id,omitempty"` // Uuid of the principal
-	PrincipalName string   `json:"Principal,omitempty" schema:"principal_name,omitempty"` // User name or role name
-	PrincipalType string   `json:"PType,omitempty" schema:"principal_type,omitempty"`     // Principal type: User, Role etc..
+	PrincipalName string   `json:"Principal,omitempty" schema:"principal_name,omitempty"` // User, group or role name
+	PrincipalType string   `json:"PType,omitempty" schema:"principal_type,omitempty"`     // Principal type: User, Group or Role
 	Rights        string   `json:"Rights,omitempty" schema:"rights,omitempty"`            // Permissions: Grant,View,Edit,Delete or None to remove this item
 	RightList     []string `json:"-"`
+
+	// Directory of the principal such as "Active Directory" and domain name. Required for Group principal
+	DirectoryService string `json:"-" schema:"directory_service,omitempty"`
+	DirectoryName    string `json:"-" schema:"directory_name,omitempty"`
 }
 
 // ChallengeRules represents list of login rule set
@@ -78,16 +86,16 @@ type WorkflowApprover struct {
 	NoManagerAction  string          `json:"NoManagerAction,omitempty" schema:"no_manager_action,omitempty"` // Can be "approve", "deny" or "useBackup"
 	BackupApprover   *BackupApprover `json:"BackupApprover,omitempty" schema:"backup_approver,omitempty"`
 	OptionsSelector  bool            `json:"OptionsSelector,omitempty" schema:"options_selector,omitempty"` // When there more than 2 approval levels, add this attribute to only one
-	DirectoryService string          `json:"-"`
-	DirectoryName    string          `json:"-"`
+	DirectoryService string          `json:"-" schema:"directory_service,omitempty"`
+	DirectoryName    string          `json:"-" schema:"directory_name,omitempty"`
 }
 
 type BackupApprover struct {
 	Guid             string `json:"Guid,omitempty" schema:"guid,omitempty"`
 	Name             string `json:"Name,omitempty" schema:"name,omitempty"`
 	Type             string `json:"Type,omitempty" schema:"type,omitempty"` // Either "User" or "Role"
-	DirectoryService string `json:"-"`
-	DirectoryName    string `json:"-"`
+	DirectoryService string `json:"-" schema:"directory_service,omitempty"`
+	DirectoryName    string `json:"-" schema:"directory_name,omitempty"`
 }
 
 type WorkflowDefaultOptions struct {
@@ -109,11 +117,19 @@ type ZoneRole struct {
 	Windows           bool   `json:"Windows,omitempty" schema:"windows,omitempty"`
 }
 
+// resolver returns name to ID cache of client. nil cache is a no-op
+func (o *vaultObject) resolver() *restapi.ResolverCache {
+	if o.client == nil {
+		return nil
+	}
+	return o.client.Resolver
+}
+
 // deleteObjectBoolAPI a object and returns a map that contains deletion result
-func (o *vaultObject) deleteObjectBoolAPI(idfield string) (*restapi.BoolResponse, error) {
+func (o *vaultObject) deleteObjectBoolAPI(idfield string) (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var funcArg = make(map[string]interface{})
@@ -125,24 +141,25 @@ func (o *vaultObject) deleteObjectBoolAPI(idfield string) (*restapi.BoolResponse
 
 	resp, err := o.client.CallBoolAPI(o.apiDelete, funcArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
+	o.resolver().InvalidateID(o.ID)
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // deleteObjectMapAPI a object and returns a map that contains deletion result
-func (o *vaultObject) deleteObjectMapAPI(idfield string) (*restapi.GenericMapResponse, error) {
+func (o *vaultObject) deleteObjectMapAPI(idfield string) (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var funcArg = make(map[string]interface{})
@@ -160,15 +177,16 @@ func (o *vaultObject) deleteObjectMapAPI(idfield string) (*restapi.GenericMapRes
 	if !reply.Success {
 		return nil, fmt.Errorf(reply.Message)
 	}
+	o.resolver().InvalidateID(o.ID)
 
-	return reply, nil
+	return reply.ToBase(), nil
 }
 
 // deleteObjectStringAPI a object and returns a map that contains deletion result
-func (o *vaultObject) deleteObjectStringAPI(idfield string) (*restapi.StringResponse, error) {
+func (o *vaultObject) deleteObjectStringAPI(idfield string) (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var funcArg = make(map[string]interface{})
@@ -186,20 +204,25 @@ func (o *vaultObject) deleteObjectStringAPI(idfield string) (*restapi.StringResp
 	if !reply.Success {
 		return nil, fmt.Errorf(reply.Message)
 	}
+	o.resolver().InvalidateID(o.ID)
 
-	return reply, nil
+	return reply.ToBase(), nil
 }
 
 // SetPermissions sets permissions. isRemove indicates whether to remove all permissions instead of setting permissions
 func (o *vaultObject) SetPermissions(isRemove bool) (*restapi.BaseAPIResponse, error) {
+	return o.setGrants(o.Permissions, isRemove)
+}
+
+func (o *vaultObject) setGrants(perms []Permission, isRemove bool) (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
 	var permissions []map[string]interface{}
-	for _, v := range o.Permissions {
+	for _, v := range perms {
 		var permission = make(map[string]interface{})
 		permission, err := generateRequestMap(v)
 		if isRemove {
@@ -216,7 +239,7 @@ func (o *vaultObject) SetPermissions(isRemove bool) (*restapi.BaseAPIResponse, e
 		queryArg["ID"] = o.ID
 		queryArg["PVID"] = o.ID
 		queryArg["Grants"] = permissions
-		logger.Debugf("Generated Map for SetPermissions(): %+v", queryArg)
+		o.client.Log().Debugf("Generated Map for SetPermissions(): %+v", queryArg)
 		resp, err := o.client.CallBaseAPI(o.apiPermissions, queryArg)
 		if err != nil {
 			return nil, err
@@ -229,11 +252,193 @@ func (o *vaultObject) SetPermissions(isRemove bool) (*restapi.BaseAPIResponse, e
 	return nil, nil
 }
 
+// GetPermissions returns permissions granted directly on the object. Rights are translated back through ValidPermissions
+// so "Checkout" is returned instead of "Naked". Permissions inherited from sets aren't returned
+func (o *vaultObject) GetPermissions() ([]Permission, error) {
+	perms, err := o.readGrants()
+	if err != nil {
+		return nil, err
+	}
+	for i := range perms {
+		perms[i] = toRightNames(perms[i], o.ValidPermissions)
+	}
+	return perms, nil
+}
+
+// readGrants reads permissions with rights as they are named by API
+func (o *vaultObject) readGrants() ([]Permission, error) {
+	if o.ID == "" {
+		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
+		o.client.Log().Errorf(errormsg)
+		return nil, fmt.Errorf(errormsg)
+	}
+	if o.apiGetPermissions == "" {
+		return nil, fmt.Errorf("Reading permissions of '%s' isn't supported", o.Name)
+	}
+	return readAces(o.client, o.apiGetPermissions, o.ID, o.aclTable)
+}
+
+// readAces reads access control entries of row in table. Entries inherited from elsewhere are skipped
+func readAces(c *restapi.RestClient, api string, rowKey string, table string) ([]Permission, error) {
+	var queryArg = make(map[string]interface{})
+	queryArg["RowKey"] = rowKey
+	queryArg["Table"] = table
+	queryArg["ReduceSysadmin"] = true
+	c.Log().Debugf("Generated Map for %s: %+v", api, queryArg)
+	resp, err := c.CallSliceAPI(api, queryArg)
+	if err != nil {
+		c.Log().Errorf(err.Error())
+		return nil, err
+	}
+	if !resp.Success {
+		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
+		c.Log().Errorf(errmsg)
+		return nil, fmt.Errorf(errmsg)
+	}
+
+	var perms []Permission
+	for _, r := range resp.Result {
+		ace, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if inherited, _ := ace["Inherited"].(bool); inherited {
+			continue
+		}
+		p := Permission{}
+		p.PrincipalID, _ = ace["Principal"].(string)
+		p.PrincipalName, _ = ace["PrincipalName"].(string)
+		p.PrincipalType, _ = ace["PrincipalType"].(string)
+		grant, _ := ace["GrantStr"].(string)
+		for _, right := range strings.Split(grant, ",") {
+			if right = strings.TrimSpace(right); right != "" {
+				p.RightList = append(p.RightList, right)
+			}
+		}
+		p.Rights = FlattenSliceToString(p.RightList)
+		perms = append(perms, p)
+	}
+	return perms, nil
+}
+
+// toRightNames translates API rights such as "Naked" to ValidPermissions names such as "Checkout".
+// Rights that aren't in ValidPermissions are kept as they are
+func toRightNames(p Permission, valid map[string]string) Permission {
+	names := make(map[string]string)
+	for k, v := range valid {
+		names[v] = k
+	}
+	var rights []string
+	for _, right := range p.RightList {
+		if name, ok := names[right]; ok {
+			right = name
+		}
+		rights = append(rights, right)
+	}
+	p.RightList = rights
+	p.Rights = FlattenSliceToString(rights)
+	return p
+}
+
+// PermissionDiff lists permissions changed by SyncPermissions
+type PermissionDiff struct {
+	Added   []Permission // Principals that had no permission
+	Changed []Permission // Principals whose rights are changed
+	Removed []Permission // Principals whose permissions are removed
+}
+
+// HasChanges returns true if any permission is added, changed or removed
+func (d *PermissionDiff) HasChanges() bool {
+	return len(d.Added)+len(d.Changed)+len(d.Removed) > 0
+}
+
+// SyncPermissions makes permissions granted directly on the object equal to desired. Rights of desired permissions use
+// ValidPermissions names and principal is looked up by name if PrincipalID isn't set.
+// Only principals whose rights differ are sent. Principals that aren't in desired are removed
+func (o *vaultObject) SyncPermissions(desired []Permission) (*PermissionDiff, error) {
+	current, err := o.readGrants()
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve copy of desired permissions so that caller's slice keeps rights names
+	want := make([]Permission, len(desired))
+	copy(want, desired)
+	err = ResolvePermissions2(o.client, want, o.ValidPermissions)
+	if err != nil {
+		return nil, err
+	}
+
+	have := make(map[string]Permission)
+	for _, p := range current {
+		have[strings.ToLower(p.PrincipalID)] = p
+	}
+	diff := &PermissionDiff{}
+	seen := make(map[string]bool)
+	var grants []Permission
+	for i, p := range want {
+		key := strings.ToLower(p.PrincipalID)
+		seen[key] = true
+		reported := desired[i]
+		reported.PrincipalID = p.PrincipalID
+		cur, ok := have[key]
+		switch {
+		case !ok && p.Rights == "":
+			continue
+		case !ok:
+			diff.Added = append(diff.Added, reported)
+		case sameRights(cur.Rights, p.Rights):
+			continue
+		default:
+			diff.Changed = append(diff.Changed, reported)
+		}
+		grants = append(grants, o.grant(p, p.Rights))
+	}
+	for _, p := range current {
+		if seen[strings.ToLower(p.PrincipalID)] {
+			continue
+		}
+		diff.Removed = append(diff.Removed, toRightNames(p, o.ValidPermissions))
+		grants = append(grants, o.grant(p, "None"))
+	}
+
+	if len(grants) > 0 {
+		_, err = o.setGrants(grants, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return diff, nil
+}
+
+// grant returns permission in the form sent by SetPermissions
+func (o *vaultObject) grant(p Permission, rights string) Permission {
+	if rights == "" {
+		rights = "None"
+	}
+	return Permission{PrincipalID: p.PrincipalID, PrincipalName: p.PrincipalName, PrincipalType: p.PrincipalType, Rights: rights}
+}
+
+// sameRights compares comma separated rights regardless of order
+func sameRights(a, b string) bool {
+	split := func(s string) []string {
+		var rights []string
+		for _, r := range strings.Split(s, ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				rights = append(rights, r)
+			}
+		}
+		sort.Strings(rights)
+		return rights
+	}
+	return reflect.DeepEqual(split(a), split(b))
+}
+
 // FillStruct function fills a struct with map
 func (o *vaultObject) FillStruct(m map[string]interface{}) error {
-	logger.Debugf("Input map: %v", m)
+	o.client.Log().Debugf("Input map: %v", m)
 	for k, v := range m {
-		logger.Debugf("Map key %v, map value: %v", k, v)
+		o.client.Log().Debugf("Map key %v, map value: %v", k, v)
 		err := setField(o, k, v)
 		if err != nil {
 			return err
@@ -265,6 +470,12 @@ func (o *vaultObject) AddToSetsByName(sets []string) error {
 		setObj.ObjectType = o.SetType
 		id, err := setObj.GetIDByName()
 		if err != nil || id == "" {
+			dynSet := NewDynamicSet(o.client)
+			dynSet.Name = v
+			dynSet.ObjectType = o.SetType
+			if _, err := dynSet.Query(); err == nil {
+				return fmt.Errorf("%s type Set %s is a dynamic set whose members are determined by its query", setObj.ObjectType, v)
+			}
 			return fmt.Errorf("%s type Set %s doesn't exist", setObj.ObjectType, v)
 		}
 		ids = append(ids, id)
@@ -3,7 +3,6 @@ package platform
 import (
 	"fmt"
 
-	logger "github.com/marcozj/golang-sdk/logging"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -44,12 +43,12 @@ func (o *GroupMappings) Read() error {
 	queryArg["Args"] = subArgs
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -80,16 +79,16 @@ func (o *GroupMappings) createOrDelete(api string) error {
 	for _, v := range o.Mappings {
 		var queryArg = make(map[string]interface{})
 		queryArg[v.AttributeValue] = v.GroupName
-		logger.Debugf("Generated Map for %s: %+v", api, queryArg)
+		o.client.Log().Debugf("Generated Map for %s: %+v", api, queryArg)
 
 		resp, err := o.client.CallStringAPI(api, queryArg)
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return err
 		}
 		if !resp.Success {
 			errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-			logger.Errorf(errmsg)
+			o.client.Log().Errorf(errmsg)
 			return fmt.Errorf(errmsg)
 		}
 	}
@@ -109,14 +108,14 @@ func (o *GroupMappings) Update() error {
 	queryArg["Mappings"] = mappings
 
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdates, queryArg)
-	logger.Debugf("Generated Map for %s: %+v", o.apiUpdates, queryArg)
+	o.client.Log().Debugf("Generated Map for %s: %+v", o.apiUpdates, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
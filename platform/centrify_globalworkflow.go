@@ -4,7 +4,6 @@ import (
 	"fmt"
 
 	"github.com/marcozj/golang-sdk/enum/workflowtype"
-	logger "github.com/marcozj/golang-sdk/logging"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -36,7 +35,7 @@ func NewGlobalWorkflow(c *restapi.RestClient, wfType string) (*GlobalWorkflow, e
 	if wfType != workflowtype.AccountWorkflow.String() && wfType != workflowtype.AgentAuthWorkflow.String() &&
 		wfType != workflowtype.SecretsWorkflow.String() && wfType != workflowtype.PrivilegeElevationWorkflow.String() {
 		errormsg := fmt.Sprintf("invalid workflow type %s", wfType)
-		logger.Errorf(errormsg)
+		c.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
@@ -49,12 +48,12 @@ func (o *GlobalWorkflow) Read() error {
 	queryArg["key"] = o.Type
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -84,20 +83,20 @@ func (o *GlobalWorkflow) Update() (*restapi.GenericMapResponse, error) {
 	var queryArg = make(map[string]interface{})
 	queryArg, err := generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
 
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
@@ -111,20 +110,20 @@ func (o *GlobalWorkflow) Delete() error {
 	var queryArg = make(map[string]interface{})
 	queryArg, err := generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
 
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -2,9 +2,11 @@ package platform
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/marcozj/golang-sdk/enum/setsubtype"
 	"github.com/marcozj/golang-sdk/enum/settype"
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -40,6 +42,8 @@ func NewManualSet(c *restapi.RestClient) *ManualSet {
 	s.apiUpdate = "/Collection/UpdateCollection"
 	s.apiUpdateMembers = "/Collection/UpdateMembersCollection"
 	s.apiPermissions = "/Collection/SetCollectionPermissions"
+	s.apiGetPermissions = "/Acl/GetRowAces"
+	s.aclTable = "Sets"
 
 	return &s
 }
@@ -50,7 +54,7 @@ func NewManualSetWithType(c *restapi.RestClient, setType string) (*ManualSet, er
 	s.ObjectType = setType
 	err := s.ResolveValidMemberPerms()
 	if err != nil {
-		logger.Errorf(err.Error())
+		c.Log().Errorf(err.Error())
 		return nil, err
 	}
 
@@ -59,9 +63,14 @@ func NewManualSetWithType(c *restapi.RestClient, setType string) (*ManualSet, er
 
 // Read function fetches a ManualSet from source, including attribute values. Returns error if any
 func (o *ManualSet) Read() error {
+	return o.read(o)
+}
+
+// read fetches set into target, which is the set itself or a set type that embeds it such as *DynamicSet
+func (o *ManualSet) read(target interface{}) error {
 	if o.ID == "" {
-		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(target))
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -71,99 +80,124 @@ func (o *ManualSet) Read() error {
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
-	mapToStruct(o, resp.Result)
+	mapToStruct(target, resp.Result)
 
 	return nil
 }
 
 // Create function creates a new Manual Set and returns a map that contains creation result
-func (o *ManualSet) Create() (*restapi.StringResponse, error) {
+func (o *ManualSet) Create() (*restapi.BaseAPIResponse, error) {
+	return o.create(o)
+}
+
+// create sends attributes of target, which is the set itself or a set type that embeds it, to create API of the set
+func (o *ManualSet) create(target interface{}) (*restapi.BaseAPIResponse, error) {
 	// If ObjectType is "Application", SubObjectType to be set to either "Desktop" or "Web"
 	// If SubObjectType is not set, the set will be visible for both Web and Desktop applications
-	queryArg, err := generateRequestMap(o)
+	queryArg, err := generateRequestMap(target)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
 
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallStringAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	// Assign ID after successful creation so that the same object can be used for subsequent update operation
 	o.ID = resp.Result
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Delete function deletes a Manual Set and returns a map that contains deletion result
-func (o *ManualSet) Delete() (*restapi.GenericMapResponse, error) {
+func (o *ManualSet) Delete() (*restapi.BaseAPIResponse, error) {
 	return o.deleteObjectMapAPI("")
 }
 
 // Update function updates an existing Manual Set and returns a map that contains update result
-func (o *ManualSet) Update() (*restapi.GenericMapResponse, error) {
+func (o *ManualSet) Update() (*restapi.BaseAPIResponse, error) {
+	return o.update(o)
+}
+
+// update sends attributes of target, which is the set itself or a set type that embeds it, to update API
+func (o *ManualSet) update(target interface{}) (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
-		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(target))
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
-	var queryArg = make(map[string]interface{})
-	queryArg, err := generateRequestMap(o)
+	queryArg, err := generateRequestMap(target)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
 
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
+}
+
+// Validate checks set attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *ManualSet) Validate() error {
+	v := &validator{}
+	v.required("name", o.Name)
+	v.required("type", o.ObjectType)
+	v.oneOf("type", o.ObjectType, settype.Values())
+	if o.SubObjectType != "" {
+		v.check(o.ObjectType == settype.Application.String(), "subtype", "is only applicable to Application set")
+		v.oneOf("subtype", o.SubObjectType, setsubtype.Values())
+	}
+
+	return v.err(o, o.Name)
 }
 
 // Query function returns a single Set object in map format
 func (o *ManualSet) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM Sets WHERE 1=1"
+	q := query.Select("Sets")
 	if o.ObjectType != "" {
-		query += " AND ObjectType='" + o.ObjectType + "'"
+		q.Where(query.Eq("ObjectType", o.ObjectType))
 	}
 	if o.CollectionType != "" {
-		query += " AND CollectionType='" + o.CollectionType + "'"
+		q.Where(query.Eq("CollectionType", o.CollectionType))
 	}
 	if o.Name != "" {
-		query += " AND Name='" + o.Name + "'"
+		q.Where(query.Eq("Name", o.Name))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 // UpdateSetMembers adds or removes members from the ManualSet
@@ -171,8 +205,8 @@ func (o *ManualSet) UpdateSetMembers(ids []string, action string) (*restapi.Stri
 	var resp *restapi.StringResponse
 	var err error
 	if o.ID == "" {
-		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(o))
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	if action != "add" && action != "remove" {
@@ -195,12 +229,12 @@ func (o *ManualSet) UpdateSetMembers(ids []string, action string) (*restapi.Stri
 		queryArg[action] = members
 		resp, err = o.client.CallStringAPI(o.apiUpdateMembers, queryArg)
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, err
 		}
 		if !resp.Success {
 			errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-			logger.Errorf(errmsg)
+			o.client.Log().Errorf(errmsg)
 			return nil, fmt.Errorf(errmsg)
 		}
 	}
@@ -208,11 +242,261 @@ func (o *ManualSet) UpdateSetMembers(ids []string, action string) (*restapi.Stri
 	return resp, nil
 }
 
+// ManualSetMember is a member of a set
+type ManualSetMember struct {
+	ID     string
+	Name   string // Name of the member. Account is named "user@resource", e.g. "root@host1". Empty if object no longer exists
+	Type   string // Table of the member such as Server or VaultAccount, which is ObjectType of the set
+	Object Object // Member as typed object such as *System or *Account filled with attributes returned by query. See GetMembers
+}
+
+// SetMembersDiff lists members changed by SyncMembers
+type SetMembersDiff struct {
+	Added   []ManualSetMember
+	Removed []ManualSetMember
+}
+
+// HasChanges returns true if any member is added or removed
+func (d *SetMembersDiff) HasChanges() bool {
+	return len(d.Added)+len(d.Removed) > 0
+}
+
+// setMemberNameColumns are columns that make up member name of each set type
+var setMemberNameColumns = map[string][]string{
+	settype.System.String():          {"Name"},
+	settype.Database.String():        {"Name"},
+	settype.Domain.String():          {"Name"},
+	settype.Account.String():         {"User", "Name"},
+	settype.Secret.String():          {"SecretName"},
+	settype.SSHKey.String():          {"Name"},
+	settype.Service.String():         {"WindowsServiceName"},
+	settype.Application.String():     {"Name"},
+	settype.ResourceProfile.String(): {"Name"},
+	settype.CloudProvider.String():   {"Name"},
+}
+
+// setMemberObjects creates typed object of each set type. Application set holds both desktop and web apps and
+// resource profile has no object type so their members have no typed object
+var setMemberObjects = map[string]func(c *restapi.RestClient) Object{
+	settype.System.String():        func(c *restapi.RestClient) Object { return NewSystem(c) },
+	settype.Database.String():      func(c *restapi.RestClient) Object { return NewDatabase(c) },
+	settype.Domain.String():        func(c *restapi.RestClient) Object { return NewDomain(c) },
+	settype.Account.String():       func(c *restapi.RestClient) Object { return NewAccount(c) },
+	settype.Secret.String():        func(c *restapi.RestClient) Object { return NewSecret(c) },
+	settype.SSHKey.String():        func(c *restapi.RestClient) Object { return NewSSHKey(c) },
+	settype.Service.String():       func(c *restapi.RestClient) Object { return NewService(c) },
+	settype.CloudProvider.String(): func(c *restapi.RestClient) Object { return NewCloudProvider(c) },
+}
+
+// setMembersBatchSize is maximum number of members sent or looked up in one API call
+const setMembersBatchSize = 100
+
+// GetMembers returns members of the set together with their names. Object of each member is a typed object of the
+// set's ObjectType, e.g. *System for Server set, except for Application and ResourceProfiles sets whose members
+// can't be mapped to a single type. Members whose object no longer exists are returned with empty Name and nil
+// Object so that SyncMembers can remove them
+func (o *ManualSet) GetMembers() ([]ManualSetMember, error) {
+	if o.ID == "" {
+		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(o))
+		o.client.Log().Errorf(errormsg)
+		return nil, fmt.Errorf(errormsg)
+	}
+	columns, ok := setMemberNameColumns[o.ObjectType]
+	if !ok {
+		return nil, fmt.Errorf("Invalid Set type")
+	}
+
+	ids, err := getSetMemberIDs(o.client, o.ID)
+	if err != nil {
+		return nil, err
+	}
+	var members []ManualSetMember
+	for start := 0; start < len(ids); start += setMembersBatchSize {
+		end := start + setMembersBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := make([]interface{}, end-start)
+		for i, id := range ids[start:end] {
+			batch[i] = id
+		}
+		found, err := o.queryMembers(columns, query.In("ID", batch...))
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[string]ManualSetMember)
+		for _, m := range found {
+			byID[strings.ToLower(m.ID)] = m
+		}
+		for _, id := range ids[start:end] {
+			m, ok := byID[strings.ToLower(id)]
+			if !ok {
+				m = ManualSetMember{ID: id, Type: o.ObjectType}
+			}
+			members = append(members, m)
+		}
+	}
+
+	return members, nil
+}
+
+// SyncMembers makes members of the set equal to desired. Each desired member is given either by ID or by name,
+// which is "user@resource" for account set and name of the object for other set types.
+// Members are added and removed in batches and only those that differ are sent. If a batch fails, the returned diff
+// lists only members changed before the failure and is returned together with the error
+func (o *ManualSet) SyncMembers(desired []string) (*SetMembersDiff, error) {
+	current, err := o.GetMembers()
+	if err != nil {
+		return nil, err
+	}
+	have := make(map[string]ManualSetMember)
+	for _, m := range current {
+		have[strings.ToLower(m.ID)] = m
+	}
+
+	diff := &SetMembersDiff{}
+	want := make(map[string]bool)
+	for _, d := range desired {
+		m, err := o.resolveMember(d, current)
+		if err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(m.ID)
+		if want[key] {
+			continue
+		}
+		want[key] = true
+		if _, ok := have[key]; !ok {
+			diff.Added = append(diff.Added, m)
+		}
+	}
+	for _, m := range current {
+		if !want[strings.ToLower(m.ID)] {
+			diff.Removed = append(diff.Removed, m)
+		}
+	}
+
+	n, err := o.updateMembers(diff.Added, "add")
+	if err != nil {
+		diff.Added, diff.Removed = diff.Added[:n], nil
+		return diff, err
+	}
+	n, err = o.updateMembers(diff.Removed, "remove")
+	if err != nil {
+		diff.Removed = diff.Removed[:n]
+		return diff, err
+	}
+
+	return diff, nil
+}
+
+// resolveMember finds member by ID or name, first among current members then in tenant
+func (o *ManualSet) resolveMember(idOrName string, current []ManualSetMember) (ManualSetMember, error) {
+	for _, m := range current {
+		if strings.EqualFold(m.ID, idOrName) || strings.EqualFold(m.Name, idOrName) {
+			return m, nil
+		}
+	}
+
+	columns := setMemberNameColumns[o.ObjectType]
+	condition := query.Or(query.Eq("ID", idOrName), query.Eq(columns[0], idOrName))
+	if len(columns) > 1 {
+		// Account name is user@resource. User name itself may contain @ so split at the last one
+		condition = query.Eq("ID", idOrName)
+		if i := strings.LastIndex(idOrName, "@"); i > 0 {
+			condition = query.Or(condition, query.And(query.Eq(columns[0], idOrName[:i]), query.Eq(columns[1], idOrName[i+1:])))
+		}
+	}
+	found, err := o.queryMembers(columns, condition)
+	if err != nil {
+		return ManualSetMember{}, err
+	}
+	switch len(found) {
+	case 0:
+		return ManualSetMember{}, fmt.Errorf("%s member %s doesn't exist", o.ObjectType, idOrName)
+	case 1:
+		return found[0], nil
+	}
+	return ManualSetMember{}, fmt.Errorf("%s member %s matches %d objects, use ID instead", o.ObjectType, idOrName, len(found))
+}
+
+// queryMembers returns objects of set type that satisfy condition. columns make up member name
+func (o *ManualSet) queryMembers(columns []string, condition query.Condition) ([]ManualSetMember, error) {
+	script, err := query.Select(o.ObjectType).Where(condition).Build()
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return nil, err
+	}
+	results, err := RedRockQuery(o.client, script, nil)
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return nil, err
+	}
+
+	var members []ManualSetMember
+	for _, r := range results {
+		item, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		row, ok := item["Row"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		m := ManualSetMember{Type: o.ObjectType}
+		m.ID, _ = row["ID"].(string)
+		var names []string
+		for _, c := range columns {
+			name, _ := row[c].(string)
+			names = append(names, name)
+		}
+		m.Name = strings.Join(names, "@")
+		if newObject, ok := setMemberObjects[o.ObjectType]; ok {
+			obj := newObject(o.client)
+			if err := mapToStruct(obj, row); err != nil {
+				o.client.Log().Errorf("Failed to decode %s member %s. %v", o.ObjectType, m.ID, err)
+			} else {
+				m.Object = obj
+			}
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// updateMembers adds or removes members in batches. Returns number of members in batches that succeeded
+func (o *ManualSet) updateMembers(members []ManualSetMember, action string) (int, error) {
+	for start := 0; start < len(members); start += setMembersBatchSize {
+		end := start + setMembersBatchSize
+		if end > len(members) {
+			end = len(members)
+		}
+		var batch []setMember
+		for _, m := range members[start:end] {
+			batch = append(batch, setMember{Key: m.ID, MemberType: "Row", Table: o.ObjectType})
+		}
+		var queryArg = make(map[string]interface{})
+		queryArg["id"] = o.ID
+		queryArg[action] = batch
+		resp, err := o.client.CallStringAPI(o.apiUpdateMembers, queryArg)
+		if err != nil {
+			o.client.Log().Errorf(err.Error())
+			return start, err
+		}
+		if !resp.Success {
+			errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
+			o.client.Log().Errorf(errmsg)
+			return start, fmt.Errorf(errmsg)
+		}
+	}
+	return len(members), nil
+}
+
 // SetMemberPermissions sets member permissions. isRemove indicates whether to remove all permissions instead of setting permissions
 func (o *ManualSet) SetMemberPermissions(isRemove bool) (*restapi.GenericMapResponse, error) {
 	if o.ID == "" {
-		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(o))
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
@@ -235,15 +519,15 @@ func (o *ManualSet) SetMemberPermissions(isRemove bool) (*restapi.GenericMapResp
 		queryArg["PVID"] = o.ID
 		queryArg["RowKey"] = o.ID
 		queryArg["Grants"] = permissions
-		logger.Debugf("Generated Map for SetMemberPermissions(): %+v", queryArg)
+		o.client.Log().Debugf("Generated Map for SetMemberPermissions(): %+v", queryArg)
 		resp, err := o.client.CallGenericMapAPI(o.apiMemberPermissions, queryArg)
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, err
 		}
 		if !resp.Success {
 			errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-			logger.Errorf(errmsg)
+			o.client.Log().Errorf(errmsg)
 			return nil, fmt.Errorf(errmsg)
 		}
 		return resp, nil
@@ -300,46 +584,59 @@ func (o *ManualSet) GetIDByName() (string, error) {
 		return "", fmt.Errorf("Set type must be provided")
 	}
 
+	// Collection type is part of the key since lookup with empty collection type also finds dynamic sets
+	scope := o.ObjectType + "/" + o.CollectionType
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, scope); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("Error retrieving set: %s", err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, scope, o.ID)
 
 	return o.ID, nil
 }
 
 // GetByName retrieves set from tenant by name
 func (o *ManualSet) GetByName() error {
+	return o.getByName(o)
+}
+
+// getByName looks up ID of set by name and reads it into target
+func (o *ManualSet) getByName(target interface{}) error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf("Failed to find ID of set %s. %v", o.Name, err)
 		}
 	}
 
-	err := o.Read()
+	err := o.read(target)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	return nil
 }
 
 // DeleteByName deletes a Set by name
-func (o *ManualSet) DeleteByName() (*restapi.GenericMapResponse, error) {
+func (o *ManualSet) DeleteByName() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, fmt.Errorf("Failed to find ID of Set %s. %v", o.Name, err)
 		}
 	}
 	resp, err := o.Delete()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
@@ -536,3 +833,42 @@ func (o *ManualSet) ValidateMemberPermissions(perms []string) ([]string, error)
 			"InnerExceptions": null
 		}
 */
+
+// ManualSetFilter selects sets returned by ListManualSets. Empty fields are ignored
+type ManualSetFilter struct {
+	ListOptions
+	Name           string // Substring of set name
+	ObjectType     string // Type of set members such as Server or VaultAccount
+	CollectionType string // ManualBucket, Phantom, etc.
+}
+
+// ManualSetIterator iterates over sets returned by ListManualSets
+type ManualSetIterator struct {
+	*Iterator
+}
+
+// Value returns current set. Only attributes returned by query are filled, call Read() for the rest
+func (it *ManualSetIterator) Value() *ManualSet {
+	obj := NewManualSet(it.client)
+	it.decode(obj)
+	return obj
+}
+
+// ListManualSets returns iterator over sets that match filter. Pages are fetched as iteration goes
+func ListManualSets(c *restapi.RestClient, filter ManualSetFilter) *ManualSetIterator {
+	q, err := listQuery(c, "Sets", "")
+	if err != nil {
+		return &ManualSetIterator{errIterator(err)}
+	}
+	if filter.Name != "" {
+		q.Where(query.Contains("Name", filter.Name))
+	}
+	if filter.ObjectType != "" {
+		q.Where(query.Eq("ObjectType", filter.ObjectType))
+	}
+	if filter.CollectionType != "" {
+		q.Where(query.Eq("CollectionType", filter.CollectionType))
+	}
+
+	return &ManualSetIterator{newIterator(c, q, filter.ListOptions)}
+}
@@ -0,0 +1,49 @@
+package platform
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSyncMembersPartialFailure(t *testing.T) {
+	c, srv := newTestClient(t, func(path string, args map[string]interface{}) interface{} {
+		switch path {
+		case "/Collection/GetMembers":
+			return []interface{}{map[string]interface{}{"Key": "old"}}
+		case "/RedRock/query":
+			script, _ := args["Script"].(string)
+			var rows []map[string]interface{}
+			for _, id := range []string{"old", "new"} {
+				if strings.Contains(script, "'"+id+"'") {
+					rows = append(rows, map[string]interface{}{"ID": id, "Name": id + "01"})
+				}
+			}
+			return redRockRows(rows...)
+		case "/Collection/UpdateMembersCollection":
+			if _, ok := args["remove"]; ok {
+				return testAPIError("Remove failed")
+			}
+			return ""
+		}
+		t.Errorf("unexpected call to %s", path)
+		return nil
+	})
+	defer srv.Close()
+
+	set := NewManualSet(c)
+	set.ID = "set1"
+	set.ObjectType = "Server"
+	diff, err := set.SyncMembers([]string{"new"})
+	if err == nil {
+		t.Fatal("expected error from remove")
+	}
+	if diff == nil {
+		t.Fatal("partial diff wasn't returned")
+	}
+	if len(diff.Added) != 1 || diff.Added[0].ID != "new" {
+		t.Errorf("added = %+v, want [new]", diff.Added)
+	}
+	if len(diff.Removed) != 0 {
+		t.Errorf("removed = %+v, want none", diff.Removed)
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"strings"
 
 	"github.com/marcozj/golang-sdk/enum/resourcetype"
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -33,6 +33,8 @@ func NewMultiplexedAccount(c *restapi.RestClient) *MultiplexedAccount {
 	s.apiDelete = "/Subscriptions/DeleteMPAccount"
 	s.apiUpdate = "/Subscriptions/UpdateMPAccount"
 	s.apiPermissions = "/Subscriptions/SetMultiplexedAccountPermissions"
+	s.apiGetPermissions = "/Acl/GetRowAces"
+	s.aclTable = "MultiplexedAccount"
 
 	return &s
 }
@@ -41,7 +43,7 @@ func NewMultiplexedAccount(c *restapi.RestClient) *MultiplexedAccount {
 func (o *MultiplexedAccount) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -51,13 +53,13 @@ func (o *MultiplexedAccount) Read() error {
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -71,7 +73,7 @@ func (o *MultiplexedAccount) Read() error {
 }
 
 // Create function creates a new MultiplexedAccount
-func (o *MultiplexedAccount) Create() (*restapi.StringResponse, error) {
+func (o *MultiplexedAccount) Create() (*restapi.BaseAPIResponse, error) {
 	err := o.resolveAccountID()
 	if err != nil {
 		return nil, err
@@ -80,38 +82,39 @@ func (o *MultiplexedAccount) Create() (*restapi.StringResponse, error) {
 	var queryArg = make(map[string]interface{})
 	queryArg, err = generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallStringAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	// Assign ID after successful creation so that the same object can be used for subsequent update operation
 	o.ID = resp.Result
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Delete function deletes a MultiplexedAccount
-func (o *MultiplexedAccount) Delete() (*restapi.GenericMapResponse, error) {
+func (o *MultiplexedAccount) Delete() (*restapi.BaseAPIResponse, error) {
 	return o.deleteObjectMapAPI("")
 }
 
 // Update function updates an existing MultiplexedAccount
-func (o *MultiplexedAccount) Update() (*restapi.StringResponse, error) {
+func (o *MultiplexedAccount) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
@@ -123,33 +126,49 @@ func (o *MultiplexedAccount) Update() (*restapi.StringResponse, error) {
 	var queryArg = make(map[string]interface{})
 	queryArg, err = generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallStringAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
+}
+
+// Validate checks multiplexed account attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *MultiplexedAccount) Validate() error {
+	v := &validator{}
+	v.required("name", o.Name)
+	if len(o.RealAccounts) > 0 {
+		v.check(len(o.RealAccounts) == 2, "accounts", "exactly two accounts are required")
+	} else {
+		v.check(o.RealAccount1ID != "" || o.RealAccount1UPN != "", "account1_id", "is required")
+		v.check(o.RealAccount2ID != "" || o.RealAccount2UPN != "", "account2_id", "is required")
+	}
+	v.check(o.RealAccount1ID == "" || o.RealAccount1ID != o.RealAccount2ID, "account2_id", "must be different from account1_id")
+
+	return v.err(o, o.Name)
 }
 
 // Query function returns a single MultiplexedAccount object in map format
 func (o *MultiplexedAccount) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM MultiplexedAccount WHERE 1=1"
+	q := query.Select("MultiplexedAccount")
 	if o.Name != "" {
-		query += " AND Name='" + o.Name + "'"
+		q.Where(query.Eq("Name", o.Name))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 // GetIDByName returns MultiplexedAccount ID by name
@@ -158,12 +177,18 @@ func (o *MultiplexedAccount) GetIDByName() (string, error) {
 		return "", fmt.Errorf("MultiplexedAccount name must be provided")
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("Error retrieving MultiplexedAccount: %s", err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, "", o.ID)
 
 	return o.ID, nil
 }
@@ -173,7 +198,7 @@ func (o *MultiplexedAccount) GetByName() error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf("Failed to find ID of MultiplexedAccount %s. %v", o.Name, err)
 		}
 	}
@@ -186,11 +211,11 @@ func (o *MultiplexedAccount) GetByName() error {
 }
 
 // DeleteByName deletes a MultiplexedAccount by name
-func (o *MultiplexedAccount) DeleteByName() (*restapi.GenericMapResponse, error) {
+func (o *MultiplexedAccount) DeleteByName() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, fmt.Errorf("Failed to find ID of MultiplexedAccount %s. %v", o.Name, err)
 		}
 	}
@@ -227,7 +252,7 @@ func (o *MultiplexedAccount) resolveAccountID() error {
 
 			acctid, err := account.GetIDByName()
 			if err != nil {
-				logger.Errorf(err.Error())
+				o.client.Log().Errorf(err.Error())
 				return fmt.Errorf(err.Error())
 			}
 			o.RealAccounts = append(o.RealAccounts, acctid)
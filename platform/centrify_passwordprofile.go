@@ -3,7 +3,6 @@ package platform
 import (
 	"fmt"
 
-	logger "github.com/marcozj/golang-sdk/logging"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -44,7 +43,7 @@ func NewPasswordProfile(c *restapi.RestClient) *PasswordProfile {
 func (o *PasswordProfile) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -56,12 +55,12 @@ func (o *PasswordProfile) Read() error {
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 	// Loop through respond results and grab the matched record
@@ -75,7 +74,7 @@ func (o *PasswordProfile) Read() error {
 		row := item["Row"].(map[string]interface{})
 		//logger.Debugf("Checking name: %s, profiletype: %s", row["Name"], row["ProfileType"])
 		if row["ID"] == o.ID {
-			logger.Debugf("Found an item: %+v", row)
+			o.client.Log().Debugf("Found an item: %+v", row)
 			// If ProfileType is defined, then compare it
 			if o.ProfileType == "" {
 				pwdpfs = append(pwdpfs, row)
@@ -86,7 +85,7 @@ func (o *PasswordProfile) Read() error {
 	}
 	err = queryError(len(pwdpfs))
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
@@ -96,67 +95,84 @@ func (o *PasswordProfile) Read() error {
 }
 
 // Delete function deletes an password profile and returns a map that contains deletion result
-func (o *PasswordProfile) Delete() (*restapi.BoolResponse, error) {
+func (o *PasswordProfile) Delete() (*restapi.BaseAPIResponse, error) {
 	return o.deleteObjectBoolAPI("")
 }
 
 // Create function creates an password profile and returns a map that contains update result
-func (o *PasswordProfile) Create() (*restapi.StringResponse, error) {
+func (o *PasswordProfile) Create() (*restapi.BaseAPIResponse, error) {
 	var queryArg = make(map[string]interface{})
 
 	queryArg, err := generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
 
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallStringAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	// Assign ID after successful creation so that the same object can be used for subsequent update operation
 	o.ID = resp.Result
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Update function updates an existing password profile and returns a map that contains update result
-func (o *PasswordProfile) Update() (*restapi.GenericMapResponse, error) {
+func (o *PasswordProfile) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
 	queryArg, err := generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
 
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
+}
+
+// Validate checks password profile attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *PasswordProfile) Validate() error {
+	v := &validator{}
+	v.required("name", o.Name)
+	v.inRange("minimum_password_length", o.MinimumPasswordLength, 4, maxInt32)
+	v.inRange("maximum_password_length", o.MaximumPasswordLength, 4, maxInt32)
+	v.check(o.MaximumPasswordLength == 0 || o.MinimumPasswordLength <= o.MaximumPasswordLength, "minimum_password_length", "can't be greater than maximum_password_length")
+	v.check(o.MinimumAlphabeticCharacterCount+o.MinimumNonAlphabeticCharacterCount <= o.MaximumPasswordLength || o.MaximumPasswordLength == 0,
+		"minimum_alphabetic_character_count", "together with minimum_non_alphabetic_character_count can't be greater than maximum_password_length")
+	v.oneOf("first_character_type", o.FirstCharacterType, []string{"AlphaOnly", "AlphaNumericOnly"})
+	v.oneOf("last_character_type", o.LastCharacterType, []string{"AlphaOnly", "AlphaNumericOnly"})
+
+	return v.err(o, o.Name)
 }
 
 // Query function returns a single password profile object
@@ -170,12 +186,12 @@ func (o *PasswordProfile) Query() (map[string]interface{}, error) {
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 	// Loop through respond results and grab the matched record
@@ -187,7 +203,7 @@ func (o *PasswordProfile) Query() (map[string]interface{}, error) {
 		item := v.(map[string]interface{})
 		row := item["Row"].(map[string]interface{})
 		if row["Name"] == o.Name {
-			logger.Debugf("Found an item: %+v", row)
+			o.client.Log().Debugf("Found an item: %+v", row)
 			// If ProfileType is defined, then compare it
 			if o.ProfileType == "" {
 				pwdpfs = append(pwdpfs, row)
@@ -199,7 +215,7 @@ func (o *PasswordProfile) Query() (map[string]interface{}, error) {
 
 	err = queryError(len(pwdpfs))
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
@@ -212,12 +228,18 @@ func (o *PasswordProfile) GetIDByName() (string, error) {
 		return "", fmt.Errorf("Password profile name must be provided")
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("Error retrieving password profile: %s", err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, "", o.ID)
 
 	return o.ID, nil
 }
@@ -227,31 +249,31 @@ func (o *PasswordProfile) GetByName() error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf("Failed to find ID of password profile %s. %v", o.Name, err)
 		}
 	}
 
 	err := o.Read()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	return nil
 }
 
 // DeleteByName deletes a password profile by name
-func (o *PasswordProfile) DeleteByName() (*restapi.BoolResponse, error) {
+func (o *PasswordProfile) DeleteByName() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, fmt.Errorf("Failed to find ID of password profile %s. %v", o.Name, err)
 		}
 	}
 	resp, err := o.Delete()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
@@ -2,9 +2,7 @@ package platform
 
 import (
 	"fmt"
-	"strings"
 
-	logger "github.com/marcozj/golang-sdk/logging"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -307,7 +305,7 @@ func NewPolicy(c *restapi.RestClient) *Policy {
 func (o *Policy) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 
@@ -318,12 +316,12 @@ func (o *Policy) Read() error {
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -331,10 +329,10 @@ func (o *Policy) Read() error {
 	mapToStruct(o, resp.Result)
 
 	// Fill root level attributes: Params, LinkType, PolicySet, Description
-	resp2, err2 := o.Query("")
+	resp2, err2 := o.query(false)
 	//logger.Debugf("Response for Policy query: %+v", resp2)
 	if err2 != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err2
 	}
 
@@ -418,12 +416,12 @@ func (o *Policy) Read() error {
 }
 
 // Delete function deletes a Policy and returns a map that contains deletion result
-func (o *Policy) Delete() (*restapi.GenericMapResponse, error) {
+func (o *Policy) Delete() (*restapi.BaseAPIResponse, error) {
 	return o.deleteObjectMapAPI("path")
 }
 
 // Create function creates a Policy and returns a map that contains update result
-func (o *Policy) Create() (*restapi.GenericMapResponse, error) {
+func (o *Policy) Create() (*restapi.BaseAPIResponse, error) {
 	var queryArg = make(map[string]interface{})
 
 	// Handle plinks
@@ -445,7 +443,7 @@ func (o *Policy) Create() (*restapi.GenericMapResponse, error) {
 	// Convert to nested map
 	nestedmap, err := generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	//logger.Debugf("Nested map for Create(): %+v", nestedmap)
@@ -462,27 +460,28 @@ func (o *Policy) Create() (*restapi.GenericMapResponse, error) {
 	policy["Newpolicy"] = true
 	queryArg["policy"] = policy
 
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
 
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallGenericMapAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Update function updates an existing Policy and returns a map that contains update result
-func (o *Policy) Update() (*restapi.GenericMapResponse, error) {
+func (o *Policy) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -490,7 +489,7 @@ func (o *Policy) Update() (*restapi.GenericMapResponse, error) {
 	// Convert to nested map
 	nestedmap, err := generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
@@ -517,45 +516,67 @@ func (o *Policy) Update() (*restapi.GenericMapResponse, error) {
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg2)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 	policy["RevStamp"] = resp.Result["RevStamp"]
 
 	queryArg["policy"] = policy
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
 
+	o.resolver().InvalidateID(o.ID)
 	reply, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !reply.Success {
 		errmsg := fmt.Sprintf("%s %s", reply.Message, reply.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return reply, nil
+	return reply.ToBase(), nil
 }
 
-// Query function returns a single Policy object in map format
-func (o *Policy) Query(key string) (map[string]interface{}, error) {
+// Validate checks policy attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *Policy) Validate() error {
+	v := &validator{}
+	v.required("name", o.Name)
+	if o.Plink != nil {
+		v.oneOf("plink.link_type", o.Plink.LinkType, []string{"Global", "Role", "Collection", "Inactive"})
+		if o.Plink.LinkType == "Role" || o.Plink.LinkType == "Collection" {
+			v.check(len(o.Plink.Params) > 0, "plink.policy_assignment", "is required when link_type is %s", o.Plink.LinkType)
+		}
+	}
+	if err := o.ValidateSettings(); err != nil {
+		v.add("settings", err.Error())
+	}
+
+	return v.err(o, o.Name)
+}
+
+// Query function returns a single Policy object in map format. Policy is matched by ID if it is known, otherwise by name
+func (o *Policy) Query() (map[string]interface{}, error) {
+	return o.query(o.ID == "")
+}
+
+func (o *Policy) query(byName bool) (map[string]interface{}, error) {
 	var queryArg = make(map[string]interface{})
 	resp, err := o.client.CallGenericMapAPI(o.apiGetPolicies, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
@@ -565,7 +586,7 @@ func (o *Policy) Query(key string) (map[string]interface{}, error) {
 	for _, result := range results {
 		row = result.(map[string]interface{})["Row"].(map[string]interface{})
 		//logger.Debugf("Query row: %+v", row)
-		if strings.EqualFold(key, "name") {
+		if byName {
 			if row["PolicySet"] == "/Policy/"+o.Name {
 				return row, nil
 			}
@@ -576,7 +597,7 @@ func (o *Policy) Query(key string) (map[string]interface{}, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("Found 0 matched policy")
+	return nil, fmt.Errorf("Found 0 matched policy: %w", ErrNotFound)
 }
 
 // GetIDByName returns password profile ID by name
@@ -585,12 +606,18 @@ func (o *Policy) GetIDByName() (string, error) {
 		return "", fmt.Errorf("Policy name must be provided")
 	}
 
-	result, err := o.Query("name")
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
+	result, err := o.query(true)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("Error retrieving policy: %s", err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, "", o.ID)
 
 	return o.ID, nil
 }
@@ -600,14 +627,14 @@ func (o *Policy) GetByName() error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf("Failed to find ID of password profile %s. %v", o.Name, err)
 		}
 	}
 
 	err := o.Read()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	return nil
@@ -620,12 +647,12 @@ func (o *Policy) getPlinks() ([]map[string]interface{}, string, error) {
 	queryArg["Args"] = subArgs
 	resp, err := o.client.CallGenericMapAPI(o.apiGetPolicies, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, "", err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, "", fmt.Errorf(errmsg)
 	}
 
@@ -645,7 +672,7 @@ func (o *Policy) constructPlinks() ([]map[string]interface{}, string) {
 	var plinks []map[string]interface{}
 	oldplinks, rev, err := o.getPlinks()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, ""
 	}
 	// Loop through exiting plinks, find matching plink and remove it
@@ -3,7 +3,6 @@ package platform
 import (
 	"fmt"
 
-	logger "github.com/marcozj/golang-sdk/logging"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -45,12 +44,12 @@ func (o *PolicyLinks) GetPlinks() ([]map[string]interface{}, string, error) {
 	queryArg["Args"] = subArgs
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, "", err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, "", fmt.Errorf(errmsg)
 	}
 
@@ -69,7 +68,7 @@ func (o *PolicyLinks) GetPlinks() ([]map[string]interface{}, string, error) {
 func (o *PolicyLinks) Read() error {
 	plinks, _, err := o.GetPlinks()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
@@ -88,7 +87,7 @@ func (o *PolicyLinks) Read() error {
 func (o *PolicyLinks) Update() (*restapi.GenericMapResponse, error) {
 	oldplinks, rev, err := o.GetPlinks()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	// Only change plinks order, not insert or delete any from the list
@@ -110,15 +109,15 @@ func (o *PolicyLinks) Update() (*restapi.GenericMapResponse, error) {
 	queryArg["Plinks"] = newplinks
 	queryArg["RevStamp"] = rev
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
@@ -3,7 +3,7 @@ package platform
 import (
 	"fmt"
 
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -49,7 +49,7 @@ func NewRole(c *restapi.RestClient) *Role {
 func (o *Role) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -61,12 +61,12 @@ func (o *Role) Read() error {
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -82,7 +82,7 @@ func (o *Role) Read() error {
 	// Get admin rights
 	rights, err := o.GetAdminRights()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	var r []string
@@ -97,39 +97,40 @@ func (o *Role) Read() error {
 }
 
 // Create function creates a new role and returns a map that contains creation result
-func (o *Role) Create() (*restapi.GenericMapResponse, error) {
+func (o *Role) Create() (*restapi.BaseAPIResponse, error) {
 	var queryArg = make(map[string]interface{})
 	queryArg["Name"] = o.Name
 	if o.Description != "" {
 		queryArg["Description"] = o.Description
 	}
 
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallGenericMapAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 	// Upon successful creation, assign ID
 	o.ID = resp.Result["_RowKey"].(string)
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Delete function deletes a role and returns a map that contains deletion result
-func (o *Role) Delete() (*restapi.GenericMapResponse, error) {
+func (o *Role) Delete() (*restapi.BaseAPIResponse, error) {
 	return o.deleteObjectMapAPI("Name")
 }
 
 // Update function updates a existing role and returns a map that contains update result
-func (o *Role) Update() (*restapi.GenericMapResponse, error) {
+func (o *Role) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -141,19 +142,20 @@ func (o *Role) Update() (*restapi.GenericMapResponse, error) {
 		queryArg["Description"] = o.Description
 	}
 
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // GetAdminRights function fetches admin rights that are assigned to a role
@@ -161,7 +163,7 @@ func (o *Role) Update() (*restapi.GenericMapResponse, error) {
 func (o *Role) GetAdminRights() (map[string]interface{}, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -170,7 +172,7 @@ func (o *Role) GetAdminRights() (map[string]interface{}, error) {
 	rights := make(map[string]interface{})
 	resp, err := o.client.CallGenericMapAPI(o.apiGetRights, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
@@ -186,7 +188,7 @@ func (o *Role) GetAdminRights() (map[string]interface{}, error) {
 		}
 	} else {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
@@ -198,7 +200,7 @@ func (o *Role) GetAdminRights() (map[string]interface{}, error) {
 func (o *Role) AssignAdminRights() (*restapi.GenericMapResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArgs []map[string]interface{}
@@ -220,13 +222,13 @@ func (o *Role) AssignAdminRights() (*restapi.GenericMapResponse, error) {
 
 		resp, err := o.client.CallGenericMapListAPI(o.apiAssignRights, queryArgs)
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, err
 		}
 
 		if !resp.Success {
 			errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-			logger.Errorf(errmsg)
+			o.client.Log().Errorf(errmsg)
 			return nil, fmt.Errorf(errmsg)
 		}
 
@@ -241,7 +243,7 @@ func (o *Role) AssignAdminRights() (*restapi.GenericMapResponse, error) {
 func (o *Role) RemoveAdminRights(rights map[string]interface{}) (*restapi.GenericMapResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArgs []map[string]interface{}
@@ -254,13 +256,13 @@ func (o *Role) RemoveAdminRights(rights map[string]interface{}) (*restapi.Generi
 
 	resp, err := o.client.CallGenericMapListAPI(o.apiUnassignRights, queryArgs)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
@@ -277,7 +279,7 @@ func getAllAdminRights(client *restapi.RestClient) (map[string]interface{}, erro
 	rights := make(map[string]interface{})
 	resp, err := client.CallGenericMapAPI("/Redrock/Query", queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
@@ -290,10 +292,10 @@ func getAllAdminRights(client *restapi.RestClient) (map[string]interface{}, erro
 		}
 	} else {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
-	logger.Debugf("List of all admin rights: %v", rights)
+	client.Log().Debugf("List of all admin rights: %v", rights)
 
 	return rights, err
 }
@@ -304,7 +306,7 @@ func (o *Role) UpdateMembers(ids []string, action string, membertype string) (*r
 	var err error
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	if action != "Add" && action != "Delete" {
@@ -321,12 +323,12 @@ func (o *Role) UpdateMembers(ids []string, action string, membertype string) (*r
 	queryArg[membertype] = actionArg
 	resp, err = o.client.CallStringAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
@@ -337,7 +339,7 @@ func (o *Role) UpdateMembers(ids []string, action string, membertype string) (*r
 func (o *Role) UpdateRoleMembers(members []RoleMember, action string) (*restapi.StringResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	if action != "Add" && action != "Delete" {
@@ -375,12 +377,12 @@ func (o *Role) UpdateRoleMembers(members []RoleMember, action string) (*restapi.
 
 	resp, err := o.client.CallStringAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
@@ -390,7 +392,7 @@ func (o *Role) UpdateRoleMembers(members []RoleMember, action string) (*restapi.
 func (o *Role) getMembers() ([]RoleMember, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -400,7 +402,7 @@ func (o *Role) getMembers() ([]RoleMember, error) {
 	var members []RoleMember
 	resp, err := o.client.CallGenericMapAPI(o.apiGetRoleMembers, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
@@ -417,21 +419,35 @@ func (o *Role) getMembers() ([]RoleMember, error) {
 		}
 	} else {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	return members, nil
 }
 
+// Validate checks role attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *Role) Validate() error {
+	v := &validator{}
+	v.required("name", o.Name)
+	for i, m := range o.Members {
+		path := fmt.Sprintf("member[%d]", i)
+		v.required(path+".type", m.MemberType)
+		v.oneOf(path+".type", m.MemberType, []string{"User", "Group", "Role"})
+		v.check(m.MemberID != "" || m.MemberName != "", path+".name", "name or id is required")
+	}
+
+	return v.err(o, o.Name)
+}
+
 // Query function returns a single role object in map format
 func (o *Role) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM Role WHERE 1=1"
+	q := query.Select("Role")
 	if o.Name != "" {
-		query += " AND Name='" + o.Name + "'"
+		q.Where(query.Eq("Name", o.Name))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 // GetIDByName returns role ID by name
@@ -440,12 +456,18 @@ func (o *Role) GetIDByName() (string, error) {
 		return "", fmt.Errorf("Role name must be provided")
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("Error retrieving role: %s", err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, "", o.ID)
 
 	return o.ID, nil
 }
@@ -455,31 +477,31 @@ func (o *Role) GetByName() error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf("Failed to find ID of role %s. %v", o.Name, err)
 		}
 	}
 
 	err := o.Read()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	return nil
 }
 
 // DeleteByName deletes a role by name
-func (o *Role) DeleteByName() (*restapi.GenericMapResponse, error) {
+func (o *Role) DeleteByName() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, fmt.Errorf("Failed to find ID of role %s. %v", o.Name, err)
 		}
 	}
 	resp, err := o.Delete()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
@@ -646,3 +668,34 @@ func (o *Role) DeleteByName() (*restapi.GenericMapResponse, error) {
 		"Description": "AD accounts who can login to non-domain joined machines but without any privileges."
 	}
 */
+
+// RoleFilter selects roles returned by ListRoles. Empty fields are ignored
+type RoleFilter struct {
+	ListOptions
+	Name string // Substring of role name
+}
+
+// RoleIterator iterates over roles returned by ListRoles
+type RoleIterator struct {
+	*Iterator
+}
+
+// Value returns current role. Only attributes returned by query are filled, call Read() for the rest
+func (it *RoleIterator) Value() *Role {
+	obj := NewRole(it.client)
+	it.decode(obj)
+	return obj
+}
+
+// ListRoles returns iterator over roles that match filter. Pages are fetched as iteration goes
+func ListRoles(c *restapi.RestClient, filter RoleFilter) *RoleIterator {
+	q, err := listQuery(c, "Role", "")
+	if err != nil {
+		return &RoleIterator{errIterator(err)}
+	}
+	if filter.Name != "" {
+		q.Where(query.Contains("Name", filter.Name))
+	}
+
+	return &RoleIterator{newIterator(c, q, filter.ListOptions)}
+}
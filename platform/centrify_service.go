@@ -6,8 +6,10 @@ import (
 
 	"github.com/marcozj/golang-sdk/enum/computerclass"
 	"github.com/marcozj/golang-sdk/enum/resourcetype"
+	"github.com/marcozj/golang-sdk/enum/servicetype"
 	"github.com/marcozj/golang-sdk/enum/settype"
-	logger "github.com/marcozj/golang-sdk/logging"
+	weekday "github.com/marcozj/golang-sdk/enum/weekday"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -43,6 +45,8 @@ func NewService(c *restapi.RestClient) *Service {
 	s.apiDelete = "/Subscriptions/DeleteSubscription"
 	s.apiUpdate = "/Subscriptions/UpdateSubscription"
 	s.apiPermissions = "/Subscriptions/SetSubscriptionPermissions"
+	s.apiGetPermissions = "/Acl/GetRowAces"
+	s.aclTable = "Subscriptions"
 
 	return &s
 }
@@ -51,7 +55,7 @@ func NewService(c *restapi.RestClient) *Service {
 func (o *Service) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -61,12 +65,12 @@ func (o *Service) Read() error {
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -76,7 +80,7 @@ func (o *Service) Read() error {
 }
 
 // Create function creates a new Service
-func (o *Service) Create() (*restapi.StringResponse, error) {
+func (o *Service) Create() (*restapi.BaseAPIResponse, error) {
 	err := o.resolveIDs()
 	if err != nil {
 		return nil, err
@@ -85,38 +89,39 @@ func (o *Service) Create() (*restapi.StringResponse, error) {
 	var queryArg = make(map[string]interface{})
 	queryArg, err = generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallStringAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	// Assign ID after successful creation so that the same object can be used for subsequent update operation
 	o.ID = resp.Result
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Delete function deletes a Service
-func (o *Service) Delete() (*restapi.GenericMapResponse, error) {
+func (o *Service) Delete() (*restapi.BaseAPIResponse, error) {
 	return o.deleteObjectMapAPI("")
 }
 
 // Update function updates an existing Service
-func (o *Service) Update() (*restapi.StringResponse, error) {
+func (o *Service) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
@@ -128,33 +133,61 @@ func (o *Service) Update() (*restapi.StringResponse, error) {
 	var queryArg = make(map[string]interface{})
 	queryArg, err = generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallStringAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
+}
+
+// Validate checks service attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *Service) Validate() error {
+	v := &validator{}
+	v.check(o.SystemID != "" || o.SystemName != "", "system_id", "is required")
+	v.required("service_name", o.Name)
+	v.required("service_type", o.ServiceType)
+	v.oneOf("service_type", o.ServiceType, servicetype.Values())
+	if o.EnableManagement {
+		admin := o.AdminAccountID != "" || o.AdminAccountUPN != ""
+		multiplexed := o.MultiplexedAccountID != "" || o.MultiplexedAccountName != ""
+		v.check(multiplexed, "multiplexed_account_id", "is required when enable_management is enabled")
+		v.check(admin, "admin_account_id", "is required when enable_management is enabled")
+	}
+	if o.RestartTimeRestriction {
+		v.required("days_of_week", o.DaysOfWeek)
+		v.required("restart_start_time", o.RestartStartTime)
+		v.required("restart_end_time", o.RestartEndTime)
+	}
+	if o.DaysOfWeek != "" {
+		for _, day := range strings.Split(o.DaysOfWeek, ",") {
+			v.oneOf("days_of_week", strings.TrimSpace(day), weekday.Values())
+		}
+	}
+
+	return v.err(o, o.Name)
 }
 
 // Query function returns a single Service object in map format
 func (o *Service) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM Subscriptions WHERE 1=1"
+	q := query.Select("Subscriptions")
 	if o.Name != "" {
-		query += " AND WindowsServiceName='" + o.Name + "'"
+		q.Where(query.Eq("WindowsServiceName", o.Name))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 // GetIDByName returns service ID by name
@@ -163,12 +196,18 @@ func (o *Service) GetIDByName() (string, error) {
 		return "", fmt.Errorf("Service name must be provided")
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("Error retrieving service: %s", err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, "", o.ID)
 
 	return o.ID, nil
 }
@@ -178,7 +217,7 @@ func (o *Service) GetByName() error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf("Failed to find ID of service %s. %v", o.Name, err)
 		}
 	}
@@ -191,11 +230,11 @@ func (o *Service) GetByName() error {
 }
 
 // DeleteByName deletes a service by name
-func (o *Service) DeleteByName() (*restapi.GenericMapResponse, error) {
+func (o *Service) DeleteByName() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, fmt.Errorf("Failed to find ID of service %s. %v", o.Name, err)
 		}
 	}
@@ -217,7 +256,7 @@ func (o *Service) resolveIDs() error {
 		system.ComputerClass = computerclass.Windows.String()
 		o.SystemID, err = system.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf(err.Error())
 		}
 	}
@@ -244,7 +283,7 @@ func (o *Service) resolveIDs() error {
 
 			o.AdminAccountID, err = account.GetIDByName()
 			if err != nil {
-				logger.Errorf(err.Error())
+				o.client.Log().Errorf(err.Error())
 				return fmt.Errorf(err.Error())
 			}
 		}
@@ -255,7 +294,7 @@ func (o *Service) resolveIDs() error {
 			mplex.Name = o.MultiplexedAccountName
 			o.MultiplexedAccountID, err = mplex.GetIDByName()
 			if err != nil {
-				logger.Errorf(err.Error())
+				o.client.Log().Errorf(err.Error())
 				return fmt.Errorf(err.Error())
 			}
 		}
@@ -417,3 +456,35 @@ https://developer.centrify.com/reference#post_subscriptions-deletesubscription
 		"InnerExceptions":null
 	}
 */
+
+// ServiceFilter selects services returned by ListServices. Empty fields are ignored
+type ServiceFilter struct {
+	ListOptions
+	Name  string // Substring of service name
+	SetID string // Only services in this set
+}
+
+// ServiceIterator iterates over services returned by ListServices
+type ServiceIterator struct {
+	*Iterator
+}
+
+// Value returns current service. Only attributes returned by query are filled, call Read() for the rest
+func (it *ServiceIterator) Value() *Service {
+	obj := NewService(it.client)
+	it.decode(obj)
+	return obj
+}
+
+// ListServices returns iterator over services that match filter. Pages are fetched as iteration goes
+func ListServices(c *restapi.RestClient, filter ServiceFilter) *ServiceIterator {
+	q, err := listQuery(c, "Subscriptions", filter.SetID)
+	if err != nil {
+		return &ServiceIterator{errIterator(err)}
+	}
+	if filter.Name != "" {
+		q.Where(query.Contains("WindowsServiceName", filter.Name))
+	}
+
+	return &ServiceIterator{newIterator(c, q, filter.ListOptions)}
+}
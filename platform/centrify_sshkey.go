@@ -3,9 +3,12 @@ package platform
 import (
 	"fmt"
 
+	"github.com/marcozj/golang-sdk/audit"
+	"github.com/marcozj/golang-sdk/enum/keypairtype"
 	"github.com/marcozj/golang-sdk/enum/settype"
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
+	"github.com/marcozj/golang-sdk/sshkey"
 )
 
 // SSHKey - Encapsulates a single generic SSHKey
@@ -39,6 +42,8 @@ func NewSSHKey(c *restapi.RestClient) *SSHKey {
 	s.apiRetrieve = "/ServerManage/RetrieveSshKey"
 	s.apiGetChallenge = "/ServerManage/GetSshKeyRightsAndChallenges"
 	s.apiPermissions = "/ServerManage/SetSSHKeyPermissions"
+	s.apiGetPermissions = "/Acl/GetRowAces"
+	s.aclTable = "SshKeys"
 
 	return &s
 }
@@ -47,7 +52,7 @@ func NewSSHKey(c *restapi.RestClient) *SSHKey {
 func (o *SSHKey) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -55,14 +60,14 @@ func (o *SSHKey) Read() error {
 
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
-	logger.Debugf("Response for SSHKey from tenant: %v", resp)
+	o.client.Log().Debugf("Response for SSHKey from tenant: %v", resp)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -71,12 +76,12 @@ func (o *SSHKey) Read() error {
 	// Get SSH Key challenge profile information
 	resp, err = o.client.CallGenericMapAPI(o.apiGetChallenge, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 	if v, ok := resp.Result["Challenges"]; ok {
@@ -96,87 +101,110 @@ func (o *SSHKey) Read() error {
 }
 
 // Create function creates a new SSHKey and returns a map that contains creation result
-func (o *SSHKey) Create() (*restapi.StringResponse, error) {
+func (o *SSHKey) Create() (*restapi.BaseAPIResponse, error) {
 	var queryArg = make(map[string]interface{})
 	queryArg, err := generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	// Special handling of challenge checkout profile
 	queryArg["updateChallenges"] = true
 
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
 
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallStringAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	// Assign ID after successful creation so that the same object can be used for subsequent update operation
 	o.ID = resp.Result
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Delete function deletes a SSHKey and returns a map that contains deletion result
-func (o *SSHKey) Delete() (*restapi.StringResponse, error) {
+func (o *SSHKey) Delete() (*restapi.BaseAPIResponse, error) {
 	return o.deleteObjectStringAPI("")
 }
 
 // Update function updates an existing SSHKey and returns a map that contains update result
-func (o *SSHKey) Update() (*restapi.GenericMapResponse, error) {
+func (o *SSHKey) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
 	queryArg, err := generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	// Special handling of password checkout profile
 	queryArg["updateChallenges"] = true
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
+}
+
+// Validate checks SSH key attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *SSHKey) Validate() error {
+	v := &validator{}
+	v.required("name", o.Name)
+	if o.ID == "" {
+		v.required("private_key", o.PrivateKey)
+	}
+	v.oneOf("key_pair_type", o.KeyPairType, keypairtype.Values())
+	v.challengeRules("challenge_rule", o.ChallengeRules)
+
+	return v.err(o, o.Name)
 }
 
 // Query function returns a single SSHKey object in map format
 func (o *SSHKey) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM SshKeys WHERE 1=1"
+	q := query.Select("SshKeys")
 	if o.Name != "" {
-		query += " AND Name='" + o.Name + "'"
+		q.Where(query.Eq("Name", o.Name))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 // RetriveSSHKey retrieves SSH Key from vault
-func (o *SSHKey) RetriveSSHKey() (string, error) {
+func (o *SSHKey) RetriveSSHKey() (sshkey string, err error) {
+	defer func() {
+		recordAccess(o.client, audit.ActionRetrieveSSHKey, settype.SSHKey.String(), o.ID, o.Name, "", err)
+	}()
+
+	return o.retrieveSSHKey()
+}
+
+func (o *SSHKey) retrieveSSHKey() (string, error) {
 	if o.ID == "" && o.Name == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return "", fmt.Errorf(errormsg)
 	}
 	// If SSHKey name is provided, try to find out its ID
@@ -184,7 +212,7 @@ func (o *SSHKey) RetriveSSHKey() (string, error) {
 		var err error
 		o.ID, err = o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return "", err
 		}
 	}
@@ -203,31 +231,103 @@ func (o *SSHKey) RetriveSSHKey() (string, error) {
 
 	resp, err := o.client.CallStringAPI(o.apiRetrieve, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return "", fmt.Errorf(errmsg)
 	}
 
 	return resp.Result, nil
 }
 
+// SetKeyPair sets private key to be uploaded from key pair that is generated or parsed by sshkey package.
+// Private key is encrypted with passphrase if it isn't empty
+func (o *SSHKey) SetKeyPair(k *sshkey.KeyPair, passphrase string) error {
+	data, err := k.Marshal(sshkey.PEM, passphrase)
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return err
+	}
+	o.PrivateKey = string(data)
+	o.Passphrase = passphrase
+	if o.Description == "" {
+		o.Description = k.Comment
+	}
+
+	return nil
+}
+
+// RetrieveKeyPair retrieves private key from vault and parses it so that it can be converted locally, e.g.
+// to PuTTY format with Export(keypairtype.PuTTY, passphrase). Passphrase of SSHKey unlocks the key. Description is
+// used as comment if key has none
+func (o *SSHKey) RetrieveKeyPair() (*sshkey.KeyPair, error) {
+	o.KeyPairType = keypairtype.PrivateKey.String()
+	thekey, err := o.RetriveSSHKey()
+	if err != nil {
+		return nil, err
+	}
+	k, err := sshkey.Parse([]byte(thekey), o.Passphrase)
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return nil, err
+	}
+	if k.Comment == "" {
+		k.Comment = o.Description
+	}
+
+	return k, nil
+}
+
+// GenerateSSHKey generates key pair locally and uploads it to vault as SSHKey with name. Private key is encrypted
+// with passphrase if it isn't empty. It returns created SSHKey and authorized_keys line of public key
+func GenerateSSHKey(c *restapi.RestClient, name string, opts sshkey.Options, passphrase string) (*SSHKey, string, error) {
+	k, err := sshkey.Generate(opts)
+	if err != nil {
+		c.Log().Errorf(err.Error())
+		return nil, "", err
+	}
+	authorizedKey, err := k.AuthorizedKey()
+	if err != nil {
+		c.Log().Errorf(err.Error())
+		return nil, "", err
+	}
+
+	obj := NewSSHKey(c)
+	obj.Name = name
+	err = obj.SetKeyPair(k, passphrase)
+	if err != nil {
+		return nil, "", err
+	}
+	_, err = obj.Create()
+	if err != nil {
+		return nil, "", fmt.Errorf("Error creating sshkey %s: %v", name, err)
+	}
+
+	return obj, authorizedKey, nil
+}
+
 // GetIDByName returns SSHKey ID by name
 func (o *SSHKey) GetIDByName() (string, error) {
 	if o.Name == "" {
 		return "", fmt.Errorf("SSHKey name must be provided")
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("Error retrieving SSHKey: %s", err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, "", o.ID)
 
 	return o.ID, nil
 }
@@ -237,7 +337,7 @@ func (o *SSHKey) GetByName() error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf("Failed to find ID of sshkey %s. %v", o.Name, err)
 		}
 	}
@@ -250,11 +350,11 @@ func (o *SSHKey) GetByName() error {
 }
 
 // DeleteByName deletes a sshkey by name
-func (o *SSHKey) DeleteByName() (*restapi.StringResponse, error) {
+func (o *SSHKey) DeleteByName() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, fmt.Errorf("Failed to find ID of sshkey %s. %v", o.Name, err)
 		}
 	}
@@ -412,3 +512,35 @@ func (o *SSHKey) DeleteByName() (*restapi.StringResponse, error) {
 			"InnerExceptions": null
 		}
 */
+
+// SSHKeyFilter selects SSH keys returned by ListSSHKeys. Empty fields are ignored
+type SSHKeyFilter struct {
+	ListOptions
+	Name  string // Substring of SSH key name
+	SetID string // Only SSH keys in this set
+}
+
+// SSHKeyIterator iterates over SSH keys returned by ListSSHKeys
+type SSHKeyIterator struct {
+	*Iterator
+}
+
+// Value returns current SSH key. Only attributes returned by query are filled, call Read() for the rest
+func (it *SSHKeyIterator) Value() *SSHKey {
+	obj := NewSSHKey(it.client)
+	it.decode(obj)
+	return obj
+}
+
+// ListSSHKeys returns iterator over SSH keys that match filter. Pages are fetched as iteration goes
+func ListSSHKeys(c *restapi.RestClient, filter SSHKeyFilter) *SSHKeyIterator {
+	q, err := listQuery(c, "SshKeys", filter.SetID)
+	if err != nil {
+		return &SSHKeyIterator{errIterator(err)}
+	}
+	if filter.Name != "" {
+		q.Where(query.Contains("Name", filter.Name))
+	}
+
+	return &SSHKeyIterator{newIterator(c, q, filter.ListOptions)}
+}
@@ -2,8 +2,9 @@ package platform
 
 import (
 	"fmt"
+	"strings"
 
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -50,7 +51,7 @@ func NewUser(c *restapi.RestClient) *User {
 func (o *User) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -60,12 +61,12 @@ func (o *User) Read() error {
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -76,12 +77,12 @@ func (o *User) Read() error {
 }
 
 // Delete function deletes a user and returns a map that contains deletion result
-func (o *User) Delete() (*restapi.GenericMapResponse, error) {
+func (o *User) Delete() (*restapi.BaseAPIResponse, error) {
 	return o.deleteObjectMapAPI("")
 }
 
 // Create function creates a new user and returns a map that contains creation result
-func (o *User) Create() (*restapi.StringResponse, error) {
+func (o *User) Create() (*restapi.BaseAPIResponse, error) {
 	var queryArg = make(map[string]interface{})
 	// During user creation, InEverybodyRole must be set to false for OAuth client user.
 	// If not, when edit user from portal the save button is activated although user creation is successful and nothing is changed
@@ -92,64 +93,66 @@ func (o *User) Create() (*restapi.StringResponse, error) {
 	}
 	queryArg, err := generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
 
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallStringAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 	// Upon successful creation, assign ID
 	o.ID = resp.Result
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Update function updates a existing user and returns a map that contains update result
-func (o *User) Update() (*restapi.GenericMapResponse, error) {
+func (o *User) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
 	queryArg, err := generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
 
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // ChangePassword function changes user's password
 func (o *User) ChangePassword() (*restapi.BoolResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -159,13 +162,13 @@ func (o *User) ChangePassword() (*restapi.BoolResponse, error) {
 
 	resp, err := o.client.CallBoolAPI(o.apiUpdatePassword, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
@@ -181,28 +184,41 @@ func (o *User) ChangeUserPassword(pw string) error {
 		}
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf("Failed to find ID of user %s. %v", o.Name, err)
 		}
 	}
 	o.Password = pw
 	_, err := o.ChangePassword()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
 	return nil
 }
 
+// Validate checks user attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *User) Validate() error {
+	v := &validator{}
+	v.required("username", o.Name)
+	if o.ID == "" && !o.SendEmailInvite {
+		v.required("password", o.Password)
+	}
+	v.check(o.ConfirmPassword == "" || o.ConfirmPassword == o.Password, "confirm_password", "doesn't match password")
+	v.check(!strings.Contains(o.Name, " "), "username", "can't contain space")
+
+	return v.err(o, o.Name)
+}
+
 // Query function returns a single user object in map format
 func (o *User) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM User WHERE 1=1"
+	q := query.Select("User")
 	if o.Name != "" {
-		query += " AND Username='" + o.Name + "'"
+		q.Where(query.Eq("Username", o.Name))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 // GetIDByName returns user ID by name
@@ -211,12 +227,18 @@ func (o *User) GetIDByName() (string, error) {
 		return "", fmt.Errorf("User name must be provided")
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("Error retrieving user '%s': %s", o.Name, err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, "", o.ID)
 
 	return o.ID, nil
 }
@@ -258,7 +280,7 @@ func (o *User) AddToRoles(roles []string) error {
 }
 
 // DeleteByName deletes a Centrify Directory user by username
-func (o *User) DeleteByName() (*restapi.GenericMapResponse, error) {
+func (o *User) DeleteByName() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
@@ -462,3 +484,34 @@ func (o *User) DeleteByName() (*restapi.GenericMapResponse, error) {
 		Respond result
 
 */
+
+// UserFilter selects users returned by ListUsers. Empty fields are ignored
+type UserFilter struct {
+	ListOptions
+	Name string // Substring of user name
+}
+
+// UserIterator iterates over users returned by ListUsers
+type UserIterator struct {
+	*Iterator
+}
+
+// Value returns current user. Only attributes returned by query are filled, call Read() for the rest
+func (it *UserIterator) Value() *User {
+	obj := NewUser(it.client)
+	it.decode(obj)
+	return obj
+}
+
+// ListUsers returns iterator over users that match filter. Pages are fetched as iteration goes
+func ListUsers(c *restapi.RestClient, filter UserFilter) *UserIterator {
+	q, err := listQuery(c, "User", "")
+	if err != nil {
+		return &UserIterator{errIterator(err)}
+	}
+	if filter.Name != "" {
+		q.Where(query.Contains("Username", filter.Name))
+	}
+
+	return &UserIterator{newIterator(c, q, filter.ListOptions)}
+}
@@ -3,11 +3,15 @@ package platform
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/marcozj/golang-sdk/audit"
+	"github.com/marcozj/golang-sdk/enum/keypairtype"
 	"github.com/marcozj/golang-sdk/enum/resourcetype"
 	"github.com/marcozj/golang-sdk/enum/settype"
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
+	"github.com/marcozj/golang-sdk/sshkey"
 )
 
 // Account - Encapsulates a single generic Account
@@ -18,10 +22,12 @@ type Account struct {
 	ResourceName string `json:"-"`
 	// Account specific APIs
 	apiUpdatePassword    string
+	apiRotatePassword    string
 	apiCheckDelete       string
 	apiGetChallenge      string
 	apiCheckoutPassword  string
 	apiCheckinPassword   string
+	apiExtendCheckout    string
 	apiSetAdminAccount   string
 	apiGetAccessKeys     string
 	apiRetrieveAccessKey string
@@ -74,11 +80,15 @@ func NewAccount(c *restapi.RestClient) *Account {
 	s.apiDelete = "/ServerManage/DeleteAccount"
 	s.apiUpdate = "/ServerManage/UpdateAccount"
 	s.apiUpdatePassword = "/ServerManage/UpdatePassword"
+	s.apiRotatePassword = "/ServerManage/RotatePassword"
 	s.apiCheckDelete = "/ServerManage/CanDeleteAccount"
 	s.apiGetChallenge = "/ServerManage/GetAccountChallenges"
 	s.apiCheckoutPassword = "/ServerManage/CheckoutPassword"
 	s.apiCheckinPassword = "/ServerManage/CheckinPassword"
+	s.apiExtendCheckout = "/ServerManage/ExtendCheckout"
 	s.apiPermissions = "/ServerManage/SetAccountPermissions"
+	s.apiGetPermissions = "/Acl/GetRowAces"
+	s.aclTable = "VaultAccount"
 	s.apiSetAdminAccount = "/ServerManage/SetAdministrativeAccounts"
 	s.apiGetAccessKeys = "/Aws/GetAccessKeys"
 	s.apiRetrieveAccessKey = "/Aws/RetrieveAccessKey"
@@ -93,7 +103,7 @@ func NewAccount(c *restapi.RestClient) *Account {
 func (o *Account) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -102,13 +112,13 @@ func (o *Account) Read() error {
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -120,12 +130,12 @@ func (o *Account) Read() error {
 	// Get password checkout profile information
 	resp, err = o.client.CallGenericMapAPI(o.apiGetChallenge, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 	if v, ok := resp.Result["PasswordCheckoutDefaultProfile"]; ok {
@@ -151,7 +161,7 @@ func (o *Account) Read() error {
 	if o.CloudProviderID != "" {
 		keys, err := o.GetAccessKeys()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return err
 		}
 		for _, key := range keys {
@@ -163,24 +173,24 @@ func (o *Account) Read() error {
 }
 
 // Create function creates a new Account and returns a map that contains creation result
-func (o *Account) Create() (*restapi.StringResponse, error) {
+func (o *Account) Create() (*restapi.BaseAPIResponse, error) {
 	// Resolve host id if not provided
 	err := o.resolveHostID()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	err = o.processWorkflow()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	var queryArg = make(map[string]interface{})
 	queryArg, err = generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	// Special handling of password checkout profile
@@ -190,55 +200,56 @@ func (o *Account) Create() (*restapi.StringResponse, error) {
 		queryArg["WorkflowSent"] = true
 	}
 
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
 
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallStringAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	// Assign ID after successful creation so that the same object can be used for subsequent update operation
 	o.ID = resp.Result
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Delete function deletes a Account and returns a map that contains deletion result
-func (o *Account) Delete() (*restapi.BoolResponse, error) {
+func (o *Account) Delete() (*restapi.BaseAPIResponse, error) {
 	return o.deleteObjectBoolAPI("")
 }
 
 // Update function updates an existing Account and returns a map that contains update result
-func (o *Account) Update() (*restapi.GenericMapResponse, error) {
+func (o *Account) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
 	// Resolve host id if not provided
 	err := o.resolveHostID()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	err = o.processWorkflow()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	var queryArg = make(map[string]interface{})
 	queryArg, err = generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	// Special handling of password checkout profile
@@ -247,28 +258,29 @@ func (o *Account) Update() (*restapi.GenericMapResponse, error) {
 	// Need to always send this when workflow is turned on and off
 	queryArg["WorkflowSent"] = true
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
 
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // ChangePassword function updates an existing Account password and returns a map that contains update result
 func (o *Account) ChangePassword() (*restapi.BoolResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -278,13 +290,97 @@ func (o *Account) ChangePassword() (*restapi.BoolResponse, error) {
 
 	resp, err := o.client.CallBoolAPI(o.apiUpdatePassword, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
+		return nil, fmt.Errorf(errmsg)
+	}
+
+	return resp, nil
+}
+
+// RotationStatus is password rotation state of an account
+type RotationStatus struct {
+	IsManaged          bool
+	LastChange         time.Time // Zero if tenant has no record of password change
+	Healthy            string    // Result of last health check such as "OK" or "Unknown"
+	HealthError        string
+	LastHealthCheck    time.Time
+	NeedsPasswordReset string // "NotNeeded" unless rotation is pending
+	ResetRetryCount    int    // Number of failed attempts of pending rotation
+	ResetLastError     string
+}
+
+// PendingRotation returns true if tenant has a rotation that isn't done yet
+func (s *RotationStatus) PendingRotation() bool {
+	return s.NeedsPasswordReset != "" && s.NeedsPasswordReset != "NotNeeded"
+}
+
+// GetRotationStatus returns when password was last changed, whether it is managed, its health and pending rotation
+func (o *Account) GetRotationStatus() (*RotationStatus, error) {
+	if o.ID == "" {
+		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
+		o.client.Log().Errorf(errormsg)
+		return nil, fmt.Errorf(errormsg)
+	}
+	q := query.Select("VaultAccount", "ID", "IsManaged", "LastChange", "Healthy", "HealthError", "LastHealthCheck",
+		"NeedsPasswordReset", "PasswordResetRetryCount", "PasswordResetLastError").Where(query.Eq("ID", o.ID))
+	row, err := queryVaultObject(o.client, q)
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return nil, err
+	}
+
+	s := &RotationStatus{}
+	s.IsManaged, _ = row["IsManaged"].(bool)
+	s.LastChange = parseDate(row["LastChange"])
+	s.Healthy, _ = row["Healthy"].(string)
+	s.HealthError, _ = row["HealthError"].(string)
+	s.LastHealthCheck = parseDate(row["LastHealthCheck"])
+	s.NeedsPasswordReset, _ = row["NeedsPasswordReset"].(string)
+	if v, ok := row["PasswordResetRetryCount"].(float64); ok {
+		s.ResetRetryCount = int(v)
+	}
+	s.ResetLastError, _ = row["PasswordResetLastError"].(string)
+
+	return s, nil
+}
+
+// RotatePassword asks vault to rotate password of managed account
+func (o *Account) RotatePassword() (resp *restapi.BoolResponse, err error) {
+	defer func() {
+		recordAccess(o.client, audit.ActionRotatePassword, settype.Account.String(), o.ID, o.User, "", err)
+	}()
+
+	if o.ID == "" {
+		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
+		o.client.Log().Errorf(errormsg)
+		return nil, fmt.Errorf(errormsg)
+	}
+	status, err := o.GetRotationStatus()
+	if err != nil {
+		return nil, err
+	}
+	if !status.IsManaged {
+		return nil, fmt.Errorf("Password of account %s isn't managed and can't be rotated", o.User)
+	}
+
+	var queryArg = make(map[string]interface{})
+	queryArg["ID"] = o.ID
+
+	resp, err = o.client.CallBoolAPI(o.apiRotatePassword, queryArg)
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return nil, err
+	}
+
+	if !resp.Success {
+		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
@@ -302,33 +398,68 @@ func (o *Account) ValidateCredentialType() error {
 	return nil
 }
 
+// Validate checks account attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *Account) Validate() error {
+	v := &validator{}
+	v.required("name", o.User)
+
+	// Account belongs to exactly one resource, given either by ID or by ResourceType and ResourceName
+	resources := 0
+	for _, id := range []string{o.Host, o.DomainID, o.DatabaseID, o.CloudProviderID} {
+		if id != "" {
+			resources++
+		}
+	}
+	switch {
+	case resources > 1:
+		v.add("host_id", "only one of host_id, domain_id, database_id and cloudprovider_id can be set")
+	case resources == 0 && o.ResourceType != "":
+		v.oneOf("ResourceType", o.ResourceType, resourcetype.Values())
+		v.required("ResourceName", o.ResourceName)
+	case resources == 0 && o.ID == "":
+		v.add("host_id", "one of host_id, domain_id, database_id and cloudprovider_id is required")
+	}
+
+	v.oneOf("credential_type", o.CredentialType, []string{"Password", "SshKey"})
+	if o.ID == "" {
+		v.check(o.CredentialType != "Password" || o.Password != "", "password", "is required when credential_type is Password")
+	}
+	v.check(o.CredentialType != "SshKey" || o.SSHKeyID != "", "sshkey_id", "is required when credential_type is SshKey")
+	v.inRange("checkout_lifetime", o.DefaultCheckoutTime, 15, maxInt32)
+	v.challengeRules("challenge_rule", o.ChallengeRules)
+	v.challengeRules("access_secret_checkout_rule", o.AccessSecretCheckoutRules)
+	v.approvers("workflow_approver", o.WorkflowEnabled && o.WorkflowApprovers == "", o.WorkflowApproverList)
+
+	return v.err(o, o.User)
+}
+
 // Query function returns a single Account object in map format
 func (o *Account) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM VaultAccount WHERE 1=1"
+	q := query.Select("VaultAccount")
 	if o.User != "" {
-		query += " AND User='" + o.User + "'"
+		q.Where(query.Eq("User", o.User))
 	}
 	if o.Host != "" {
-		query += " AND Host='" + o.Host + "'"
+		q.Where(query.Eq("Host", o.Host))
 	}
 	if o.DatabaseID != "" {
-		query += " AND DatabaseID='" + o.DatabaseID + "'"
+		q.Where(query.Eq("DatabaseID", o.DatabaseID))
 	}
 	if o.DomainID != "" {
-		query += " AND DomainID='" + o.DomainID + "'"
+		q.Where(query.Eq("DomainID", o.DomainID))
 	}
 	if o.CloudProviderID != "" {
-		query += " AND CloudProviderId='" + o.CloudProviderID + "'"
+		q.Where(query.Eq("CloudProviderId", o.CloudProviderID))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 // CheckoutPassword checks out account password from vault
 func (o *Account) checkoutPassword() (*restapi.GenericMapResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -337,56 +468,72 @@ func (o *Account) checkoutPassword() (*restapi.GenericMapResponse, error) {
 
 	resp, err := o.client.CallGenericMapAPI(o.apiCheckoutPassword, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	return resp, nil
 }
 
-// CheckoutPassword checks out account password from vault
-// Returns actual password, coid or error
-func (o *Account) CheckoutPassword(checkin bool) (string, error) {
+// resolveCheckoutID finds ID of account to be checked out
+func (o *Account) resolveCheckoutID() error {
 	// To checkout account password, we must know its ID
 	// In order to know the ID of the account, we must know username + Host/DatabaseID/DomainID
 	if o.ID == "" {
 		// if ID is unknown, try to find out using User, ResourceType and ResourceName
 		_, err := o.getResourceID()
 		if err != nil {
-			logger.Errorf(err.Error())
-			return "", err
+			o.client.Log().Errorf(err.Error())
+			return err
 		}
 		acctresult, err := o.Query()
 		if err != nil {
-			logger.Errorf(err.Error())
-			return "", fmt.Errorf("Error retrieving account object: %s", err)
+			o.client.Log().Errorf(err.Error())
+			return fmt.Errorf("Error retrieving account object: %s", err)
 		}
 		o.ID = acctresult["ID"].(string)
 	}
 	// Check again if ID is known
 	if o.ID == "" {
-		return "", fmt.Errorf("Missing ID for account %s in %s with type %s", o.User, o.ResourceName, o.ResourceType)
+		return fmt.Errorf("Missing ID for account %s in %s with type %s", o.User, o.ResourceName, o.ResourceType)
+	}
+	return nil
+}
+
+// CheckoutPassword checks out account password from vault
+// Returns actual password, coid or error
+func (o *Account) CheckoutPassword(checkin bool) (password string, err error) {
+	var coid string
+	defer func() {
+		recordAccess(o.client, audit.ActionCheckoutPassword, settype.Account.String(), o.ID, o.User, coid, err)
+	}()
+
+	err = o.resolveCheckoutID()
+	if err != nil {
+		return "", err
 	}
 
 	// Checking out password
 	reply, err := o.checkoutPassword()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", err
 	}
 
+	if v, ok := reply.Result["COID"].(string); ok {
+		coid = v
+	}
 	if pw, ok := reply.Result["Password"]; ok {
 		if checkin {
-			coid := reply.Result["COID"]
-			if coid != nil {
-				result, err := o.CheckinPassword(coid.(string))
+			if coid != "" {
+				result, err := o.CheckinPassword(coid)
 				if err != nil {
-					logger.Errorf(err.Error())
+					o.client.Log().Errorf(err.Error())
 					return pw.(string), err
 				}
 				if !result.Success {
@@ -402,23 +549,27 @@ func (o *Account) CheckoutPassword(checkin bool) (string, error) {
 }
 
 // CheckinPassword checks in an checked out account password
-func (o *Account) CheckinPassword(coid string) (*restapi.BoolResponse, error) {
+func (o *Account) CheckinPassword(coid string) (resp *restapi.BoolResponse, err error) {
+	defer func() {
+		recordAccess(o.client, audit.ActionCheckinPassword, settype.Account.String(), o.ID, o.User, coid, err)
+	}()
+
 	if coid == "" {
 		errormsg := fmt.Sprintf("Missing COID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
 	queryArg["ID"] = coid
 
-	resp, err := o.client.CallBoolAPI(o.apiCheckinPassword, queryArg)
+	resp, err = o.client.CallBoolAPI(o.apiCheckinPassword, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
@@ -426,12 +577,16 @@ func (o *Account) CheckinPassword(coid string) (*restapi.BoolResponse, error) {
 }
 
 // RetrieveSSHKey retrieves SSH key from an account
-func (o *Account) RetrieveSSHKey(keytype string, passphrase string) (string, error) {
+func (o *Account) RetrieveSSHKey(keytype string, passphrase string) (key string, err error) {
+	defer func() {
+		recordAccess(o.client, audit.ActionRetrieveSSHKey, settype.Account.String(), o.ID, o.User, "", err)
+	}()
+
 	if o.ID == "" {
 		// if ID is unknown, try to find out using User, ResourceType and ResourceName
 		_, err := o.getResourceID()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return "", err
 		}
 		acctresult, err := o.Query()
@@ -449,18 +604,38 @@ func (o *Account) RetrieveSSHKey(keytype string, passphrase string) (string, err
 		return "", fmt.Errorf("SSH Key ID not found for account %s", o.User)
 	}
 
-	sshkey := NewSSHKey(o.client)
-	sshkey.ID = o.CredentialID
-	sshkey.KeyPairType = keytype
-	sshkey.Passphrase = passphrase
-	sshkey.KeyFormat = "PEM"
-	thekey, err := sshkey.RetriveSSHKey()
+	obj := NewSSHKey(o.client)
+	obj.ID = o.CredentialID
+	obj.KeyPairType = keytype
+	obj.Passphrase = passphrase
+	obj.KeyFormat = "PEM"
+	// Use unexported call so that only account access is recorded
+	key, err = obj.retrieveSSHKey()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("Error retrieve sshkey. %v", err)
 	}
 
-	return thekey, nil
+	return key, nil
+}
+
+// RetrieveKeyPair retrieves SSH private key of account and parses it so that it can be converted locally, e.g.
+// to PuTTY format with Export(keypairtype.PuTTY, passphrase). Account user is used as comment if key has none
+func (o *Account) RetrieveKeyPair(passphrase string) (*sshkey.KeyPair, error) {
+	thekey, err := o.RetrieveSSHKey(keypairtype.PrivateKey.String(), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	k, err := sshkey.Parse([]byte(thekey), passphrase)
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return nil, err
+	}
+	if k.Comment == "" {
+		k.Comment = o.User
+	}
+
+	return k, nil
 }
 
 func (o *Account) getResourceID() (string, error) {
@@ -484,7 +659,7 @@ func (o *Account) getResourceID() (string, error) {
 				resource.Name = o.ResourceName
 				result, err := resource.Query()
 				if err != nil {
-					logger.Errorf(err.Error())
+					o.client.Log().Errorf(err.Error())
 					return "", fmt.Errorf("Error retrieving system object: %s", err)
 				}
 				resourceID = result["ID"].(string)
@@ -494,7 +669,7 @@ func (o *Account) getResourceID() (string, error) {
 				resource.Name = o.ResourceName
 				result, err := resource.Query()
 				if err != nil {
-					logger.Errorf(err.Error())
+					o.client.Log().Errorf(err.Error())
 					return "", fmt.Errorf("Error retrieving database object: %s", err)
 				}
 				resourceID = result["ID"].(string)
@@ -504,7 +679,7 @@ func (o *Account) getResourceID() (string, error) {
 				resource.Name = o.ResourceName
 				result, err := resource.Query()
 				if err != nil {
-					logger.Errorf(err.Error())
+					o.client.Log().Errorf(err.Error())
 					return "", fmt.Errorf("Error retrieving domain object: %s", err)
 				}
 				resourceID = result["ID"].(string)
@@ -514,7 +689,7 @@ func (o *Account) getResourceID() (string, error) {
 				resource.Name = o.ResourceName
 				result, err := resource.Query()
 				if err != nil {
-					logger.Errorf(err.Error())
+					o.client.Log().Errorf(err.Error())
 					return "", fmt.Errorf("Error retrieving domain object: %s", err)
 				}
 				resourceID = result["ID"].(string)
@@ -546,7 +721,7 @@ func (o *Account) ResolveValidPermissions() {
 func (o *Account) SetAdminAccount(enable bool) error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -556,12 +731,12 @@ func (o *Account) SetAdminAccount(enable bool) error {
 	queryArg["Systems"] = []string{o.Host}
 	resp, err := o.client.CallGenericMapAPI(o.apiSetAdminAccount, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -572,7 +747,7 @@ func (o *Account) SetAdminAccount(enable bool) error {
 func (o *Account) VerifyAccessKey(key AccessKey) error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -585,12 +760,12 @@ func (o *Account) VerifyAccessKey(key AccessKey) error {
 
 	resp, err := o.client.CallStringAPI(o.apiVerifyAccessKey, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -601,7 +776,7 @@ func (o *Account) VerifyAccessKey(key AccessKey) error {
 func (o *Account) AddAccessKey(key AccessKey) error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -612,12 +787,12 @@ func (o *Account) AddAccessKey(key AccessKey) error {
 
 	resp, err := o.client.CallStringAPI(o.apiAddAccessKey, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -628,13 +803,13 @@ func (o *Account) AddAccessKey(key AccessKey) error {
 func (o *Account) SafeAddAccessKey(key AccessKey) error {
 	err := o.VerifyAccessKey(key)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
 	err = o.AddAccessKey(key)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	return nil
@@ -644,7 +819,7 @@ func (o *Account) SafeAddAccessKey(key AccessKey) error {
 func (o *Account) GetAccessKeys() ([]AccessKey, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -653,21 +828,21 @@ func (o *Account) GetAccessKeys() ([]AccessKey, error) {
 
 	resp, err := o.client.CallSliceAPI(o.apiGetAccessKeys, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	keys := []AccessKey{}
-	logger.Debugf("Get key response: %+v", resp)
+	o.client.Log().Debugf("Get key response: %+v", resp)
 	for _, p := range resp.Result {
 		key := &AccessKey{}
 		mapToStruct(key, p.(map[string]interface{}))
-		logger.Debugf("Filled key: %+v", key)
+		o.client.Log().Debugf("Filled key: %+v", key)
 		keys = append(keys, *key)
 	}
 
@@ -678,7 +853,7 @@ func (o *Account) GetAccessKeys() ([]AccessKey, error) {
 func (o *Account) DeleteAccessKey(id string) error {
 	if id == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 
@@ -686,22 +861,26 @@ func (o *Account) DeleteAccessKey(id string) error {
 	queryArg["AccessKeyRowkey"] = id
 	resp, err := o.client.CallStringAPI(o.apiDeleteAccessKey, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 	return nil
 }
 
 // RetrieveAccessKey retrieves secret access key
-func (o *Account) RetrieveAccessKey(accessKeyID string) (string, error) {
+func (o *Account) RetrieveAccessKey(accessKeyID string) (secretKey string, err error) {
+	defer func() {
+		recordAccess(o.client, audit.ActionRetrieveAccessKey, settype.Account.String(), o.ID, o.User, "", err)
+	}()
+
 	if accessKeyID == "" {
 		errormsg := fmt.Sprintf("Missing Access Key ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return "", fmt.Errorf(errormsg)
 	}
 
@@ -711,12 +890,12 @@ func (o *Account) RetrieveAccessKey(accessKeyID string) (string, error) {
 		// if ID is unknown, try to find out using User, ResourceType and ResourceName
 		_, err := o.getResourceID()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return "", err
 		}
 		acctresult, err := o.Query()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return "", fmt.Errorf("Error retrieving account object: %s", err)
 		}
 		o.ID = acctresult["ID"].(string)
@@ -728,7 +907,7 @@ func (o *Account) RetrieveAccessKey(accessKeyID string) (string, error) {
 
 	keys, err := o.GetAccessKeys()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", err
 	}
 	var id string
@@ -742,12 +921,12 @@ func (o *Account) RetrieveAccessKey(accessKeyID string) (string, error) {
 	queryArg["AccessKeyRowkey"] = id
 	resp, err := o.client.CallGenericMapAPI(o.apiRetrieveAccessKey, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return "", fmt.Errorf(errmsg)
 	}
 
@@ -760,18 +939,24 @@ func (o *Account) GetIDByName() (string, error) {
 		return "", fmt.Errorf("%s name must be provided", GetVarType(o))
 	}
 
-	_, err := o.getResourceID()
+	resourceID, err := o.getResourceID()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", err
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.User, resourceID); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("Error retrieving %s %s: %s", GetVarType(o), o.User, err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.User, resourceID, o.ID)
 
 	return o.ID, nil
 }
@@ -781,7 +966,7 @@ func (o *Account) GetByName() error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf("Failed to find ID of %s %s. %v", GetVarType(o), o.User, err)
 		}
 	}
@@ -794,11 +979,11 @@ func (o *Account) GetByName() error {
 }
 
 // DeleteByName deletes a DesktopApp by name
-func (o *Account) DeleteByName() (*restapi.BoolResponse, error) {
+func (o *Account) DeleteByName() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, fmt.Errorf("Failed to find ID of DesktopApp %s. %v", o.Name, err)
 		}
 	}
@@ -1477,3 +1662,70 @@ Add AWS Access Key
 		"InnerExceptions": null
 	}
 */
+
+// AccountFilter selects accounts returned by ListAccounts. Empty fields are ignored
+type AccountFilter struct {
+	ListOptions
+	User            string    // Substring of account name
+	Host            string    // Only accounts of this system
+	DatabaseID      string    // Only accounts of this database
+	DomainID        string    // Only accounts of this domain
+	CloudProviderID string    // Only accounts of this cloud provider
+	SetID           string    // Only accounts in this set
+	CheckedOutBy    string    // Only accounts whose password this user checked out, according to tenant events
+	CheckedOutSince time.Time // Only accounts checked out since this time. Defaults to any time if CheckedOutBy is set
+	All             bool      // Confirms that filter without other fields means every account. RotateAccounts requires it
+}
+
+// hasCriteria returns true if filter narrows down accounts
+func (f AccountFilter) hasCriteria() bool {
+	return f.User != "" || f.Host != "" || f.DatabaseID != "" || f.DomainID != "" || f.CloudProviderID != "" ||
+		f.SetID != "" || f.CheckedOutBy != "" || !f.CheckedOutSince.IsZero()
+}
+
+// AccountIterator iterates over accounts returned by ListAccounts
+type AccountIterator struct {
+	*Iterator
+}
+
+// Value returns current account. Only attributes returned by query are filled, call Read() for the rest
+func (it *AccountIterator) Value() *Account {
+	obj := NewAccount(it.client)
+	it.decode(obj)
+	return obj
+}
+
+// ListAccounts returns iterator over accounts that match filter. Pages are fetched as iteration goes
+func ListAccounts(c *restapi.RestClient, filter AccountFilter) *AccountIterator {
+	q, err := listQuery(c, "VaultAccount", filter.SetID)
+	if err != nil {
+		return &AccountIterator{errIterator(err)}
+	}
+	if filter.User != "" {
+		q.Where(query.Contains("User", filter.User))
+	}
+	if filter.Host != "" {
+		q.Where(query.Eq("Host", filter.Host))
+	}
+	if filter.DatabaseID != "" {
+		q.Where(query.Eq("DatabaseID", filter.DatabaseID))
+	}
+	if filter.DomainID != "" {
+		q.Where(query.Eq("DomainID", filter.DomainID))
+	}
+	if filter.CloudProviderID != "" {
+		q.Where(query.Eq("CloudProviderId", filter.CloudProviderID))
+	}
+	if filter.CheckedOutBy != "" || !filter.CheckedOutSince.IsZero() {
+		checkouts := query.Select("Event", "AccountID").Where(query.EndsWith("EventType", ".PasswordCheckout"))
+		if filter.CheckedOutBy != "" {
+			checkouts.Where(query.Eq("NormalizedUser", filter.CheckedOutBy))
+		}
+		if !filter.CheckedOutSince.IsZero() {
+			checkouts.Where(query.Ge("WhenOccurred", filter.CheckedOutSince))
+		}
+		q.Where(query.InQuery("ID", checkouts))
+	}
+
+	return &AccountIterator{newIterator(c, q, filter.ListOptions)}
+}
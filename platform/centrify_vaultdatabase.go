@@ -3,8 +3,9 @@ package platform
 import (
 	"fmt"
 
+	"github.com/marcozj/golang-sdk/enum/databaseclass"
 	"github.com/marcozj/golang-sdk/enum/settype"
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -53,6 +54,8 @@ func NewDatabase(c *restapi.RestClient) *Database {
 	s.apiGetChallenge = "/ServerManage/GetComputerChallenges"
 	s.apiAddToSets = "/Collection/UpdateMembersCollection"
 	s.apiPermissions = "/ServerManage/SetDatabasePermissions"
+	s.apiGetPermissions = "/Acl/GetRowAces"
+	s.aclTable = "VaultDatabase"
 	s.setTable = "VaultDatabase"
 
 	return &s
@@ -62,23 +65,28 @@ func NewDatabase(c *restapi.RestClient) *Database {
 func (o *Database) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
+	script, err := query.Select("VaultDatabase").Where(query.Eq("VaultDatabase.ID", o.ID)).Build()
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return err
+	}
 	var queryArg = make(map[string]interface{})
 	queryArg["ID"] = o.ID
-	queryArg["Script"] = "SELECT * FROM VaultDatabase WHERE VaultDatabase.ID = '" + o.ID + "'"
+	queryArg["Script"] = script
 	queryArg["Args"] = subArgs
 
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -86,7 +94,7 @@ func (o *Database) Read() error {
 	var results = resp.Result["Results"].([]interface{})
 	if len(results) < 1 {
 		// Make sure error message contains "not exist"
-		logger.Debugf("Returning Database does not exist in tenant")
+		o.client.Log().Debugf("Returning Database does not exist in tenant")
 		return fmt.Errorf("Database does not exist in tenant")
 	} else if len(results) > 1 {
 		// this should never happen
@@ -104,87 +112,106 @@ func (o *Database) Read() error {
 }
 
 // Create function creates a new Database and returns a map that contains creation result
-func (o *Database) Create() (*restapi.StringResponse, error) {
+func (o *Database) Create() (*restapi.BaseAPIResponse, error) {
 	var queryArg = make(map[string]interface{})
 	queryArg, err := generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallStringAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	// Assign ID after successful creation so that the same object can be used for subsequent update operation
 	o.ID = resp.Result
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Delete function deletes a Database and returns a map that contains deletion result
-func (o *Database) Delete() (*restapi.BoolResponse, error) {
+func (o *Database) Delete() (*restapi.BaseAPIResponse, error) {
 	return o.deleteObjectBoolAPI("")
 }
 
 // Update function updates an existing Database and returns a map that contains update result
-func (o *Database) Update() (*restapi.GenericMapResponse, error) {
+func (o *Database) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
 	var queryArg = make(map[string]interface{})
 	queryArg, err := generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
+}
+
+// Validate checks database attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *Database) Validate() error {
+	v := &validator{}
+	v.required("name", o.Name)
+	v.required("hostname", o.FQDN)
+	v.required("database_class", o.DatabaseClass)
+	v.oneOf("database_class", o.DatabaseClass, databaseclass.Values())
+	v.inRange("port", o.Port, 1, 65535)
+	if o.DatabaseClass == databaseclass.Oracle.String() {
+		v.required("service_name", o.ServiceName)
+	}
+	v.check(o.InstanceName == "" || o.DatabaseClass == databaseclass.SQLServer.String(), "instance_name", "is only applicable to SQLServer database")
+	v.passwordSettings(o.DefaultCheckoutTime, o.PasswordRotateDuration, o.MinimumPasswordAge, o.PasswordHistoryCleanUpDuration)
+
+	return v.err(o, o.Name)
 }
 
 // Query function returns a single database object in map format
 func (o *Database) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM VaultDatabase WHERE 1=1"
+	q := query.Select("VaultDatabase")
 	if o.Name != "" {
-		query += " AND Name='" + o.Name + "'"
+		q.Where(query.Eq("Name", o.Name))
 	}
 	if o.FQDN != "" {
-		query += " AND FQDN='" + o.FQDN + "'"
+		q.Where(query.Eq("FQDN", o.FQDN))
 	}
 	if o.DatabaseClass != "" {
-		query += " AND DatabaseClass='" + o.DatabaseClass + "'"
+		q.Where(query.Eq("DatabaseClass", o.DatabaseClass))
 	}
 	if o.InstanceName != "" {
-		query += " AND InstanceName='" + o.InstanceName + "'"
+		q.Where(query.Eq("InstanceName", o.InstanceName))
 	}
 	if o.ServiceName != "" {
-		query += " AND ServiceName='" + o.ServiceName + "'"
+		q.Where(query.Eq("ServiceName", o.ServiceName))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 // GetIDByName returns database ID by name
@@ -193,12 +220,18 @@ func (o *Database) GetIDByName() (string, error) {
 		return "", fmt.Errorf("Database name must be provided")
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("Error retrieving database: %s", err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, "", o.ID)
 
 	return o.ID, nil
 }
@@ -208,7 +241,7 @@ func (o *Database) GetByName() error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf("Failed to find ID of database %s. %v", o.Name, err)
 		}
 	}
@@ -221,11 +254,11 @@ func (o *Database) GetByName() error {
 }
 
 // DeleteByName deletes a database by name
-func (o *Database) DeleteByName() (*restapi.BoolResponse, error) {
+func (o *Database) DeleteByName() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, fmt.Errorf("Failed to find ID of database %s. %v", o.Name, err)
 		}
 	}
@@ -397,3 +430,43 @@ func (o *Database) DeleteByName() (*restapi.BoolResponse, error) {
 			"InnerExceptions": null
 		}
 */
+
+// DatabaseFilter selects databases returned by ListDatabases. Empty fields are ignored
+type DatabaseFilter struct {
+	ListOptions
+	Name          string // Substring of database name
+	FQDN          string
+	DatabaseClass string // SQLServer, Oracle, etc.
+	SetID         string // Only databases in this set
+}
+
+// DatabaseIterator iterates over databases returned by ListDatabases
+type DatabaseIterator struct {
+	*Iterator
+}
+
+// Value returns current database. Only attributes returned by query are filled, call Read() for the rest
+func (it *DatabaseIterator) Value() *Database {
+	obj := NewDatabase(it.client)
+	it.decode(obj)
+	return obj
+}
+
+// ListDatabases returns iterator over databases that match filter. Pages are fetched as iteration goes
+func ListDatabases(c *restapi.RestClient, filter DatabaseFilter) *DatabaseIterator {
+	q, err := listQuery(c, "VaultDatabase", filter.SetID)
+	if err != nil {
+		return &DatabaseIterator{errIterator(err)}
+	}
+	if filter.Name != "" {
+		q.Where(query.Contains("Name", filter.Name))
+	}
+	if filter.FQDN != "" {
+		q.Where(query.Eq("FQDN", filter.FQDN))
+	}
+	if filter.DatabaseClass != "" {
+		q.Where(query.Eq("DatabaseClass", filter.DatabaseClass))
+	}
+
+	return &DatabaseIterator{newIterator(c, q, filter.ListOptions)}
+}
@@ -4,7 +4,7 @@ import (
 	"fmt"
 
 	"github.com/marcozj/golang-sdk/enum/settype"
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -69,6 +69,8 @@ func NewDomain(c *restapi.RestClient) *Domain {
 	s.apiDelete = "/ServerManage/DeleteDomain"
 	s.apiUpdate = "/ServerManage/UpdateDomain"
 	s.apiPermissions = "/ServerManage/SetDomainPermissions"
+	s.apiGetPermissions = "/Acl/GetRowAces"
+	s.aclTable = "VaultDomain"
 	s.apiSetAdminAccount = "/ServerManage/SetAdministrativeAccounts"
 	s.apiCanDelete = "/ServerManage/CanDeleteDomain"
 
@@ -79,23 +81,28 @@ func NewDomain(c *restapi.RestClient) *Domain {
 func (o *Domain) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
+	script, err := query.Select("VaultDomain").Where(query.Eq("ID", o.ID)).Build()
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return err
+	}
 	var queryArg = make(map[string]interface{})
 	queryArg["ID"] = o.ID
-	queryArg["Script"] = "SELECT * FROM VaultDomain WHERE ID = '" + o.ID + "'"
+	queryArg["Script"] = script
 	queryArg["Args"] = subArgs
 
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -118,63 +125,64 @@ func (o *Domain) Read() error {
 }
 
 // Create function creates a new Domain and returns a map that contains creation result
-func (o *Domain) Create() (*restapi.StringResponse, error) {
+func (o *Domain) Create() (*restapi.BaseAPIResponse, error) {
 	var queryArg = make(map[string]interface{})
 	queryArg["Name"] = o.Name
 	queryArg["VerifyDomain"] = o.VerifyDomain
 
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallStringAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	// Assign ID after successful creation so that the same object can be used for subsequent update operation
 	o.ID = resp.Result
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Delete function deletes a Domain and returns a map that contains deletion result
-func (o *Domain) Delete() (*restapi.BoolResponse, error) {
+func (o *Domain) Delete() (*restapi.BaseAPIResponse, error) {
 	var queryArg = make(map[string]interface{})
 	queryArg["ID"] = o.ID
 	// Check if the domain can be deleted
 	resp, err := o.client.CallGenericMapAPI(o.apiCanDelete, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 	if resp.Result["can"].(bool) {
 		return o.deleteObjectBoolAPI("")
 	}
 
-	logger.Debugf("Domain cannot be deleted: %+v", resp.Result["why"])
+	o.client.Log().Debugf("Domain cannot be deleted: %+v", resp.Result["why"])
 	return nil, nil
 }
 
 // Update function updates an existing Domain and returns a map that contains update result
-func (o *Domain) Update() (*restapi.GenericMapResponse, error) {
+func (o *Domain) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
 	err := o.processZoneRoleWorkflow()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
@@ -184,29 +192,42 @@ func (o *Domain) Update() (*restapi.GenericMapResponse, error) {
 		return nil, err
 	}
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
+}
+
+// Validate checks domain attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *Domain) Validate() error {
+	v := &validator{}
+	v.required("name", o.Name)
+	v.passwordSettings(o.DefaultCheckoutTime, o.PasswordRotateDuration, o.MinimumPasswordAge, o.PasswordHistoryCleanUpDuration)
+	v.inRange("zone_joined_check_interval", o.RefreshZoneJoinedIntervalMinutes, 1, maxInt32)
+	v.inRange("zonerole_cleanup_interval", o.ZoneRoleCleanupIntervalHours, 1, maxInt32)
+	v.approvers("assigned_zonerole_approver", false, o.ZoneRoleWorkflowApproverList)
+
+	return v.err(o, o.Name)
 }
 
 // Query function returns a single Set object in map format
 func (o *Domain) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM VaultDomain WHERE 1=1"
+	q := query.Select("VaultDomain")
 	if o.Name != "" {
-		query += " AND Name='" + o.Name + "'"
+		q.Where(query.Eq("Name", o.Name))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 // SetAdminAccount sets domain administrative account
@@ -233,12 +254,12 @@ func (o *Domain) SetAdminAccount() error {
 
 	resp, err := o.client.CallGenericMapAPI(o.apiSetAdminAccount, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 	return nil
@@ -250,12 +271,18 @@ func (o *Domain) GetIDByName() (string, error) {
 		return "", fmt.Errorf("Domain name must be provided")
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("Error retrieving domain: %s", err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, "", o.ID)
 
 	return o.ID, nil
 }
@@ -265,31 +292,31 @@ func (o *Domain) GetByName() error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf("Failed to find ID of domain %s. %v", o.Name, err)
 		}
 	}
 
 	err := o.Read()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	return nil
 }
 
 // DeleteByName deletes a domain by name
-func (o *Domain) DeleteByName() (*restapi.BoolResponse, error) {
+func (o *Domain) DeleteByName() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, fmt.Errorf("Failed to find ID of domain %s. %v", o.Name, err)
 		}
 	}
 	resp, err := o.Delete()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
@@ -559,3 +586,35 @@ func (o *Domain) processZoneRoleWorkflow() error {
 		"InnerExceptions": null
 	}
 */
+
+// DomainFilter selects domains returned by ListDomains. Empty fields are ignored
+type DomainFilter struct {
+	ListOptions
+	Name  string // Substring of domain name
+	SetID string // Only domains in this set
+}
+
+// DomainIterator iterates over domains returned by ListDomains
+type DomainIterator struct {
+	*Iterator
+}
+
+// Value returns current domain. Only attributes returned by query are filled, call Read() for the rest
+func (it *DomainIterator) Value() *Domain {
+	obj := NewDomain(it.client)
+	it.decode(obj)
+	return obj
+}
+
+// ListDomains returns iterator over domains that match filter. Pages are fetched as iteration goes
+func ListDomains(c *restapi.RestClient, filter DomainFilter) *DomainIterator {
+	q, err := listQuery(c, "VaultDomain", filter.SetID)
+	if err != nil {
+		return &DomainIterator{errIterator(err)}
+	}
+	if filter.Name != "" {
+		q.Where(query.Contains("Name", filter.Name))
+	}
+
+	return &DomainIterator{newIterator(c, q, filter.ListOptions)}
+}
@@ -5,8 +5,10 @@ import (
 	"os/user"
 	"strings"
 
+	"github.com/marcozj/golang-sdk/audit"
+	"github.com/marcozj/golang-sdk/enum/secrettype"
 	"github.com/marcozj/golang-sdk/enum/settype"
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -49,6 +51,8 @@ func NewSecret(c *restapi.RestClient) *Secret {
 	s.apiRetrieveSecret = "/ServerManage/RetrieveSecretContents"
 	s.apiMoveSecret = "/ServerManage/MoveSecret"
 	s.apiPermissions = "/ServerManage/SetSecretPermissions"
+	s.apiGetPermissions = "/Acl/GetRowAces"
+	s.aclTable = "DataVault"
 	s.apiGetChallenge = "/ServerManage/GetSecretRightsAndChallenges"
 	s.apiRequestSecretDownloadUrl = "ServerManage/RequestSecretDownloadUrl"
 	s.apiDownloadSecretFileInChunks = "ServerManage/DownloadSecretFileInChunks"
@@ -60,7 +64,7 @@ func NewSecret(c *restapi.RestClient) *Secret {
 func (o *Secret) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -68,14 +72,14 @@ func (o *Secret) Read() error {
 
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
-	logger.Debugf("Response for Secret from tenant: %v", resp)
+	o.client.Log().Debugf("Response for Secret from tenant: %v", resp)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -84,12 +88,12 @@ func (o *Secret) Read() error {
 	// Get challenge profile information
 	resp, err = o.client.CallGenericMapAPI(o.apiGetChallenge, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 	if v, ok := resp.Result["DataVaultDefaultProfile"]; ok {
@@ -113,24 +117,24 @@ func (o *Secret) Read() error {
 }
 
 // Create function creates a new Secret and returns a map that contains creation result
-func (o *Secret) Create() (*restapi.StringResponse, error) {
+func (o *Secret) Create() (*restapi.BaseAPIResponse, error) {
 	// Resolve FolderID if only ParentPath is provided
 	err := o.resolveFolderdID()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	err = o.processWorkflow()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	var queryArg = make(map[string]interface{})
 	queryArg, err = generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	queryArg["updateChallenges"] = false
@@ -138,82 +142,84 @@ func (o *Secret) Create() (*restapi.StringResponse, error) {
 		queryArg["WorkflowSent"] = true
 	}
 
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
 
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallStringAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	// Assign ID after successful creation so that the same object can be used for subsequent update operation
 	o.ID = resp.Result
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Delete function deletes a Secret and returns a map that contains deletion result
-func (o *Secret) Delete() (*restapi.BoolResponse, error) {
+func (o *Secret) Delete() (*restapi.BaseAPIResponse, error) {
 	return o.deleteObjectBoolAPI("")
 }
 
 // Update function updates an existing Secret and returns a map that contains update result
-func (o *Secret) Update() (*restapi.GenericMapResponse, error) {
+func (o *Secret) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
 	// Resolve FolderID if only ParentPath is provided or NewParentPath is provided for moving into another folder
 	err := o.resolveFolderdID()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	err = o.processWorkflow()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	var queryArg = make(map[string]interface{})
 	queryArg, err = generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	queryArg["updateChallenges"] = true
 	// Need to always send this when workflow is turned on and off
 	queryArg["WorkflowSent"] = true
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
 
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // MoveSecret function moves an existing Secret to another folder
 func (o *Secret) MoveSecret() (*restapi.BoolResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -221,38 +227,56 @@ func (o *Secret) MoveSecret() (*restapi.BoolResponse, error) {
 	queryArg["targetFolderId"] = o.FolderID
 	//queryArg["updateChallenges"] = true
 
-	logger.Debugf("Generated Map for MoveFolder(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for MoveFolder(): %+v", queryArg)
 
 	resp, err := o.client.CallBoolAPI(o.apiMoveSecret, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	return resp, nil
 }
 
+// Validate checks secret attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *Secret) Validate() error {
+	v := &validator{}
+	v.required("secret_name", o.SecretName)
+	v.required("type", o.Type)
+	v.oneOf("type", o.Type, secrettype.Values())
+	if o.ID == "" && o.Type == secrettype.Text.String() {
+		v.required("secret_text", o.SecretText)
+	}
+	v.challengeRules("challenge_rule", o.ChallengeRules)
+	v.approvers("workflow_approver", o.WorkflowEnabled, o.WorkflowApprovers)
+	if o.WorkflowDefaultOptions != nil {
+		v.inRange("workflow_default_options.grant_minute", o.WorkflowDefaultOptions.GrantMin, 1, maxInt32)
+	}
+
+	return v.err(o, o.SecretName)
+}
+
 // Query function returns a single Secret object in map format
 func (o *Secret) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM DataVault WHERE 1=1"
+	q := query.Select("DataVault")
 	if o.SecretName != "" {
-		query += " AND SecretName='" + o.SecretName + "'"
+		q.Where(query.Eq("SecretName", o.SecretName))
 	}
 	// ParentPath should always be added
-	query += " AND ParentPath='" + o.ParentPath + "'"
+	q.Where(query.Eq("ParentPath", o.ParentPath))
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 func (o *Secret) checkoutSecret() (*restapi.GenericMapResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -261,12 +285,12 @@ func (o *Secret) checkoutSecret() (*restapi.GenericMapResponse, error) {
 
 	resp, err := o.client.CallGenericMapAPI(o.apiRetrieveSecret, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
@@ -274,13 +298,17 @@ func (o *Secret) checkoutSecret() (*restapi.GenericMapResponse, error) {
 }
 
 // CheckoutSecret checks out secret from vault
-func (o *Secret) CheckoutSecret() (string, error) {
+func (o *Secret) CheckoutSecret() (secret string, err error) {
+	defer func() {
+		recordAccess(o.client, audit.ActionCheckoutSecret, settype.Secret.String(), o.ID, o.SecretName, "", err)
+	}()
+
 	// To retrieve secret, we must know its ID
 	// In order to know ID, we must know SecretName + ParentPath
 	if o.ID == "" {
 		err := o.GetByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return "", fmt.Errorf("Failed to find secret %s. %v", o.SecretName, err)
 		}
 	}
@@ -292,12 +320,12 @@ func (o *Secret) CheckoutSecret() (string, error) {
 
 	resp, err := o.checkoutSecret()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("Error retrieving secret content for %s: %s", o.SecretName, err)
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return "", fmt.Errorf(errmsg)
 	}
 	if p, ok := resp.Result["SecretText"]; ok {
@@ -313,12 +341,18 @@ func (o *Secret) GetIDByName() (string, error) {
 		return "", fmt.Errorf("Secret name must be provided")
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.SecretName, o.ParentPath); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("Error retrieving secret: %s", err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.SecretName, o.ParentPath, o.ID)
 
 	return o.ID, nil
 }
@@ -328,7 +362,7 @@ func (o *Secret) GetByName() error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf("Failed to find ID of secret %s. %v", o.SecretName, err)
 		}
 	}
@@ -341,11 +375,11 @@ func (o *Secret) GetByName() error {
 }
 
 // DeleteByName deletes a Secret by name
-func (o *Secret) DeleteByName() (*restapi.BoolResponse, error) {
+func (o *Secret) DeleteByName() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, fmt.Errorf("Failed to find ID of secret %s. %v", o.SecretName, err)
 		}
 	}
@@ -383,11 +417,15 @@ func (o *Secret) resolveFolderdID() error {
 	return nil
 }
 
-func (o *Secret) DownloadSecretFile(saveToHome bool) (string, error) {
+func (o *Secret) DownloadSecretFile(saveToHome bool) (filename string, err error) {
+	defer func() {
+		recordAccess(o.client, audit.ActionDownloadSecret, settype.Secret.String(), o.ID, o.SecretName, "", err)
+	}()
+
 	if o.ID == "" {
 		err := o.GetByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return "", fmt.Errorf("Failed to find secret %s. %v", o.SecretName, err)
 		}
 	}
@@ -401,12 +439,12 @@ func (o *Secret) DownloadSecretFile(saveToHome bool) (string, error) {
 
 	resp, err := o.client.CallGenericMapAPI(o.apiRequestSecretDownloadUrl, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return "", fmt.Errorf(errmsg)
 	}
 
@@ -420,7 +458,7 @@ func (o *Secret) DownloadSecretFile(saveToHome bool) (string, error) {
 		savedfilepath = user.HomeDir + "/" + savedfilepath
 	}
 	var downloadArg = make(map[string]interface{})
-	o.client.DownloadFile(o.apiDownloadSecretFileInChunks+"?FilePath="+secretfilepath, downloadArg, savedfilepath)
+	err = o.client.DownloadFile(o.apiDownloadSecretFileInChunks+"?FilePath="+secretfilepath, downloadArg, savedfilepath)
 	if err != nil {
 		return "", err
 	}
@@ -433,7 +471,7 @@ func (o *Secret) CheckoutSecretAndFile(saveToHome bool) (string, error) {
 	if o.ID == "" {
 		err := o.GetByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return "", fmt.Errorf("Failed to find secret %s. %v", o.SecretName, err)
 		}
 	}
@@ -445,19 +483,7 @@ func (o *Secret) CheckoutSecretAndFile(saveToHome bool) (string, error) {
 
 	// if it is text secret, retrieve secret text
 	if o.Type == "Text" {
-		resp, err := o.checkoutSecret()
-		if err != nil {
-			logger.Errorf(err.Error())
-			return "", fmt.Errorf("Error retrieving secret content for %s: %s", o.SecretName, err)
-		}
-		if !resp.Success {
-			errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-			logger.Errorf(errmsg)
-			return "", fmt.Errorf(errmsg)
-		}
-		if p, ok := resp.Result["SecretText"]; ok {
-			return p.(string), nil
-		}
+		return o.CheckoutSecret()
 	} else if o.Type == "File" {
 		filename, err := o.DownloadSecretFile(saveToHome)
 		if err != nil {
@@ -703,3 +729,43 @@ func (o *Secret) processWorkflow() error {
 		"InnerExceptions": null
 	}
 */
+
+// SecretFilter selects secrets returned by ListSecrets. Empty fields are ignored
+type SecretFilter struct {
+	ListOptions
+	SecretName string // Substring of secret name
+	ParentPath string // Only secrets directly in this folder path
+	Type       string // Text or File
+	SetID      string // Only secrets in this set
+}
+
+// SecretIterator iterates over secrets returned by ListSecrets
+type SecretIterator struct {
+	*Iterator
+}
+
+// Value returns current secret. Only attributes returned by query are filled, call Read() for the rest
+func (it *SecretIterator) Value() *Secret {
+	obj := NewSecret(it.client)
+	it.decode(obj)
+	return obj
+}
+
+// ListSecrets returns iterator over secrets that match filter. Pages are fetched as iteration goes
+func ListSecrets(c *restapi.RestClient, filter SecretFilter) *SecretIterator {
+	q, err := listQuery(c, "DataVault", filter.SetID)
+	if err != nil {
+		return &SecretIterator{errIterator(err)}
+	}
+	if filter.SecretName != "" {
+		q.Where(query.Contains("SecretName", filter.SecretName))
+	}
+	if filter.ParentPath != "" {
+		q.Where(query.Eq("ParentPath", filter.ParentPath))
+	}
+	if filter.Type != "" {
+		q.Where(query.Eq("Type", filter.Type))
+	}
+
+	return &SecretIterator{newIterator(c, q, filter.ListOptions)}
+}
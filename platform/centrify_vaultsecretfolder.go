@@ -5,7 +5,7 @@ import (
 	"strings"
 
 	"github.com/marcozj/golang-sdk/enum/settype"
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -41,6 +41,8 @@ func NewSecretFolder(c *restapi.RestClient) *SecretFolder {
 	s.apiGetChallenge = "/ServerManage/GetSecretsFolderRightsAndChallenges"
 	s.apiMoveFolder = "/ServerManage/MoveFolder"
 	s.apiPermissions = "/ServerManage/SetSecretsFolderPermissions"
+	s.apiGetPermissions = "/Acl/GetRowAces"
+	s.aclTable = "Sets"
 	s.apiMemberPermissions = "/ServerManage/SetSecretCollectionPermissions"
 	s.Type = "Folder"
 
@@ -51,7 +53,7 @@ func NewSecretFolder(c *restapi.RestClient) *SecretFolder {
 func (o *SecretFolder) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -60,12 +62,12 @@ func (o *SecretFolder) Read() error {
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -73,7 +75,7 @@ func (o *SecretFolder) Read() error {
 	var results = resp.Result["Results"].([]interface{})
 	if len(results) < 1 {
 		// Make sure error message contains "not exist"
-		logger.Debugf("Returning SecretFolder does not exist in tenant")
+		o.client.Log().Debugf("Returning SecretFolder does not exist in tenant")
 		return fmt.Errorf("SecretFolder does not exist in tenant")
 	} else if len(results) > 1 {
 		// this should never happen
@@ -88,12 +90,12 @@ func (o *SecretFolder) Read() error {
 	// Get challenge profile
 	resp, err = o.client.CallGenericMapAPI(o.apiGetChallenge, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 	//logger.Debugf("Challenges result: %+v", resp)
@@ -114,11 +116,11 @@ func (o *SecretFolder) Read() error {
 }
 
 // Create function creates a new SecretFolder and returns a map that contains creation result
-func (o *SecretFolder) Create() (*restapi.StringResponse, error) {
+func (o *SecretFolder) Create() (*restapi.BaseAPIResponse, error) {
 	// Resolve ParentID if only ParentPath is provided
 	err := o.resolveParentID()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
@@ -129,82 +131,84 @@ func (o *SecretFolder) Create() (*restapi.StringResponse, error) {
 	}
 	queryArg["updateChallenges"] = false
 
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
 
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallStringAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	// Assign ID after successful creation so that the same object can be used for subsequent update operation
 	o.ID = resp.Result
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Delete function deletes a SecretFolder and returns a map that contains deletion result
-func (o *SecretFolder) Delete() (*restapi.BoolResponse, error) {
+func (o *SecretFolder) Delete() (*restapi.BaseAPIResponse, error) {
 	return o.deleteObjectBoolAPI("")
 }
 
 // Update function updates an existing SecretFolder and returns a map that contains update result
-func (o *SecretFolder) Update() (*restapi.GenericMapResponse, error) {
+func (o *SecretFolder) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
 	// Resolve ParentID if only ParentPath is provided or NewParentPath is provided for moving into another folder
 	err := o.resolveParentID()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	var queryArg = make(map[string]interface{})
 	queryArg, err = generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	queryArg["updateChallenges"] = true
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
 
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // MoveFolder function moves an existing SecretFolder to another folder
 func (o *SecretFolder) MoveFolder() (*restapi.BoolResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
 	// Resolve ParentID if only ParentPath is provided or NewParentPath is provided for moving into another folder
 	err := o.resolveParentID()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
@@ -213,41 +217,52 @@ func (o *SecretFolder) MoveFolder() (*restapi.BoolResponse, error) {
 	queryArg["targetFolderId"] = o.ParentID
 	//queryArg["updateChallenges"] = true
 
-	logger.Debugf("Generated Map for MoveFolder(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for MoveFolder(): %+v", queryArg)
 
 	resp, err := o.client.CallBoolAPI(o.apiMoveFolder, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	return resp, nil
 }
 
+// Validate checks secret folder attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *SecretFolder) Validate() error {
+	v := &validator{}
+	v.required("name", o.Name)
+	v.oneOf("type", o.Type, []string{"Folder"})
+	v.check(!strings.Contains(o.Name, "\\"), "name", "can't contain '\\'")
+	v.challengeRules("challenge_rule", o.ChallengeRules)
+
+	return v.err(o, o.Name)
+}
+
 // Query function returns a single SecretFolder object in map format
 func (o *SecretFolder) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM Sets WHERE 1=1 AND ObjectType='DataVault' AND CollectionType='Phantom'"
+	q := query.Select("Sets").Where(query.Eq("ObjectType", "DataVault"), query.Eq("CollectionType", "Phantom"))
 	if o.Name != "" {
-		query += " AND Name='" + o.Name + "'"
+		q.Where(query.Eq("Name", o.Name))
 	}
 	if o.ParentPath != "" {
-		query += " AND ParentPath='" + o.ParentPath + "'"
+		q.Where(query.Eq("ParentPath", o.ParentPath))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 // SetMemberPermissions sets member permissions. isRemove indicates whether to remove all permissions instead of setting permissions
 func (o *SecretFolder) SetMemberPermissions(isRemove bool) (*restapi.GenericMapResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
@@ -269,15 +284,15 @@ func (o *SecretFolder) SetMemberPermissions(isRemove bool) (*restapi.GenericMapR
 		queryArg["ID"] = o.ID
 		queryArg["PVID"] = o.ID
 		queryArg["Grants"] = permissions
-		logger.Debugf("Generated Map for SetMemberPermissions(): %+v", queryArg)
+		o.client.Log().Debugf("Generated Map for SetMemberPermissions(): %+v", queryArg)
 		resp, err := o.client.CallGenericMapAPI(o.apiMemberPermissions, queryArg)
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, err
 		}
 		if !resp.Success {
 			errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-			logger.Errorf(errmsg)
+			o.client.Log().Errorf(errmsg)
 			return nil, fmt.Errorf(errmsg)
 		}
 		return resp, nil
@@ -291,13 +306,19 @@ func (o *SecretFolder) GetIDByName() (string, error) {
 		return "", fmt.Errorf("Secret folder name must be provided")
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, o.ParentPath); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
 		errormsg := fmt.Sprintf("Failed to retrieve secret folder '%s' in '%s'. %v", o.Name, o.ParentPath, err)
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return "", fmt.Errorf(errormsg)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, o.ParentPath, o.ID)
 
 	return o.ID, nil
 }
@@ -307,7 +328,7 @@ func (o *SecretFolder) GetByName() error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf(err.Error())
 		}
 	}
@@ -320,11 +341,11 @@ func (o *SecretFolder) GetByName() error {
 }
 
 // DeleteByName deletes a Secret folder by name
-func (o *SecretFolder) DeleteByName() (*restapi.BoolResponse, error) {
+func (o *SecretFolder) DeleteByName() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, fmt.Errorf(err.Error())
 		}
 	}
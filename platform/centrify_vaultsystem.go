@@ -2,10 +2,12 @@ package platform
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/marcozj/golang-sdk/enum/computerclass"
+	"github.com/marcozj/golang-sdk/enum/managementmode"
 	"github.com/marcozj/golang-sdk/enum/settype"
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -112,6 +114,8 @@ func NewSystem(c *restapi.RestClient) *System {
 	s.apiGetPrivilegeElevationChallenge = "/PrivilegeElevation/GetChallenges"
 	s.apiAddToSets = "/Collection/UpdateMembersCollection"
 	s.apiPermissions = "/ServerManage/SetResourcePermissions"
+	s.apiGetPermissions = "/Acl/GetRowAces"
+	s.aclTable = "Server"
 	s.apiGetAgentAuthWorkflowConfig = "/ServerManage/GetAgentAuthWorkflowConfig"
 	s.apiGetPrivilegeElevationWorkflowConfig = "/ServerManage/GetPrivilegeElevationWorkflowConfig"
 	//s.setTable = "Server"
@@ -123,23 +127,28 @@ func NewSystem(c *restapi.RestClient) *System {
 func (o *System) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
+	script, err := query.Select("Server").Where(query.Eq("Server.ID", o.ID)).Build()
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return err
+	}
 	var queryArg = make(map[string]interface{})
 	queryArg["ID"] = o.ID
-	queryArg["Script"] = "SELECT * FROM Server WHERE Server.ID = '" + o.ID + "'"
+	queryArg["Script"] = script
 	queryArg["Args"] = subArgs
 
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -160,7 +169,7 @@ func (o *System) Read() error {
 	// Get system login profile information
 	resp, err = o.client.CallGenericMapAPI(o.apiGetChallenge, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
@@ -181,7 +190,7 @@ func (o *System) Read() error {
 	// Get Privilege Elevation Challenge profile information
 	resp, err = o.client.CallGenericMapAPI(o.apiGetPrivilegeElevationChallenge, args)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
@@ -200,7 +209,7 @@ func (o *System) Read() error {
 	// Get AgentAuth workflow approvers
 	resp, err = o.client.CallGenericMapAPI(o.apiGetAgentAuthWorkflowConfig, args)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
@@ -215,7 +224,7 @@ func (o *System) Read() error {
 	// Get privilege elevation workflow approvers
 	resp, err = o.client.CallGenericMapAPI(o.apiGetPrivilegeElevationWorkflowConfig, args)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	if !resp.Success {
@@ -231,93 +240,95 @@ func (o *System) Read() error {
 }
 
 // Create function creates a new system
-func (o *System) Create() (*restapi.StringResponse, error) {
+func (o *System) Create() (*restapi.BaseAPIResponse, error) {
 	err := o.processWorkflow()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	err = o.processZoneRoleWorkflow()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	var queryArg = make(map[string]interface{})
 	queryArg, err = generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	// Special handling of system login profile
 	queryArg["updateChallenges"] = true
 
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallStringAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	// Assign ID after successful creation so that the same object can be used for subsequent update operation
 	o.ID = resp.Result
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Delete function deletes a system and returns a map that contains deletion result
-func (o *System) Delete() (*restapi.BoolResponse, error) {
+func (o *System) Delete() (*restapi.BaseAPIResponse, error) {
 	return o.deleteObjectBoolAPI("")
 }
 
 // Update function updates an existing system and returns a map that contains update result
-func (o *System) Update() (*restapi.GenericMapResponse, error) {
+func (o *System) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
 	err := o.processWorkflow()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	err = o.processZoneRoleWorkflow()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	var queryArg = make(map[string]interface{})
 	queryArg, err = generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	// Special handling of system login profile
 	queryArg["updateChallenges"] = true
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // ValidateZoneWorkflow checks if domain_id is set if use_domainadmin_for_zonerole_workflow is true
@@ -329,20 +340,58 @@ func (o *System) ValidateZoneWorkflow() error {
 	return nil
 }
 
+// Validate checks system attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *System) Validate() error {
+	v := &validator{}
+	v.required("name", o.Name)
+	v.required("fqdn", o.FQDN)
+	v.required("computer_class", o.ComputerClass)
+	v.oneOf("computer_class", o.ComputerClass, computerclass.Values())
+	v.oneOf("session_type", o.SessionType, []string{"Rdp", "Ssh"})
+	// Tenant reports Unknown for systems whose management mode isn't set
+	if o.ManagementMode != "" && !strings.EqualFold(o.ManagementMode, managementmode.Unknown.String()) {
+		v.check(o.isComputerClass(computerclass.Windows), "management_mode", "is only applicable to Windows system")
+		v.oneOf("management_mode", o.ManagementMode, managementmode.Values())
+	}
+	if o.ManagementPort != 0 {
+		if o.isComputerClass(computerclass.Windows, computerclass.F5BIGIP, computerclass.PaloAltoPANOS, computerclass.VMwareVMkernel) {
+			v.inRange("management_port", o.ManagementPort, 1, 65535)
+		} else {
+			v.add("management_port", "is only applicable to Windows, F5 BIG-IP, PAN-OS and VMkernel system")
+		}
+	}
+	v.inRange("port", o.Port, 1, 65535)
+	v.passwordSettings(o.DefaultCheckoutTime, o.PasswordRotateDuration, o.MinimumPasswordAge, o.PasswordHistoryCleanUpDuration)
+	v.inRange("sshkey_rotate_interval", o.SSHKeysRotateDuration, 1, maxInt32)
+	v.inRange("minimum_sshkey_age", o.MinimumSSHKeysAge, 0, maxInt32)
+	v.inRange("sshkey_historycleanup_duration", o.SSHKeysCleanUpDuration, 90, maxInt32)
+	if err := o.ValidateZoneWorkflow(); err != nil {
+		v.add("domain_id", "%v", err)
+	}
+	v.check(!o.ProxyUserIsManaged || o.ProxyUser != "", "proxyuser", "is required when proxyuser_managed is enabled")
+	v.challengeRules("challenge_rule", o.ChallengeRules)
+	v.challengeRules("privilege_elevation_rule", o.PrivilegeElevationRules)
+	v.approvers("agent_auth_workflow_approver", o.AgentAuthWorkflowEnabled, o.AgentAuthWorkflowApprovers)
+	v.approvers("privilege_elevation_workflow_approver", o.PrivilegeElevationWorkflowEnabled, o.PrivilegeElevationWorkflowApprovers)
+	v.approvers("assigned_zonerole_approver", false, o.ZoneRoleWorkflowApproverList)
+
+	return v.err(o, o.Name)
+}
+
 // Query function returns a single System object in map format
 func (o *System) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM Server WHERE 1=1"
+	q := query.Select("Server")
 	if o.Name != "" {
-		query += " AND Name='" + o.Name + "'"
+		q.Where(query.Eq("Name", o.Name))
 	}
 	if o.FQDN != "" {
-		query += " AND FQDN='" + o.FQDN + "'"
+		q.Where(query.Eq("FQDN", o.FQDN))
 	}
 	if o.ComputerClass != "" {
-		query += " AND ComputerClass='" + o.ComputerClass + "'"
+		q.Where(query.Eq("ComputerClass", o.ComputerClass))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 // GetIDByName returns system ID by name
@@ -357,13 +406,19 @@ func (o *System) GetIDByName() (string, error) {
 	//	return "", fmt.Errorf("FQDN must be provided")
 	//}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, o.ComputerClass); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
 		errormsg := fmt.Sprintf("Failed to retrieve system '%s' with type '%s'. %s", o.Name, o.ComputerClass, err)
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return "", fmt.Errorf(errormsg)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, o.ComputerClass, o.ID)
 
 	return o.ID, nil
 }
@@ -373,40 +428,50 @@ func (o *System) GetByName() error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf(err.Error())
 		}
 	}
 
 	err := o.Read()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	return nil
 }
 
 // DeleteByName deletes a system by name
-func (o *System) DeleteByName() (*restapi.BoolResponse, error) {
+func (o *System) DeleteByName() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, fmt.Errorf(err.Error())
 		}
 	}
 	resp, err := o.Delete()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	return resp, nil
 }
 
+// isComputerClass returns true if computer class of system is one of classes regardless of case
+func (o *System) isComputerClass(classes ...computerclass.ComputerClass) bool {
+	for _, c := range classes {
+		if strings.EqualFold(o.ComputerClass, c.String()) {
+			return true
+		}
+	}
+	return false
+}
+
 // ResolveValidPermissions assign valid permissions according to computer class
 func (o *System) ResolveValidPermissions() {
-	if o.ComputerClass == computerclass.Windows.String() || o.ComputerClass == computerclass.Unix.String() {
+	if o.isComputerClass(computerclass.Windows, computerclass.Unix) {
 		o.ValidPermissions = ValidPermissionMap.WinNix
 	} else {
 		o.ValidPermissions = ValidPermissionMap.System
@@ -751,3 +816,47 @@ func (o *System) processZoneRoleWorkflow() error {
 			"InnerExceptions": null
 		}
 */
+
+// SystemFilter selects systems returned by ListSystems. Empty fields are ignored
+type SystemFilter struct {
+	ListOptions
+	Name          string // Substring of system name
+	FQDN          string
+	ComputerClass string // Windows, Unix, etc.
+	DomainID      string // Only systems joined to this domain
+	SetID         string // Only systems in this set
+}
+
+// SystemIterator iterates over systems returned by ListSystems
+type SystemIterator struct {
+	*Iterator
+}
+
+// Value returns current system. Only attributes returned by query are filled, call Read() for the rest
+func (it *SystemIterator) Value() *System {
+	obj := NewSystem(it.client)
+	it.decode(obj)
+	return obj
+}
+
+// ListSystems returns iterator over systems that match filter. Pages are fetched as iteration goes
+func ListSystems(c *restapi.RestClient, filter SystemFilter) *SystemIterator {
+	q, err := listQuery(c, "Server", filter.SetID)
+	if err != nil {
+		return &SystemIterator{errIterator(err)}
+	}
+	if filter.Name != "" {
+		q.Where(query.Contains("Name", filter.Name))
+	}
+	if filter.FQDN != "" {
+		q.Where(query.Eq("FQDN", filter.FQDN))
+	}
+	if filter.ComputerClass != "" {
+		q.Where(query.Eq("ComputerClass", filter.ComputerClass))
+	}
+	if filter.DomainID != "" {
+		q.Where(query.Eq("DomainId", filter.DomainID))
+	}
+
+	return &SystemIterator{newIterator(c, q, filter.ListOptions)}
+}
@@ -4,7 +4,8 @@ import (
 	"fmt"
 
 	"github.com/marcozj/golang-sdk/enum/settype"
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/enum/webapp/accountmapping"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -38,6 +39,8 @@ func newWebpp(c *restapi.RestClient) *WebApp {
 	s.apiDelete = "/SaasManage/DeleteApplication"
 	s.apiUpdate = "/SaasManage/UpdateApplicationDE"
 	s.apiPermissions = "/SaasManage/SetApplicationPermissions"
+	s.apiGetPermissions = "/Acl/GetRowAces"
+	s.aclTable = "Application"
 	s.apiResetAppScript = "/SaasManage/ResetAppScript"
 
 	return &s
@@ -74,31 +77,32 @@ func (o *WebApp) Read() error {
 }
 */
 // Create function creates a new WebApp and returns a map that contains creation result
-func (o *WebApp) Create() (*restapi.SliceResponse, error) {
+func (o *WebApp) Create() (*restapi.BaseAPIResponse, error) {
 	var queryArg = make(map[string]interface{})
 	queryArg["ID"] = []string{o.TemplateName}
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
 
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallSliceAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
 	// Assign ID after successful creation so that the same object can be used for subsequent update operation
 	o.ID = resp.Result[0].(map[string]interface{})["_RowKey"].(string)
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 /*
 // Update function updates an existing WebApp and returns a map that contains update result
-func (o *WebApp) Update() (*restapi.GenericMapResponse, error) {
+func (o *WebApp) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
 		logger.Errorf(errormsg)
@@ -127,6 +131,7 @@ func (o *WebApp) Update() (*restapi.GenericMapResponse, error) {
 
 	logger.Debugf("Generated Map for Update(): %+v", queryArg)
 
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
 		logger.Errorf(err.Error())
@@ -142,10 +147,10 @@ func (o *WebApp) Update() (*restapi.GenericMapResponse, error) {
 }
 */
 // Delete function deletes a WebApp and returns a map that contains deletion result
-func (o *WebApp) Delete() (*restapi.SliceResponse, error) {
+func (o *WebApp) Delete() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -153,16 +158,18 @@ func (o *WebApp) Delete() (*restapi.SliceResponse, error) {
 
 	resp, err := o.client.CallSliceAPI(o.apiDelete, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
-	return resp, nil
+	o.resolver().InvalidateID(o.ID)
+
+	return resp.ToBase(), nil
 }
 
 /*
@@ -182,14 +189,37 @@ func (o *WebApp) processWorkflow() error {
 }
 
 */
+// Validate checks web app attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *WebApp) Validate() error {
+	v := &validator{}
+	o.validate(v)
+
+	return v.err(o, o.Name)
+}
+
+// validate adds violations of attributes shared by every type of web app
+func (o *WebApp) validate(v *validator) {
+	v.required("name", o.Name)
+	v.required("template_name", o.TemplateName)
+	v.oneOf("username_strategy", o.UserNameStrategy, accountmapping.Values())
+	switch o.UserNameStrategy {
+	case accountmapping.ADAttribute.String(), accountmapping.SharedAccount.String():
+		v.check(o.Username != "", "username", "is required when username_strategy is %s", o.UserNameStrategy)
+	case accountmapping.UseScript.String():
+		v.check(o.UserMapScript != "", "user_map_script", "is required when username_strategy is UseScript")
+	}
+	v.challengeRules("challenge_rule", o.ChallengeRules)
+	v.approvers("workflow_approver", o.WorkflowEnabled && o.WorkflowSettings == "", o.WorkflowApproverList)
+}
+
 // Query function returns a single WebApp object in map format
 func (o *WebApp) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM Application WHERE 1=1 AND AppType='Web'"
+	q := query.Select("Application").Where(query.Eq("AppType", "Web"))
 	if o.Name != "" {
-		query += " AND Name='" + o.Name + "'"
+		q.Where(query.Eq("Name", o.Name))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 // GetIDByName returns vault object ID by name
@@ -198,12 +228,18 @@ func (o *WebApp) GetIDByName() (string, error) {
 		return "", fmt.Errorf("%s name must be provided", GetVarType(o))
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("error retrieving %s: %s", GetVarType(o), err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, "", o.ID)
 
 	return o.ID, nil
 }
@@ -227,11 +263,11 @@ func (o *WebApp) GetByName() error {
 }
 */
 // DeleteByName deletes a DesktopApp by name
-func (o *WebApp) DeleteByName() (*restapi.SliceResponse, error) {
+func (o *WebApp) DeleteByName() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return nil, fmt.Errorf("failed to find ID of WebApp %s. %v", o.Name, err)
 		}
 	}
@@ -246,7 +282,7 @@ func (o *WebApp) DeleteByName() (*restapi.SliceResponse, error) {
 func (o *WebApp) ResetAppScript() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
@@ -256,7 +292,7 @@ func (o *WebApp) ResetAppScript() error {
 	_, err := o.client.CallGenericMapAPI(o.apiResetAppScript, queryArg)
 
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 	return nil
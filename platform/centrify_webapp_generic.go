@@ -3,7 +3,7 @@ package platform
 import (
 	"fmt"
 
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -40,24 +40,24 @@ func NewGenericWebApp(c *restapi.RestClient) *GenericWebApp {
 func (o *GenericWebApp) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
 	queryArg["_RowKey"] = o.ID
 
-	logger.Debugf("Generated Map for Read(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Read(): %+v", queryArg)
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -67,19 +67,20 @@ func (o *GenericWebApp) Read() error {
 }
 
 // Create function creates a new WebApp and returns a map that contains creation result
-func (o *GenericWebApp) Create() (*restapi.SliceResponse, error) {
+func (o *GenericWebApp) Create() (*restapi.BaseAPIResponse, error) {
 	var queryArg = make(map[string]interface{})
 	queryArg["ID"] = []string{o.TemplateName}
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
 
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallSliceAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
@@ -91,25 +92,25 @@ func (o *GenericWebApp) Create() (*restapi.SliceResponse, error) {
 	obj.ID = o.ID
 	obj.Read()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Create function creates a new WebApp and returns a map that contains creation result
 func (o *GenericWebApp) CreateComplete() error {
 	_, err := o.Create()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
 	// Perform update
 	_, err = o.Update()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
@@ -117,7 +118,7 @@ func (o *GenericWebApp) CreateComplete() error {
 	if len(o.Sets) > 0 {
 		err := o.AddToSetsByName(o.Sets)
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return err
 		}
 	}
@@ -126,12 +127,12 @@ func (o *GenericWebApp) CreateComplete() error {
 	if len(o.Permissions) > 0 {
 		err := ResolvePermissions2(o.client, o.Permissions, o.ValidPermissions)
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return err
 		}
 		_, err = o.SetPermissions(false)
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return err
 		}
 	}
@@ -140,41 +141,42 @@ func (o *GenericWebApp) CreateComplete() error {
 }
 
 // Update function updates an existing WebApp and returns a map that contains update result
-func (o *GenericWebApp) Update() (*restapi.GenericMapResponse, error) {
+func (o *GenericWebApp) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
 	err := o.processWorkflow()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	var queryArg = make(map[string]interface{})
 	queryArg, err = generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	queryArg["_RowKey"] = o.ID
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
 
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 func (o *GenericWebApp) processWorkflow() error {
@@ -198,12 +200,18 @@ func (o *GenericWebApp) GetIDByName() (string, error) {
 		return "", fmt.Errorf("%s name must be provided", GetVarType(o))
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("error retrieving %s: %s", GetVarType(o), err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, "", o.ID)
 
 	return o.ID, nil
 }
@@ -213,7 +221,7 @@ func (o *GenericWebApp) GetByName() error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf("failed to find ID of %s %s. %v", GetVarType(o), o.Name, err)
 		}
 	}
@@ -225,12 +233,22 @@ func (o *GenericWebApp) GetByName() error {
 	return nil
 }
 
+// Validate checks generic web app attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *GenericWebApp) Validate() error {
+	v := &validator{}
+	o.WebApp.validate(v)
+	v.required("url", o.Url)
+	v.check(o.CorpIdField == "" || o.CorpIdentifier != "", "additional_login_field_value", "is required when additional_login_field is set")
+
+	return v.err(o, o.Name)
+}
+
 // Query function returns a single WebApp object in map format
 func (o *GenericWebApp) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM Application WHERE 1=1 AND AppType='Web' AND WebAppType='UsernamePassword'"
+	q := query.Select("Application").Where(query.Eq("AppType", "Web"), query.Eq("WebAppType", "UsernamePassword"))
 	if o.Name != "" {
-		query += " AND Name='" + o.Name + "'"
+		q.Where(query.Eq("Name", o.Name))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
@@ -2,9 +2,11 @@ package platform
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/marcozj/golang-sdk/enum/webapp/oauth/applicationtemplate"
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/enum/webapp/oauth/tokentype"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -58,24 +60,24 @@ func NewOauthWebApp(c *restapi.RestClient) *OauthWebApp {
 func (o *OauthWebApp) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
 	queryArg["_RowKey"] = o.ID
 
-	logger.Debugf("Generated Map for Read(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Read(): %+v", queryArg)
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -88,10 +90,10 @@ func (o *OauthWebApp) Read() error {
 }
 
 // Update function updates an existing WebApp and returns a map that contains update result
-func (o *OauthWebApp) Update() (*restapi.GenericMapResponse, error) {
+func (o *OauthWebApp) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
@@ -100,25 +102,26 @@ func (o *OauthWebApp) Update() (*restapi.GenericMapResponse, error) {
 	var queryArg = make(map[string]interface{})
 	queryArg, err := generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	queryArg["_RowKey"] = o.ID
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
 
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // GetIDByName returns vault object ID by name
@@ -127,12 +130,18 @@ func (o *OauthWebApp) GetIDByName() (string, error) {
 		return "", fmt.Errorf("%s name must be provided", GetVarType(o))
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("error retrieving %s: %s", GetVarType(o), err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, "", o.ID)
 
 	return o.ID, nil
 }
@@ -142,7 +151,7 @@ func (o *OauthWebApp) GetByName() error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf("failed to find ID of %s %s. %v", GetVarType(o), o.Name, err)
 		}
 	}
@@ -154,17 +163,38 @@ func (o *OauthWebApp) GetByName() error {
 	return nil
 }
 
+// Validate checks OAuth web app attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *OauthWebApp) Validate() error {
+	v := &validator{}
+	o.WebApp.validate(v)
+	if o.ID == "" {
+		v.required("application_id", o.ApplicationID)
+	}
+	if p := o.OAuthProfile; p != nil {
+		v.inRange("oauth_profile.clientid_type", p.ClientIDType, 0, 1)
+		v.oneOf("oauth_profile.token_type", p.TokenType, tokentype.Values())
+		for _, auth := range strings.Split(p.AllowedAuth, ",") {
+			v.oneOf("oauth_profile.allowed_auth", auth, []string{"AuthorizationCode", "Implicit", "ClientCreds", "ResourceCreds"})
+		}
+		for i, s := range p.KnownScopes {
+			v.required(fmt.Sprintf("oauth_profile.scope[%d].name", i), s.Name)
+		}
+	}
+
+	return v.err(o, o.Name)
+}
+
 // Query function returns a single WebApp object in map format
 func (o *OauthWebApp) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM Application WHERE 1=1 AND AppType='Web' AND WebAppType='OAuth'"
+	q := query.Select("Application").Where(query.Eq("AppType", "Web"), query.Eq("WebAppType", "OAuth"))
 	if o.Name != "" {
-		query += " AND Name='" + o.Name + "'"
+		q.Where(query.Eq("Name", o.Name))
 	}
 	if o.ApplicationID != "" {
-		query += " AND ServiceName='" + o.ApplicationID + "'"
+		q.Where(query.Eq("ServiceName", o.ApplicationID))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 func (o *OauthWebApp) processOauthProfile() {
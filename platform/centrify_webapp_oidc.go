@@ -3,7 +3,7 @@ package platform
 import (
 	"fmt"
 
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -45,24 +45,24 @@ func NewOidcWebApp(c *restapi.RestClient) *OidcWebApp {
 func (o *OidcWebApp) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
 	queryArg["_RowKey"] = o.ID
 
-	logger.Debugf("Generated Map for Read(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Read(): %+v", queryArg)
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -75,19 +75,20 @@ func (o *OidcWebApp) Read() error {
 }
 
 // Create function creates a new WebApp and returns a map that contains creation result
-func (o *OidcWebApp) Create() (*restapi.SliceResponse, error) {
+func (o *OidcWebApp) Create() (*restapi.BaseAPIResponse, error) {
 	var queryArg = make(map[string]interface{})
 	queryArg["ID"] = []string{o.TemplateName}
-	logger.Debugf("Generated Map for Create(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Create(): %+v", queryArg)
 
+	o.resolver().Invalidate(GetVarType(o), o.GetName())
 	resp, err := o.client.CallSliceAPI(o.apiCreate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
@@ -99,26 +100,26 @@ func (o *OidcWebApp) Create() (*restapi.SliceResponse, error) {
 	obj.ID = o.ID
 	obj.Read()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	o.OAuthProfile.ClientID = obj.OAuthProfile.ClientID
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 // Create function creates a new WebApp and returns a map that contains creation result
 func (o *OidcWebApp) CreateComplete() error {
 	_, err := o.Create()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
 	// Perform update
 	_, err = o.Update()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
@@ -126,7 +127,7 @@ func (o *OidcWebApp) CreateComplete() error {
 	if len(o.Sets) > 0 {
 		err := o.AddToSetsByName(o.Sets)
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return err
 		}
 	}
@@ -135,12 +136,12 @@ func (o *OidcWebApp) CreateComplete() error {
 	if len(o.Permissions) > 0 {
 		err := ResolvePermissions2(o.client, o.Permissions, o.ValidPermissions)
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return err
 		}
 		_, err = o.SetPermissions(false)
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return err
 		}
 	}
@@ -149,41 +150,42 @@ func (o *OidcWebApp) CreateComplete() error {
 }
 
 // Update function updates an existing WebApp and returns a map that contains update result
-func (o *OidcWebApp) Update() (*restapi.GenericMapResponse, error) {
+func (o *OidcWebApp) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
 	err := o.processWorkflow()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	var queryArg = make(map[string]interface{})
 	queryArg, err = generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	queryArg["_RowKey"] = o.ID
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
 
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 func (o *OidcWebApp) processWorkflow() error {
@@ -207,12 +209,18 @@ func (o *OidcWebApp) GetIDByName() (string, error) {
 		return "", fmt.Errorf("%s name must be provided", GetVarType(o))
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("error retrieving %s: %s", GetVarType(o), err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, "", o.ID)
 
 	return o.ID, nil
 }
@@ -222,7 +230,7 @@ func (o *OidcWebApp) GetByName() error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf("failed to find ID of %s %s. %v", GetVarType(o), o.Name, err)
 		}
 	}
@@ -234,15 +242,30 @@ func (o *OidcWebApp) GetByName() error {
 	return nil
 }
 
+// Validate checks OpenID Connect web app attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *OidcWebApp) Validate() error {
+	v := &validator{}
+	o.WebApp.validate(v)
+	if o.ID == "" {
+		v.required("application_id", o.ApplicationID)
+	}
+	if p := o.OAuthProfile; p != nil {
+		v.required("oauth_profile.application_url", p.Url)
+		v.check(len(p.Redirects) > 0, "oauth_profile.redirects", "at least one redirect URI is required")
+	}
+
+	return v.err(o, o.Name)
+}
+
 // Query function returns a single WebApp object in map format
 func (o *OidcWebApp) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM Application WHERE 1=1 AND AppType='Web' AND WebAppType='OpenIDConnect'"
+	q := query.Select("Application").Where(query.Eq("AppType", "Web"), query.Eq("WebAppType", "OpenIDConnect"))
 	if o.Name != "" {
-		query += " AND Name='" + o.Name + "'"
+		q.Where(query.Eq("Name", o.Name))
 	}
 	if o.ApplicationID != "" {
-		query += " AND ServiceName='" + o.ApplicationID + "'"
+		q.Where(query.Eq("ServiceName", o.ApplicationID))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
@@ -3,7 +3,7 @@ package platform
 import (
 	"fmt"
 
-	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
@@ -54,24 +54,24 @@ func NewSamlWebApp(c *restapi.RestClient) *SamlWebApp {
 func (o *SamlWebApp) Read() error {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return fmt.Errorf(errormsg)
 	}
 	var queryArg = make(map[string]interface{})
 	queryArg["_RowKey"] = o.ID
 
-	logger.Debugf("Generated Map for Read(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Read(): %+v", queryArg)
 	// Attempt to read from an upstream API
 	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
 
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return err
 	}
 
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 
@@ -81,47 +81,48 @@ func (o *SamlWebApp) Read() error {
 }
 
 // Update function updates an existing WebApp and returns a map that contains update result
-func (o *SamlWebApp) Update() (*restapi.GenericMapResponse, error) {
+func (o *SamlWebApp) Update() (*restapi.BaseAPIResponse, error) {
 	if o.ID == "" {
 		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
-		logger.Errorf(errormsg)
+		o.client.Log().Errorf(errormsg)
 		return nil, fmt.Errorf(errormsg)
 	}
 
 	err := o.processSpMetaData()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	err = o.processWorkflow()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 
 	var queryArg = make(map[string]interface{})
 	queryArg, err = generateRequestMap(o)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	queryArg["_RowKey"] = o.ID
 
-	logger.Debugf("Generated Map for Update(): %+v", queryArg)
+	o.client.Log().Debugf("Generated Map for Update(): %+v", queryArg)
 
+	o.resolver().InvalidateID(o.ID)
 	resp, err := o.client.CallGenericMapAPI(o.apiUpdate, queryArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		o.client.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 
-	return resp, nil
+	return resp.ToBase(), nil
 }
 
 func (o *SamlWebApp) processWorkflow() error {
@@ -147,13 +148,13 @@ func (o *SamlWebApp) processSpMetaData() error {
 		queryArg["Url"] = o.SpMetadataUrl
 		resp, err := o.client.CallGenericMapAPI(o.apiGetSpMetadataFromUrl, queryArg)
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return err
 		}
 
 		if !resp.Success {
 			errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-			logger.Errorf(errmsg)
+			o.client.Log().Errorf(errmsg)
 			return fmt.Errorf(errmsg)
 		} else {
 			mapToStruct(o, resp.Result)
@@ -175,12 +176,18 @@ func (o *SamlWebApp) GetIDByName() (string, error) {
 		return "", fmt.Errorf("%s name must be provided", GetVarType(o))
 	}
 
+	if id, ok := o.resolver().Get(GetVarType(o), o.Name, ""); ok {
+		o.ID = id
+		return o.ID, nil
+	}
+
 	result, err := o.Query()
 	if err != nil {
-		logger.Errorf(err.Error())
+		o.client.Log().Errorf(err.Error())
 		return "", fmt.Errorf("error retrieving %s: %s", GetVarType(o), err)
 	}
 	o.ID = result["ID"].(string)
+	o.resolver().Set(GetVarType(o), o.Name, "", o.ID)
 
 	return o.ID, nil
 }
@@ -190,7 +197,7 @@ func (o *SamlWebApp) GetByName() error {
 	if o.ID == "" {
 		_, err := o.GetIDByName()
 		if err != nil {
-			logger.Errorf(err.Error())
+			o.client.Log().Errorf(err.Error())
 			return fmt.Errorf("failed to find ID of %s %s. %v", GetVarType(o), o.Name, err)
 		}
 	}
@@ -202,26 +209,41 @@ func (o *SamlWebApp) GetByName() error {
 	return nil
 }
 
+// Validate checks SAML web app attributes before they are sent to tenant. Returns ValidationError listing every invalid attribute
+func (o *SamlWebApp) Validate() error {
+	v := &validator{}
+	o.WebApp.validate(v)
+	v.inRange("sp_config_method", o.SpConfigMethod, 0, 1)
+	if o.SpConfigMethod == 1 {
+		v.check(o.SpMetadataUrl != "" || o.SpMetadataXml != "", "sp_metadata_url", "sp_metadata_url or sp_metadata_xml is required when sp_config_method is 1")
+	}
+	for i, a := range o.SamlAttributes {
+		v.required(fmt.Sprintf("saml_attribute[%d].name", i), a.Name)
+	}
+
+	return v.err(o, o.Name)
+}
+
 // Query function returns a single WebApp object in map format
 func (o *SamlWebApp) Query() (map[string]interface{}, error) {
-	query := "SELECT * FROM Application WHERE 1=1 AND AppType='Web' AND WebAppType='Saml'"
+	q := query.Select("Application").Where(query.Eq("AppType", "Web"), query.Eq("WebAppType", "Saml"))
 	if o.Name != "" {
-		query += " AND Name='" + o.Name + "'"
+		q.Where(query.Eq("Name", o.Name))
 	}
 	if o.ServiceName != "" {
-		query += " AND ServiceName='" + o.ServiceName + "'"
+		q.Where(query.Eq("ServiceName", o.ServiceName))
 	}
 	if o.CorpIdentifier != "" {
-		query += " AND CorpIdentifier='" + o.CorpIdentifier + "'"
+		q.Where(query.Eq("CorpIdentifier", o.CorpIdentifier))
 	}
 	if o.AdditionalField1 != "" {
-		query += " AND AdditionalField1='" + o.AdditionalField1 + "'"
+		q.Where(query.Eq("AdditionalField1", o.AdditionalField1))
 	}
 	if o.Audience != "" {
-		query += " AND Audience='" + o.Audience + "'"
+		q.Where(query.Eq("Audience", o.Audience))
 	}
 
-	return queryVaultObject(o.client, query)
+	return queryVaultObject(o.client, q)
 }
 
 /*
@@ -0,0 +1,367 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/marcozj/golang-sdk/restapi"
+)
+
+// Exit codes returned by DriftReport.ExitCode
+const (
+	DriftExitOK    = 0 // All objects match
+	DriftExitDrift = 1 // At least one object differs or is missing
+	DriftExitError = 2 // At least one object couldn't be checked
+)
+
+// readOnlyAttributes are computed by tenant so they are never compared
+var readOnlyAttributes = map[string]bool{
+	"id":          true,
+	"uuid":        true,
+	"status":      true,
+	"oidc_script": true,
+	"client_id":   true,
+	"issuer":      true,
+}
+
+// driftDefaults are server side default values of attributes per object type. Attribute that isn't defined locally
+// or isn't returned by tenant is compared as its default
+var driftDefaults = map[string]map[string]interface{}{
+	"System": {
+		"management_mode":                              "Unknown",
+		"use_domain_assignment_for_zoneroles":          true,
+		"use_domain_assignment_for_zonerole_approvers": true,
+	},
+	"AuthenticationProfile": {
+		"pass_through_duration": 30,
+	},
+	"Policy": {
+		"settings.centrify_services.session_lifespan":         12,
+		"settings.centrify_services.persist_session_lifespan": 336,
+		"settings.self_service.max_reset_allowed":             10,
+		"settings.self_service.max_time_allowed":              60,
+		"settings.password_settings.min_length":               8,
+		"settings.password_settings.max_length":               64,
+		"settings.password_settings.require_digit":            true,
+		"settings.password_settings.require_mix_case":         true,
+		"settings.password_settings.max_age_in_days":          365,
+		"settings.password_settings.password_history":         3,
+		"settings.password_settings.expire_soft_notification": 14,
+		"settings.password_settings.expire_hard_notification": 48,
+		"settings.password_settings.capture_window":           30,
+		"settings.password_settings.lockout_duration":         30,
+	},
+}
+
+// DriftOptions controls how local definitions are compared with tenant
+type DriftOptions struct {
+	Ignore   []string               // Additional attributes to ignore. Nested attributes are written as "oauth_profile.url"
+	Defaults map[string]interface{} // Server side default values in addition to those the SDK knows for each object type
+	Strict   bool                   // Also report attributes that aren't defined locally but have non default value in tenant
+}
+
+// defaults returns server side default values of kind overridden by Defaults
+func (opts DriftOptions) defaults(kind string) map[string]interface{} {
+	m := make(map[string]interface{})
+	for k, v := range driftDefaults[kind] {
+		m[k] = v
+	}
+	for k, v := range opts.Defaults {
+		m[k] = v
+	}
+	return m
+}
+
+// DriftField is a single attribute that differs
+type DriftField struct {
+	Field    string      `json:"field"`
+	Expected interface{} `json:"expected"`
+	Actual   interface{} `json:"actual"`
+}
+
+// DriftResult is comparison result of one object
+type DriftResult struct {
+	Kind    string       `json:"kind"`
+	Name    string       `json:"name"`
+	ID      string       `json:"id,omitempty"`
+	Missing bool         `json:"missing,omitempty"` // Object doesn't exist in tenant
+	Fields  []DriftField `json:"fields,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// Drifted returns true if object is missing or any attribute differs
+func (r *DriftResult) Drifted() bool {
+	return r.Missing || len(r.Fields) > 0
+}
+
+// DriftReport contains result of every checked object
+type DriftReport struct {
+	Results []DriftResult `json:"results"`
+	Checked int           `json:"checked"`
+	Drifted int           `json:"drifted"`
+	Errors  int           `json:"errors"`
+}
+
+func (r *DriftReport) add(result DriftResult) {
+	r.Checked++
+	if result.Error != "" {
+		r.Errors++
+	} else if result.Drifted() {
+		r.Drifted++
+	}
+	r.Results = append(r.Results, result)
+}
+
+// ExitCode returns DriftExitOK, DriftExitDrift or DriftExitError so that CI job fails on drift
+func (r *DriftReport) ExitCode() int {
+	switch {
+	case r.Errors > 0:
+		return DriftExitError
+	case r.Drifted > 0:
+		return DriftExitDrift
+	}
+	return DriftExitOK
+}
+
+// WriteJSON writes report in JSON format
+func (r *DriftReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// String prints objects that drifted or failed, followed by a summary line
+func (r *DriftReport) String() string {
+	var buf bytes.Buffer
+	for _, result := range r.Results {
+		switch {
+		case result.Error != "":
+			fmt.Fprintf(&buf, "! %s %q: %s\n", result.Kind, result.Name, result.Error)
+		case result.Missing:
+			fmt.Fprintf(&buf, "- %s %q: missing in tenant\n", result.Kind, result.Name)
+		case len(result.Fields) > 0:
+			fmt.Fprintf(&buf, "~ %s %q\n", result.Kind, result.Name)
+			for _, f := range result.Fields {
+				fmt.Fprintf(&buf, "      %s: expected %s, actual %s\n", f.Field, planValue(f.Expected), planValue(f.Actual))
+			}
+		}
+	}
+	fmt.Fprintf(&buf, "Drift: %d checked, %d drifted, %d errors\n", r.Checked, r.Drifted, r.Errors)
+	return buf.String()
+}
+
+// CheckDrift compares each local object definition with the object of the same name in tenant
+func CheckDrift(objects []Object, opts DriftOptions) *DriftReport {
+	report := &DriftReport{}
+	for _, o := range objects {
+		result := DriftResult{Kind: GetVarType(o), Name: o.GetName()}
+		local, err := GenerateSchemaMap(o)
+		if err == nil {
+			err = checkObjectDrift(o, local, &result, opts)
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		report.add(result)
+	}
+
+	return report
+}
+
+// CheckBundleDrift compares objects stored in bundle, such as YAML file kept in Git, with tenant of c
+func CheckBundleDrift(c *restapi.RestClient, b *Bundle, opts DriftOptions) *DriftReport {
+	report := &DriftReport{}
+	for _, kind := range BundleKinds {
+		k, ok := bundleKinds[kind]
+		if !ok {
+			continue
+		}
+		for _, m := range b.Objects[kind] {
+			local := copyMap(m)
+			delete(local, setMembersAttribute)
+			o := k.newObject(c)
+			result := DriftResult{Kind: GetVarType(o)}
+			err := fillFromSchemaMap(o, local)
+			if err == nil {
+				result.Name = o.GetName()
+				err = checkObjectDrift(o, local, &result, opts)
+			}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			report.add(result)
+		}
+	}
+
+	return report
+}
+
+func checkObjectDrift(o Object, local map[string]interface{}, result *DriftResult, opts DriftOptions) error {
+	remote, err := readRemote(o)
+	if err != nil {
+		return err
+	}
+	if remote == nil {
+		result.Missing = true
+		return nil
+	}
+	result.ID = remote.GetID()
+
+	remoteMap, err := GenerateSchemaMap(remote)
+	if err != nil {
+		return err
+	}
+	result.Fields = diffDrift(local, remoteMap, opts.defaults(result.Kind), opts)
+	return nil
+}
+
+// readRemote reads object with the same name as o into a fresh copy so that local values don't mask
+// attributes that are empty in tenant. Returns nil if object doesn't exist
+func readRemote(o Object) (Object, error) {
+	remote := cloneObject(o)
+	_, err := remote.Query()
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	id, err := remote.GetIDByName()
+	if err != nil {
+		return nil, err
+	}
+
+	resetSchemaFields(reflect.ValueOf(remote).Elem())
+	remote.(idSetter).setID(id)
+	err = remote.Read()
+	if err != nil {
+		logOf(o).Errorf(err.Error())
+		return nil, err
+	}
+	return remote, nil
+}
+
+// resetSchemaFields sets every attribute with schema tag to zero value
+func resetSchemaFields(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		sf := t.Field(i)
+		if sf.Anonymous && f.Kind() == reflect.Struct {
+			resetSchemaFields(f)
+			continue
+		}
+		if _, ok := sf.Tag.Lookup("schema"); ok && f.CanSet() {
+			f.Set(reflect.Zero(f.Type()))
+		}
+	}
+}
+
+// diffDrift compares flattened local and remote attributes. Attributes missing on either side take their default value
+func diffDrift(local, remote map[string]interface{}, defaults map[string]interface{}, opts DriftOptions) []DriftField {
+	ignore := make(map[string]bool)
+	for _, k := range opts.Ignore {
+		ignore[k] = true
+	}
+	want := make(map[string]interface{})
+	have := make(map[string]interface{})
+	flattenAttributes("", local, want)
+	flattenAttributes("", remote, have)
+
+	paths := make(map[string]bool)
+	for k := range want {
+		paths[k] = true
+	}
+	if opts.Strict {
+		for k := range have {
+			paths[k] = true
+		}
+	}
+
+	var fields []DriftField
+	for path := range paths {
+		attr := path[strings.LastIndex(path, ".")+1:]
+		if ignore[path] || readOnlyAttributes[attr] || secretAttributes[attr] {
+			continue
+		}
+		expected, defined := want[path]
+		if !defined {
+			expected = defaults[path]
+		}
+		actual, returned := have[path]
+		if !returned {
+			actual = defaults[path]
+		}
+		if reflect.DeepEqual(normalizeDriftValue(expected), normalizeDriftValue(actual)) {
+			continue
+		}
+		fields = append(fields, DriftField{Field: path, Expected: expected, Actual: actual})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+
+	return fields
+}
+
+// flattenAttributes converts nested attributes into dotted paths. Lists are kept as single value
+func flattenAttributes(prefix string, m map[string]interface{}, out map[string]interface{}) {
+	for k, v := range m {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok {
+			flattenAttributes(path, nested, out)
+			continue
+		}
+		out[path] = v
+	}
+}
+
+// normalizeDriftValue treats empty values as absent and numbers as float64 so that values decoded from YAML
+// and JSON compare equal
+func normalizeDriftValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		if v == "" {
+			return nil
+		}
+	case bool:
+		if !v {
+			return nil
+		}
+	case int:
+		return normalizeDriftValue(float64(v))
+	case int64:
+		return normalizeDriftValue(float64(v))
+	case float64:
+		if v == 0 {
+			return nil
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = normalizeDriftValue(item)
+		}
+		return list
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		m := make(map[string]interface{})
+		for k, item := range v {
+			m[k] = normalizeDriftValue(item)
+		}
+		return m
+	}
+	return v
+}
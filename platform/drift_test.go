@@ -0,0 +1,52 @@
+package platform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func driftFieldNames(fields []DriftField) []string {
+	names := []string{}
+	for _, f := range fields {
+		names = append(names, f.Field)
+	}
+	return names
+}
+
+func TestDiffDriftDefaults(t *testing.T) {
+	local := map[string]interface{}{
+		"name":     "web01",
+		"settings": map[string]interface{}{"password_settings": map[string]interface{}{"min_length": 8, "max_length": 32}},
+	}
+	remote := map[string]interface{}{
+		"name":                                "web01",
+		"management_mode":                     "Unknown",
+		"use_domain_assignment_for_zoneroles": true,
+		"description":                         "Changed",
+		"settings": map[string]interface{}{"password_settings": map[string]interface{}{
+			"max_length":       float64(64),
+			"password_history": float64(3),
+		}},
+	}
+
+	tests := []struct {
+		name string
+		kind string
+		opts DriftOptions
+		want []string
+	}{
+		{"defined attributes", "Policy", DriftOptions{}, []string{"settings.password_settings.max_length"}},
+		{"strict with defaults of kind", "System", DriftOptions{Strict: true},
+			[]string{"description", "settings.password_settings.max_length", "settings.password_settings.min_length", "settings.password_settings.password_history"}},
+		{"strict with policy defaults", "Policy", DriftOptions{Strict: true},
+			[]string{"description", "management_mode", "settings.password_settings.max_length", "use_domain_assignment_for_zoneroles"}},
+		{"option overrides default", "System", DriftOptions{Strict: true, Defaults: map[string]interface{}{"management_mode": "Smb", "description": "Changed"}},
+			[]string{"management_mode", "settings.password_settings.max_length", "settings.password_settings.min_length", "settings.password_settings.password_history"}},
+	}
+	for _, tt := range tests {
+		fields := diffDrift(local, remote, tt.opts.defaults(tt.kind), tt.opts)
+		if got := driftFieldNames(fields); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: drifted %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -2,18 +2,26 @@ package platform
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
+	"github.com/marcozj/golang-sdk/audit"
 	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform/query"
 	"github.com/marcozj/golang-sdk/restapi"
 )
 
 // Define this for convience usage
 type keyValue map[string]interface{}
 
+// ErrNotFound is returned by Query when there is no matched object
+var ErrNotFound = errors.New("Query returns 0 object")
+
 var (
 	subArgs = make(map[string]interface{})
 	// Right reppresents a struct of valid rights
@@ -130,6 +138,21 @@ func GenerateSchemaMap(i interface{}) (map[string]interface{}, error) {
 	return mapData, nil
 }
 
+// fillFromSchemaMap is the reverse of GenerateSchemaMap. It populates object attributes from map keyed by schema tags
+func fillFromSchemaMap(i interface{}, m map[string]interface{}) error {
+	dataBytes, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	schemaJSON := jsoniter.Config{TagKey: "schema", OnlyTaggedField: true}.Froze()
+	err = schemaJSON.Unmarshal(dataBytes, i)
+	if err != nil {
+		return fmt.Errorf("Failed to unmarshal map: %v", err)
+	}
+
+	return nil
+}
+
 // flattenNestedMap converts nested map to flat map. It is used by Policy object.
 // It is assumed that json tag of each nested struct element is unique
 func flattenNestedMap(flatMap map[string]interface{}, nestedMap interface{}) error {
@@ -188,11 +211,11 @@ func RedRockQuery(client *restapi.RestClient, query string, args map[string]inte
 		queryArg["Args"] = args
 	}
 
-	logger.Debugf("Query arguments: %+v", queryArg)
+	client.Log().Debugf("Query arguments: %+v", queryArg)
 	resp, err := client.CallGenericMapAPI("/RedRock/query", queryArg)
 	//logger.Debugf("Query response: %+v", resp)
 	if err != nil {
-		logger.ErrorTracef(err.Error())
+		client.Log().ErrorTracef(err.Error())
 		return nil, err
 	}
 	if !resp.Success {
@@ -204,22 +227,26 @@ func RedRockQuery(client *restapi.RestClient, query string, args map[string]inte
 	return results, nil
 }
 
-func queryVaultObject(client *restapi.RestClient, query string) (map[string]interface{}, error) {
-	results, err := RedRockQuery(client, query, nil)
+func queryVaultObject(client *restapi.RestClient, q *query.Builder) (map[string]interface{}, error) {
+	script, err := q.Build()
+	if err != nil {
+		client.Log().Errorf(err.Error())
+		return nil, err
+	}
+	results, err := RedRockQuery(client, script, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	if len(results) == 0 {
-		errmsg := "Query returns 0 object"
 		//logger.Errorf(errmsg)
-		logger.ErrorTracef(errmsg)
-		return nil, fmt.Errorf(errmsg)
+		client.Log().ErrorTracef(ErrNotFound.Error())
+		return nil, ErrNotFound
 	}
 	if len(results) > 1 {
 		errmsg := fmt.Sprintf("Query returns too many objects (found %d, expected 1)", len(results))
 		//logger.Errorf(errmsg)
-		logger.ErrorTracef(errmsg)
+		client.Log().ErrorTracef(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 	var result = results[0].(map[string]interface{})
@@ -333,46 +360,10 @@ func ConvertToValidList(input []string, validMap map[string]string) ([]string, e
 
 // ResolvePermissions given a list of Permissions, resolve PrincipalID and convert the given rights to actual rights
 func ResolvePermissions(c *restapi.RestClient, perms []Permission, validPerms map[string]string) error {
-	var err error
-	for i, p := range perms {
-		// Resolove PrincipalID
-		switch strings.ToLower(p.PrincipalType) {
-		case "user":
-			user := NewUser(c)
-			user.Name = p.PrincipalName
-			perms[i].PrincipalID, err = user.GetIDByName()
-			if err != nil {
-				return err
-			}
-		case "role":
-			role := NewRole(c)
-			role.Name = p.PrincipalName
-			perms[i].PrincipalID, err = role.GetIDByName()
-			if err != nil {
-				return err
-			}
-		default:
-			errmsg := fmt.Sprintf("Invalid PrincipalType %s", p.PrincipalType)
-			logger.ErrorTracef(errmsg)
-			return fmt.Errorf(errmsg)
-			//return fmt.Errorf("Invalid PrincipalType %s", p.PrincipalType)
-		}
-
-		// Convert rights
-		var rights []string
-		if p.Rights != "" {
-			rights, err = ConvertToValidList(strings.Split(p.Rights, ","), validPerms)
-		} else if p.RightList != nil {
-			rights, err = ConvertToValidList(p.RightList, validPerms)
-		}
-		if err != nil {
-			return err
-		}
-		perms[i].Rights = FlattenSliceToString(rights)
+	for i := range perms {
+		perms[i].PrincipalID = ""
 	}
-	logger.Debugf("Resolved permissions: %+v", perms)
-
-	return nil
+	return ResolvePermissions2(c, perms, validPerms)
 }
 
 // ResolvePermissions2 detects if PrincipalID is set, if not then resolve it
@@ -380,27 +371,14 @@ func ResolvePermissions2(c *restapi.RestClient, perms []Permission, validPerms m
 	var err error
 	for i, p := range perms {
 		// Resolove PrincipalID
+		perms[i].PrincipalType, err = principalType(p.PrincipalType)
+		if err != nil {
+			return err
+		}
 		if perms[i].PrincipalID == "" {
-			switch strings.ToLower(p.PrincipalType) {
-			case "user":
-				user := NewUser(c)
-				user.Name = p.PrincipalName
-				perms[i].PrincipalID, err = user.GetIDByName()
-				if err != nil {
-					return err
-				}
-			case "role":
-				role := NewRole(c)
-				role.Name = p.PrincipalName
-				perms[i].PrincipalID, err = role.GetIDByName()
-				if err != nil {
-					return err
-				}
-			default:
-				errmsg := fmt.Sprintf("Invalid PrincipalType %s", p.PrincipalType)
-				logger.ErrorTracef(errmsg)
-				return fmt.Errorf(errmsg)
-				//return fmt.Errorf("Invalid PrincipalType %s", p.PrincipalType)
+			perms[i].PrincipalID, err = resolvePrincipal(c, perms[i])
+			if err != nil {
+				return err
 			}
 		}
 
@@ -416,15 +394,59 @@ func ResolvePermissions2(c *restapi.RestClient, perms []Permission, validPerms m
 		}
 		perms[i].Rights = FlattenSliceToString(rights)
 	}
-	logger.Debugf("Resolved permissions: %+v", perms)
+	c.Log().Debugf("Resolved permissions: %+v", perms)
 
 	return nil
 }
 
-func noFoundError() string {
-	errmsg := "Query returns 0 object"
-	logger.Errorf(errmsg)
-	return errmsg
+// principalType returns principal type in the form expected by API: User, Group or Role
+func principalType(t string) (string, error) {
+	switch strings.ToLower(t) {
+	case "user":
+		return "User", nil
+	case "group":
+		return "Group", nil
+	case "role":
+		return "Role", nil
+	}
+	errmsg := fmt.Sprintf("Invalid PrincipalType %s", t)
+	logger.ErrorTracef(errmsg)
+	return "", fmt.Errorf(errmsg)
+}
+
+// resolvePrincipal returns ID of permission principal. Principal that names its directory service is looked up in that
+// directory, such as Active Directory user or LDAP group. Otherwise user and role are looked up in Centrify Directory
+func resolvePrincipal(c *restapi.RestClient, p Permission) (string, error) {
+	if p.DirectoryService == "" {
+		switch p.PrincipalType {
+		case "User":
+			user := NewUser(c)
+			user.Name = p.PrincipalName
+			return user.GetIDByName()
+		case "Role":
+			role := NewRole(c)
+			role.Name = p.PrincipalName
+			return role.GetIDByName()
+		}
+		return "", fmt.Errorf("DirectoryService and DirectoryName are required for %s principal %s", p.PrincipalType, p.PrincipalName)
+	}
+
+	// Get directory service
+	dirs := NewDirectoryServices(c)
+	dir, err := dirs.GetByName(p.DirectoryService, p.DirectoryName)
+	if err != nil {
+		return "", fmt.Errorf("Error retrieving directory service %s %s: %s", p.DirectoryService, p.DirectoryName, err)
+	}
+	// Get directory object
+	objs := NewDirectoryObjects(c)
+	obj, err := objs.GetByName(p.PrincipalType, p.PrincipalName, *dir)
+	if err != nil {
+		return "", err
+	}
+	if p.PrincipalType == "Role" {
+		return obj.RoleID, nil
+	}
+	return obj.ID, nil
 }
 
 func foundTooManyError(no int) string {
@@ -435,7 +457,8 @@ func foundTooManyError(no int) string {
 
 func queryError(no int) error {
 	if no == 0 {
-		return fmt.Errorf(noFoundError())
+		logger.Errorf(ErrNotFound.Error())
+		return ErrNotFound
 	}
 	if no > 1 {
 		return fmt.Errorf(foundTooManyError(no))
@@ -595,7 +618,7 @@ func GetAllZoneRoles(c *restapi.RestClient, domainid string) (map[string]ZoneRol
 	// Attempt to read from an upstream API
 	resp, err := c.CallSliceAPI("/ZoneRoleWorkflow/GetAllRoles", requestArg)
 	if err != nil {
-		logger.Errorf(err.Error())
+		c.Log().Errorf(err.Error())
 		return nil, err
 	}
 	if resp.Success {
@@ -611,7 +634,7 @@ func GetAllZoneRoles(c *restapi.RestClient, domainid string) (map[string]ZoneRol
 		return zonerolemap, nil
 	} else {
 		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
-		logger.Errorf(errmsg)
+		c.Log().Errorf(errmsg)
 		return nil, fmt.Errorf(errmsg)
 	}
 }
@@ -668,7 +691,7 @@ func FlattenZoneRoles(zoneroles []ZoneRole) string {
 func resolveZoneRoles(c *restapi.RestClient, zoneroles []ZoneRole, domainid string) error {
 	if domainid == "" {
 		errmsg := "missing domain id"
-		logger.ErrorTracef(errmsg)
+		c.Log().ErrorTracef(errmsg)
 		return fmt.Errorf(errmsg)
 	}
 	// Retrieve all zone roles
@@ -691,3 +714,36 @@ func resolveZoneRoles(c *restapi.RestClient, zoneroles []ZoneRole, domainid stri
 
 	return nil
 }
+
+// recordAccess sends credential access event to audit sink of the client if there is one
+func recordAccess(c *restapi.RestClient, action string, objtype string, id string, name string, coid string, err error) {
+	if c == nil {
+		return
+	}
+	event := audit.Event{
+		Action:     action,
+		ObjectType: objtype,
+		ObjectID:   id,
+		ObjectName: name,
+		COID:       coid,
+		Outcome:    audit.OutcomeSuccess,
+	}
+	if err != nil {
+		event.Outcome = audit.OutcomeFailure
+		event.Error = err.Error()
+	}
+	c.RecordAudit(event)
+}
+
+// parseDate converts date returned by tenant such as "/Date(1588075662725)/" into time. Returns zero time if v isn't a date
+func parseDate(v interface{}) time.Time {
+	s, _ := v.(string)
+	if !strings.HasPrefix(s, "/Date(") || !strings.HasSuffix(s, ")/") {
+		return time.Time{}
+	}
+	ms, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(s, "/Date("), ")/"), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC()
+}
@@ -0,0 +1,338 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcozj/golang-sdk/audit"
+	"github.com/marcozj/golang-sdk/enum/settype"
+)
+
+// DefaultCheckoutLifetime is checkout lifetime of tenant when neither account nor its resource has DefaultCheckoutTime
+const DefaultCheckoutLifetime = 60 * time.Minute
+
+// DefaultLeaseRegistry keeps every lease that isn't given its own registry. Call DefaultLeaseRegistry.CloseAll()
+// on shutdown so that no checkout is left behind
+var DefaultLeaseRegistry = NewLeaseRegistry()
+
+// LeaseOptions controls how checked out password is held
+type LeaseOptions struct {
+	Context     context.Context // Optional. Password is checked in as soon as context is done
+	AutoRenew   bool            // Extend checkout before it expires until lease is closed
+	RenewBefore time.Duration   // How long before expiry checkout is extended. Defaults to 1/5 of lifetime
+	Lifetime    time.Duration   // Lease lifetime. Capped to CheckoutLifetime of account, which tenant applies to checkout. Defaults to it
+	Registry    *LeaseRegistry  // Registry that tracks the lease. Defaults to DefaultLeaseRegistry
+}
+
+// Lease is a checked out account password. Close checks it in
+type Lease struct {
+	Password string
+	COID     string
+
+	account  *Account
+	lifetime time.Duration
+	registry *LeaseRegistry
+	callMu   sync.Mutex // Serializes check in and extension so that mu isn't held during API calls
+	mu       sync.Mutex
+	expires  time.Time
+	closed   bool
+	done     chan struct{}
+	onClose  []func()
+}
+
+// CheckoutLease checks out account password and returns lease that holds it until Close is called,
+// context of opts is done or registry is closed
+func (o *Account) CheckoutLease(opts LeaseOptions) (lease *Lease, err error) {
+	var coid string
+	defer func() {
+		recordAccess(o.client, audit.ActionCheckoutPassword, settype.Account.String(), o.ID, o.User, coid, err)
+	}()
+
+	err = o.resolveCheckoutID()
+	if err != nil {
+		return nil, err
+	}
+	lifetime, err := o.CheckoutLifetime()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Lifetime > 0 && opts.Lifetime < lifetime {
+		lifetime = opts.Lifetime
+	}
+
+	start := time.Now()
+	reply, err := o.checkoutPassword()
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return nil, err
+	}
+	coid, _ = reply.Result["COID"].(string)
+	pw, ok := reply.Result["Password"].(string)
+	if !ok {
+		if coid != "" {
+			// Don't leave checkout behind that nobody can check in
+			o.CheckinPassword(coid)
+		}
+		return nil, fmt.Errorf("Password checkout call doesn't contain password")
+	}
+	if coid == "" {
+		return nil, fmt.Errorf("No COID returned from checkout")
+	}
+
+	l := &Lease{
+		Password: pw,
+		COID:     coid,
+		account:  o,
+		lifetime: lifetime,
+		registry: opts.Registry,
+		done:     make(chan struct{}),
+	}
+	l.expires = start.Add(l.lifetime)
+	if l.registry == nil {
+		l.registry = DefaultLeaseRegistry
+	}
+	l.registry.add(l)
+
+	renewBefore := opts.RenewBefore
+	if renewBefore <= 0 || renewBefore >= l.lifetime {
+		renewBefore = l.lifetime / 5
+	}
+	if opts.Context != nil || opts.AutoRenew {
+		go l.watch(opts.Context, opts.AutoRenew, renewBefore)
+	}
+
+	return l, nil
+}
+
+// CheckoutLifetime returns checkout lifetime that applies to account. Lifetime set on account takes precedence over
+// the one inherited from its system, domain or database. DefaultCheckoutLifetime is returned if none of them sets it.
+// Account and its resource are read from tenant as needed
+func (o *Account) CheckoutLifetime() (time.Duration, error) {
+	if o.DefaultCheckoutTime == 0 {
+		if o.ID == "" {
+			errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(0))
+			o.client.Log().Errorf(errormsg)
+			return 0, fmt.Errorf(errormsg)
+		}
+		err := o.Read()
+		if err != nil {
+			return 0, err
+		}
+	}
+	if o.DefaultCheckoutTime > 0 {
+		return time.Duration(o.DefaultCheckoutTime) * time.Minute, nil
+	}
+
+	var minutes int
+	var err error
+	switch {
+	case o.Host != "":
+		parent := NewSystem(o.client)
+		parent.ID = o.Host
+		err = parent.Read()
+		minutes = parent.DefaultCheckoutTime
+	case o.DomainID != "":
+		parent := NewDomain(o.client)
+		parent.ID = o.DomainID
+		err = parent.Read()
+		minutes = parent.DefaultCheckoutTime
+	case o.DatabaseID != "":
+		parent := NewDatabase(o.client)
+		parent.ID = o.DatabaseID
+		err = parent.Read()
+		minutes = parent.DefaultCheckoutTime
+	}
+	if err != nil {
+		return 0, fmt.Errorf("Failed to read checkout lifetime of resource of account %s. %v", o.User, err)
+	}
+	if minutes > 0 {
+		return time.Duration(minutes) * time.Minute, nil
+	}
+	return DefaultCheckoutLifetime, nil
+}
+
+// Expires returns time when checkout expires
+func (l *Lease) Expires() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.expires
+}
+
+// Closed returns true if password is checked in
+func (l *Lease) Closed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.closed
+}
+
+// Done returns channel that is closed when lease is closed
+func (l *Lease) Done() <-chan struct{} {
+	return l.done
+}
+
+// Account returns account whose password is checked out
+func (l *Lease) Account() *Account {
+	return l.account
+}
+
+// OnClose registers f to be called once password is checked in, before Close returns. Use it to release whatever
+// depends on the checkout. f is called right away if lease is already closed
+func (l *Lease) OnClose(f func()) {
+	l.mu.Lock()
+	if !l.closed {
+		l.onClose = append(l.onClose, f)
+		l.mu.Unlock()
+		return
+	}
+	l.mu.Unlock()
+	f()
+}
+
+// Close checks in password. It is safe to call Close more than once. If check in fails, lease stays open and in its
+// registry so that Close or CloseAll can try again
+func (l *Lease) Close() error {
+	l.callMu.Lock()
+	if l.Closed() {
+		l.callMu.Unlock()
+		return nil
+	}
+	_, err := l.account.CheckinPassword(l.COID)
+	if err != nil {
+		l.callMu.Unlock()
+		return fmt.Errorf("Failed to check in password of account %s: %v", l.account.User, err)
+	}
+
+	l.mu.Lock()
+	l.closed = true
+	close(l.done)
+	hooks := l.onClose
+	l.onClose = nil
+	l.mu.Unlock()
+	l.registry.remove(l)
+	l.callMu.Unlock()
+
+	// Hooks run without lock so that they can use the lease
+	for _, f := range hooks {
+		f()
+	}
+	return nil
+}
+
+// Renew extends checkout so that it expires one lifetime from now
+func (l *Lease) Renew() (err error) {
+	l.callMu.Lock()
+	defer l.callMu.Unlock()
+
+	if l.Closed() {
+		return fmt.Errorf("Lease of account %s is closed", l.account.User)
+	}
+	defer func() {
+		recordAccess(l.account.client, audit.ActionExtendCheckout, settype.Account.String(), l.account.ID, l.account.User, l.COID, err)
+	}()
+
+	var queryArg = make(map[string]interface{})
+	queryArg["ID"] = l.COID
+	start := time.Now()
+	resp, err := l.account.client.CallBoolAPI(l.account.apiExtendCheckout, queryArg)
+	if err != nil {
+		l.account.client.Log().Errorf(err.Error())
+		return err
+	}
+	if !resp.Success {
+		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
+		l.account.client.Log().Errorf(errmsg)
+		return fmt.Errorf(errmsg)
+	}
+	l.mu.Lock()
+	l.expires = start.Add(l.lifetime)
+	l.mu.Unlock()
+
+	return nil
+}
+
+// watch checks in password when context is done and renews checkout before it expires
+func (l *Lease) watch(ctx context.Context, autoRenew bool, renewBefore time.Duration) {
+	var ctxDone <-chan struct{}
+	if ctx != nil {
+		ctxDone = ctx.Done()
+	}
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+	for {
+		var renew <-chan time.Time
+		if autoRenew {
+			timer.Reset(time.Until(l.Expires().Add(-renewBefore)))
+			renew = timer.C
+		}
+		select {
+		case <-l.done:
+			return
+		case <-ctxDone:
+			l.Close()
+			return
+		case <-renew:
+			if err := l.Renew(); err != nil {
+				// Keep the lease until it is closed. Checkout simply expires if it can't be extended
+				l.account.client.Log().Errorf("Failed to extend checkout of account %s: %v", l.account.User, err)
+				autoRenew = false
+			}
+		}
+	}
+}
+
+// LeaseRegistry tracks outstanding leases so that all of them can be checked in on shutdown
+type LeaseRegistry struct {
+	mu     sync.Mutex
+	leases map[*Lease]bool
+}
+
+// NewLeaseRegistry creates empty registry
+func NewLeaseRegistry() *LeaseRegistry {
+	return &LeaseRegistry{leases: make(map[*Lease]bool)}
+}
+
+// Leases returns leases that aren't closed
+func (r *LeaseRegistry) Leases() []*Lease {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var leases []*Lease
+	for l := range r.leases {
+		leases = append(leases, l)
+	}
+	return leases
+}
+
+// CloseAll checks in every outstanding lease. Error lists every lease that failed to check in
+func (r *LeaseRegistry) CloseAll() error {
+	var msgs []string
+	for _, l := range r.Leases() {
+		if err := l.Close(); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("%s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+func (r *LeaseRegistry) add(l *Lease) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.leases[l] = true
+}
+
+func (r *LeaseRegistry) remove(l *Lease) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.leases, l)
+}
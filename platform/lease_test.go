@@ -0,0 +1,101 @@
+package platform
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeaseLifetimeCapped(t *testing.T) {
+	c, srv := newTestClient(t, func(path string, args map[string]interface{}) interface{} {
+		switch path {
+		case "/ServerManage/CheckoutPassword":
+			if _, ok := args["Lifetime"]; ok {
+				t.Error("lifetime isn't accepted by checkout")
+			}
+			return map[string]interface{}{"COID": "co1", "Password": "secret"}
+		case "/ServerManage/CheckinPassword":
+			return true
+		}
+		t.Errorf("unexpected call to %s", path)
+		return nil
+	})
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		lifetime, want time.Duration
+	}{
+		{0, 30 * time.Minute},
+		{2 * time.Hour, 30 * time.Minute},
+		{10 * time.Minute, 10 * time.Minute},
+	} {
+		a := NewAccount(c)
+		a.ID = "acct1"
+		a.DefaultCheckoutTime = 30
+		start := time.Now()
+		l, err := a.CheckoutLease(LeaseOptions{Lifetime: tt.lifetime, Registry: NewLeaseRegistry()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := l.Expires().Sub(start); got < tt.want-time.Second || got > tt.want+time.Second {
+			t.Errorf("lifetime %v: lease expires in %v, want %v", tt.lifetime, got, tt.want)
+		}
+		if err = l.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLeaseUnlockedDuringCall(t *testing.T) {
+	entered := make(chan string)
+	release := make(chan struct{})
+	c, srv := newTestClient(t, func(path string, args map[string]interface{}) interface{} {
+		switch path {
+		case "/ServerManage/CheckoutPassword":
+			return map[string]interface{}{"COID": "co1", "Password": "secret"}
+		case "/ServerManage/ExtendCheckout", "/ServerManage/CheckinPassword":
+			entered <- path
+			<-release
+			return true
+		}
+		t.Errorf("unexpected call to %s", path)
+		return nil
+	})
+	defer srv.Close()
+
+	a := NewAccount(c)
+	a.ID = "acct1"
+	a.DefaultCheckoutTime = 30
+	l, err := a.CheckoutLease(LeaseOptions{Registry: NewLeaseRegistry()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, call := range []func() error{l.Renew, l.Close} {
+		errc := make(chan error, 1)
+		go func(call func() error) { errc <- call() }(call)
+		path := <-entered
+
+		// Lease state can be read while the call is in flight
+		state := make(chan bool, 1)
+		go func() {
+			l.Expires()
+			state <- l.Closed()
+		}()
+		select {
+		case closed := <-state:
+			if closed {
+				t.Errorf("%s: lease closed before call returned", path)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("%s: lease is locked during API call", path)
+		}
+
+		release <- struct{}{}
+		if err := <-errc; err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+	}
+	if !l.Closed() {
+		t.Error("lease isn't closed")
+	}
+}
@@ -0,0 +1,240 @@
+package platform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/marcozj/golang-sdk/platform/query"
+	"github.com/marcozj/golang-sdk/restapi"
+)
+
+// DefaultPageSize is number of rows fetched per request by List functions
+const DefaultPageSize = 100
+
+// ListOptions controls paging and sorting of List functions
+type ListOptions struct {
+	PageSize   int    // Number of rows fetched per request. Defaults to DefaultPageSize
+	Limit      int    // Maximum number of rows returned in total. 0 means no limit
+	SortBy     string // Column to sort by. Rows are always sorted by ID last so that pages don't overlap or skip rows
+	Descending bool   // Sort in descending order
+}
+
+// Iterator lazily fetches rows of a RedRock query one page at a time. List functions return typed iterators that
+// embed Iterator and add Value method, which decodes current row into object
+//
+//	it := platform.ListSystems(client, platform.SystemFilter{ComputerClass: "Unix"})
+//	for it.Next() {
+//		system := it.Value()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// Use Row or Decode to read current row of Iterator itself.
+type Iterator struct {
+	client   *restapi.RestClient
+	q        *query.Builder
+	opts     ListOptions
+	err      error
+	page     int                      // Last fetched page number, starting from 1
+	rows     []map[string]interface{} // Rows of last fetched page
+	index    int                      // Position of current row in rows
+	fetched  int                      // Number of rows returned so far
+	total    int                      // Total number of rows reported by server
+	lastPage bool
+}
+
+func newIterator(c *restapi.RestClient, q *query.Builder, opts ListOptions) *Iterator {
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultPageSize
+	}
+	if opts.SortBy != "" {
+		q.OrderBy(opts.SortBy, opts.Descending)
+	}
+	// Pages are only consistent if order is stable
+	if !strings.EqualFold(opts.SortBy, "ID") {
+		q.OrderBy("ID", false)
+	}
+	return &Iterator{client: c, q: q, opts: opts, index: -1}
+}
+
+// errIterator returns iterator that yields nothing but err
+func errIterator(err error) *Iterator {
+	return &Iterator{err: err, lastPage: true, index: -1}
+}
+
+// Next advances to next row, fetching next page when needed. Returns false when there is no more row or an error occurs
+func (it *Iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.opts.Limit > 0 && it.fetched >= it.opts.Limit {
+		return false
+	}
+	if it.index+1 >= len(it.rows) {
+		if it.lastPage {
+			return false
+		}
+		if err := it.fetch(); err != nil {
+			it.err = err
+			return false
+		}
+		if len(it.rows) == 0 {
+			return false
+		}
+	}
+	it.index++
+	it.fetched++
+
+	return true
+}
+
+// Row returns current row
+func (it *Iterator) Row() map[string]interface{} {
+	if it.index < 0 || it.index >= len(it.rows) {
+		return nil
+	}
+	return it.rows[it.index]
+}
+
+// Decode fills v with current row
+func (it *Iterator) Decode(v interface{}) error {
+	row := it.Row()
+	if row == nil {
+		return fmt.Errorf("Iterator has no current row")
+	}
+	return mapToStruct(v, row)
+}
+
+// decode fills v with current row. Decoding error stops iteration and is returned by Err
+func (it *Iterator) decode(v interface{}) {
+	if err := it.Decode(v); err != nil {
+		it.client.Log().Errorf(err.Error())
+		if it.err == nil {
+			it.err = fmt.Errorf("Failed to decode row %d. %v", it.fetched, err)
+		}
+	}
+}
+
+// Err returns error occurred during iteration
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Total returns total number of matched rows reported by server. It is known after first call to Next
+func (it *Iterator) Total() int {
+	return it.total
+}
+
+// All reads all remaining rows
+func (it *Iterator) All() ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	for it.Next() {
+		rows = append(rows, it.Row())
+	}
+	return rows, it.Err()
+}
+
+func (it *Iterator) fetch() error {
+	script, err := it.q.Build()
+	if err != nil {
+		return err
+	}
+
+	it.page++
+	args := map[string]interface{}{
+		"PageNumber": it.page,
+		"PageSize":   it.opts.PageSize,
+		"Limit":      it.opts.PageSize,
+		"Caching":    -1,
+	}
+	var queryArg = make(map[string]interface{})
+	queryArg["Script"] = script
+	queryArg["Args"] = args
+
+	it.client.Log().Debugf("Query arguments: %+v", queryArg)
+	resp, err := it.client.CallGenericMapAPI("/RedRock/query", queryArg)
+	if err != nil {
+		it.client.Log().Errorf(err.Error())
+		return err
+	}
+	if !resp.Success {
+		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
+		it.client.Log().Errorf(errmsg)
+		return fmt.Errorf(errmsg)
+	}
+
+	if v, ok := resp.Result["FullCount"].(float64); ok {
+		it.total = int(v)
+	}
+	results, _ := resp.Result["Results"].([]interface{})
+	it.rows = it.rows[:0]
+	for _, v := range results {
+		result, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if row, ok := result["Row"].(map[string]interface{}); ok {
+			it.rows = append(it.rows, row)
+		}
+	}
+	it.index = -1
+	if len(it.rows) < it.opts.PageSize || (it.total > 0 && it.page*it.opts.PageSize >= it.total) {
+		it.lastPage = true
+	}
+
+	return nil
+}
+
+// getSetMemberIDs returns ID of members in a manual set
+func getSetMemberIDs(c *restapi.RestClient, setID string) ([]string, error) {
+	var queryArg = make(map[string]interface{})
+	queryArg["ID"] = setID
+	resp, err := c.CallSliceAPI("/Collection/GetMembers", queryArg)
+	if err != nil {
+		c.Log().Errorf(err.Error())
+		return nil, err
+	}
+	if !resp.Success {
+		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
+		c.Log().Errorf(errmsg)
+		return nil, fmt.Errorf(errmsg)
+	}
+
+	var ids []string
+	for _, v := range resp.Result {
+		if member, ok := v.(map[string]interface{}); ok {
+			if key, ok := member["Key"].(string); ok {
+				ids = append(ids, key)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// listQuery starts a query against table restricted to members of set if setID isn't empty. Members of manual set
+// are matched by a subquery on set membership table. Dynamic set membership is evaluated by tenant so its member IDs
+// are fetched instead
+func listQuery(c *restapi.RestClient, table string, setID string) (*query.Builder, error) {
+	q := query.Select(table)
+	if setID == "" {
+		return q, nil
+	}
+
+	results, err := queryVaultObject(c, query.Select("Sets", "CollectionType").Where(query.Eq("ID", setID)))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find set %s. %v", setID, err)
+	}
+	if results["CollectionType"] == "ManualBucket" {
+		q.Where(query.InQuery("ID", query.Select("CollectionMembers", "Key").Where(query.Eq("CollectionID", setID))))
+		return q, nil
+	}
+
+	ids, err := getSetMemberIDs(c, setID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get members of set %s. %v", setID, err)
+	}
+	q.Where(query.InStrings("ID", ids))
+	return q, nil
+}
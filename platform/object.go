@@ -0,0 +1,164 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+
+	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/restapi"
+)
+
+// Object is implemented by every vault object type that has its own lifecycle so that
+// generic code such as "ensure exists" or "delete by name" can be written once
+type Object interface {
+	GetID() string
+	GetName() string
+	Read() error
+	Create() (*restapi.BaseAPIResponse, error)
+	Update() (*restapi.BaseAPIResponse, error)
+	Delete() (*restapi.BaseAPIResponse, error)
+	GetIDByName() (string, error)
+	GetByName() error
+	Query() (map[string]interface{}, error)
+	Validate() error
+}
+
+// Make sure all object types implement Object
+var (
+	_ Object = &AuthenticationProfile{}
+	_ Object = &CloudProvider{}
+	_ Object = &Connector{}
+	_ Object = &DesktopApp{}
+	_ Object = &ManualSet{}
+	_ Object = &DynamicSet{}
+	_ Object = &MultiplexedAccount{}
+	_ Object = &PasswordProfile{}
+	_ Object = &Policy{}
+	_ Object = &Role{}
+	_ Object = &Service{}
+	_ Object = &SSHKey{}
+	_ Object = &User{}
+	_ Object = &Account{}
+	_ Object = &Database{}
+	_ Object = &Domain{}
+	_ Object = &Secret{}
+	_ Object = &SecretFolder{}
+	_ Object = &System{}
+	_ Object = &GenericWebApp{}
+	_ Object = &OauthWebApp{}
+	_ Object = &OidcWebApp{}
+	_ Object = &SamlWebApp{}
+)
+
+// GetID returns ID of the object. It is empty if the object hasn't been created or looked up
+func (o *vaultObject) GetID() string {
+	return o.ID
+}
+
+// GetName returns name of the object
+func (o *vaultObject) GetName() string {
+	return o.Name
+}
+
+// idSetter is implemented by every type that embeds vaultObject
+type idSetter interface {
+	setID(id string)
+}
+
+func (o *vaultObject) setID(id string) {
+	o.ID = id
+}
+
+// clientGetter is implemented by every type that embeds vaultObject
+type clientGetter interface {
+	restClient() *restapi.RestClient
+}
+
+func (o *vaultObject) restClient() *restapi.RestClient {
+	return o.client
+}
+
+// Log returns logger of the client of object, e.g. for packages that work with vault objects such as sshagent
+func (o *vaultObject) Log() logger.Printer {
+	return o.client.Log()
+}
+
+// logOf returns logger of the client of o. Default logger is returned if o doesn't have a client
+func logOf(o interface{}) logger.Printer {
+	if g, ok := o.(clientGetter); ok {
+		return g.restClient().Log()
+	}
+	return logger.Printer{Handler: logger.Default()}
+}
+
+// GetName returns account name
+func (o *Account) GetName() string {
+	return o.User
+}
+
+// GetName returns secret name
+func (o *Secret) GetName() string {
+	return o.SecretName
+}
+
+// GetName returns user name
+func (o *User) GetName() string {
+	return o.Name
+}
+
+// GetName returns Windows service name
+func (o *Service) GetName() string {
+	return o.Name
+}
+
+// IsNotFound returns true if err indicates that object doesn't exist
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// EnsureExists looks up object by its name and creates it if it doesn't exist.
+// Existing object is left unchanged. Returns true if object is created
+func EnsureExists(o Object) (bool, error) {
+	if o.GetID() != "" {
+		return false, nil
+	}
+
+	_, err := o.Query()
+	if err == nil {
+		_, err = o.GetIDByName()
+		return false, err
+	}
+	if !IsNotFound(err) {
+		return false, err
+	}
+
+	_, err = o.Create()
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteObjectByName looks up object by its name and deletes it. Returns true if object is found and deleted
+func DeleteObjectByName(o Object) (bool, error) {
+	if o.GetID() == "" {
+		_, err := o.Query()
+		if IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		_, err = o.GetIDByName()
+		if err != nil {
+			return false, err
+		}
+	}
+
+	_, err := o.Delete()
+	if err != nil {
+		logOf(o).Errorf(err.Error())
+		return false, fmt.Errorf("Failed to delete %s %s. %v", GetVarType(o), o.GetID(), err)
+	}
+	return true, nil
+}
@@ -0,0 +1,39 @@
+package query
+
+import (
+	"encoding/json"
+)
+
+// Filter is a JSON filter expression used by /UserMgmt/DirectoryServiceQuery such as
+//
+//	{"_and":[{"SystemName":{"_like":"admin"}},{"ObjectType":"User"}]}
+type Filter map[string]interface{}
+
+// FilterEq matches objects whose field equals value
+func FilterEq(field string, value interface{}) Filter {
+	return Filter{field: value}
+}
+
+// FilterLike matches objects whose field is like value
+func FilterLike(field string, value string) Filter {
+	return Filter{field: map[string]interface{}{"_like": value}}
+}
+
+// FilterAnd matches objects that satisfy all filters
+func FilterAnd(filters ...Filter) Filter {
+	return Filter{"_and": filters}
+}
+
+// FilterOr matches objects that satisfy any of filters
+func FilterOr(filters ...Filter) Filter {
+	return Filter{"_or": filters}
+}
+
+// String returns filter encoded as JSON string
+func (f Filter) String() string {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
@@ -0,0 +1,374 @@
+// Package query builds RedRock SQL queries and directory service filters. Values are always escaped
+// so that names containing quotes or wildcard characters can't change meaning of a query.
+//
+//	q := query.Select("VaultAccount").
+//		Where(query.Eq("User", "o'brien"), query.In("Host", hostA, hostB)).
+//		OrderBy("User", false).
+//		Limit(10)
+//	script, err := q.Build()
+//	// SELECT * FROM VaultAccount WHERE User = 'o''brien' AND Host IN ('...', '...') ORDER BY User ASC LIMIT 10
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// likeEscape is the escape character used in LIKE patterns built by Contains, StartsWith and EndsWith
+const likeEscape = `\`
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Condition is a boolean SQL expression used in WHERE clause
+type Condition interface {
+	build() (string, error)
+}
+
+// Builder builds a single SELECT statement
+type Builder struct {
+	table      string
+	columns    []string
+	conditions []Condition
+	orderBy    []string
+	limit      int
+}
+
+// Select starts a query against table. All columns are selected if none is specified
+func Select(table string, columns ...string) *Builder {
+	return &Builder{table: table, columns: columns}
+}
+
+// Where adds conditions to the query. Conditions are combined with AND including those added by earlier calls
+func (b *Builder) Where(conditions ...Condition) *Builder {
+	b.conditions = append(b.conditions, conditions...)
+	return b
+}
+
+// OrderBy adds sort column
+func (b *Builder) OrderBy(column string, descending bool) *Builder {
+	direction := "ASC"
+	if descending {
+		direction = "DESC"
+	}
+	b.orderBy = append(b.orderBy, column+" "+direction)
+	return b
+}
+
+// Limit limits number of returned rows. 0 means no limit
+func (b *Builder) Limit(n int) *Builder {
+	b.limit = n
+	return b
+}
+
+// Build returns SQL statement
+func (b *Builder) Build() (string, error) {
+	if err := checkIdentifier(b.table); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	if len(b.columns) == 0 {
+		sb.WriteString("*")
+	} else {
+		for _, c := range b.columns {
+			if err := checkIdentifier(c); err != nil {
+				return "", err
+			}
+		}
+		sb.WriteString(strings.Join(b.columns, ", "))
+	}
+	sb.WriteString(" FROM ")
+	sb.WriteString(b.table)
+
+	if len(b.conditions) > 0 {
+		sb.WriteString(" WHERE ")
+		for i, c := range b.conditions {
+			where, err := c.build()
+			if err != nil {
+				return "", err
+			}
+			if i > 0 {
+				sb.WriteString(" AND ")
+			}
+			sb.WriteString(where)
+		}
+	}
+
+	if len(b.orderBy) > 0 {
+		for _, o := range b.orderBy {
+			if err := checkIdentifier(strings.SplitN(o, " ", 2)[0]); err != nil {
+				return "", err
+			}
+		}
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(strings.Join(b.orderBy, ", "))
+	}
+
+	if b.limit > 0 {
+		sb.WriteString(" LIMIT ")
+		sb.WriteString(strconv.Itoa(b.limit))
+	}
+
+	return sb.String(), nil
+}
+
+// String returns SQL statement or error message if the query is invalid
+func (b *Builder) String() string {
+	s, err := b.Build()
+	if err != nil {
+		return err.Error()
+	}
+	return s
+}
+
+// comparison is column <op> value
+type comparison struct {
+	column string
+	op     string
+	value  interface{}
+	escape bool
+}
+
+func (c comparison) build() (string, error) {
+	if err := checkIdentifier(c.column); err != nil {
+		return "", err
+	}
+	if c.value == nil {
+		switch c.op {
+		case "=":
+			return c.column + " IS NULL", nil
+		case "<>":
+			return c.column + " IS NOT NULL", nil
+		}
+	}
+	v, err := Literal(c.value)
+	if err != nil {
+		return "", err
+	}
+	s := c.column + " " + c.op + " " + v
+	if c.escape {
+		s += " ESCAPE '" + likeEscape + "'"
+	}
+	return s, nil
+}
+
+// Eq matches rows whose column equals value. nil value matches NULL
+func Eq(column string, value interface{}) Condition {
+	return comparison{column: column, op: "=", value: value}
+}
+
+// NotEq matches rows whose column doesn't equal value. nil value matches NOT NULL
+func NotEq(column string, value interface{}) Condition {
+	return comparison{column: column, op: "<>", value: value}
+}
+
+// Gt matches rows whose column is greater than value
+func Gt(column string, value interface{}) Condition {
+	return comparison{column: column, op: ">", value: value}
+}
+
+// Ge matches rows whose column is greater than or equal to value
+func Ge(column string, value interface{}) Condition {
+	return comparison{column: column, op: ">=", value: value}
+}
+
+// Lt matches rows whose column is less than value
+func Lt(column string, value interface{}) Condition {
+	return comparison{column: column, op: "<", value: value}
+}
+
+// Le matches rows whose column is less than or equal to value
+func Le(column string, value interface{}) Condition {
+	return comparison{column: column, op: "<=", value: value}
+}
+
+// Like matches rows whose column matches pattern. % and _ in pattern are wildcards
+func Like(column string, pattern string) Condition {
+	return comparison{column: column, op: "LIKE", value: pattern}
+}
+
+// Contains matches rows whose column contains s. Wildcard characters in s are matched literally
+func Contains(column string, s string) Condition {
+	return comparison{column: column, op: "LIKE", value: "%" + EscapeLike(s) + "%", escape: true}
+}
+
+// StartsWith matches rows whose column starts with s. Wildcard characters in s are matched literally
+func StartsWith(column string, s string) Condition {
+	return comparison{column: column, op: "LIKE", value: EscapeLike(s) + "%", escape: true}
+}
+
+// EndsWith matches rows whose column ends with s. Wildcard characters in s are matched literally
+func EndsWith(column string, s string) Condition {
+	return comparison{column: column, op: "LIKE", value: "%" + EscapeLike(s), escape: true}
+}
+
+// in is column IN (values)
+type in struct {
+	column string
+	values []interface{}
+}
+
+func (c in) build() (string, error) {
+	if err := checkIdentifier(c.column); err != nil {
+		return "", err
+	}
+	if len(c.values) == 0 {
+		// Nothing can match empty list
+		return "1=0", nil
+	}
+	var literals []string
+	for _, v := range c.values {
+		l, err := Literal(v)
+		if err != nil {
+			return "", err
+		}
+		literals = append(literals, l)
+	}
+	return c.column + " IN (" + strings.Join(literals, ", ") + ")", nil
+}
+
+// In matches rows whose column equals one of values
+func In(column string, values ...interface{}) Condition {
+	return in{column: column, values: values}
+}
+
+// InStrings is In for string slice
+func InStrings(column string, values []string) Condition {
+	var vs []interface{}
+	for _, v := range values {
+		vs = append(vs, v)
+	}
+	return in{column: column, values: vs}
+}
+
+// inQuery is column IN (SELECT ...)
+type inQuery struct {
+	column string
+	query  *Builder
+}
+
+func (c inQuery) build() (string, error) {
+	if err := checkIdentifier(c.column); err != nil {
+		return "", err
+	}
+	s, err := c.query.Build()
+	if err != nil {
+		return "", err
+	}
+	return c.column + " IN (" + s + ")", nil
+}
+
+// InQuery matches rows whose column equals a value returned by subquery, which must select a single column
+func InQuery(column string, subquery *Builder) Condition {
+	return inQuery{column: column, query: subquery}
+}
+
+// group is conditions combined with AND or OR
+type group struct {
+	op         string
+	conditions []Condition
+}
+
+func (g group) build() (string, error) {
+	var parts []string
+	for _, c := range g.conditions {
+		if c == nil {
+			continue
+		}
+		s, err := c.build()
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, s)
+	}
+	switch len(parts) {
+	case 0:
+		return "1=1", nil
+	case 1:
+		return parts[0], nil
+	}
+	return "(" + strings.Join(parts, " "+g.op+" ") + ")", nil
+}
+
+// And matches rows that satisfy all conditions
+func And(conditions ...Condition) Condition {
+	return group{op: "AND", conditions: conditions}
+}
+
+// Or matches rows that satisfy any of conditions
+func Or(conditions ...Condition) Condition {
+	return group{op: "OR", conditions: conditions}
+}
+
+// not negates condition
+type not struct {
+	condition Condition
+}
+
+func (n not) build() (string, error) {
+	s, err := n.condition.build()
+	if err != nil {
+		return "", err
+	}
+	return "NOT (" + s + ")", nil
+}
+
+// Not matches rows that don't satisfy condition
+func Not(condition Condition) Condition {
+	return not{condition: condition}
+}
+
+// Literal returns value as SQL literal. Strings are quoted with single quote doubled and time is written in UTC as RFC 3339
+func Literal(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return "'" + strings.Replace(v, "'", "''", -1) + "'", nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case time.Time:
+		return Literal(v.UTC().Format(time.RFC3339))
+	case fmt.Stringer:
+		return Literal(v.String())
+	}
+	return "", fmt.Errorf("unsupported query value type %T", value)
+}
+
+// EscapeLike escapes LIKE wildcard characters so that s is matched literally. Use it together with ESCAPE '\'
+func EscapeLike(s string) string {
+	s = strings.Replace(s, likeEscape, likeEscape+likeEscape, -1)
+	s = strings.Replace(s, "%", likeEscape+"%", -1)
+	s = strings.Replace(s, "_", likeEscape+"_", -1)
+	return s
+}
+
+func checkIdentifier(name string) error {
+	if !identifierPattern.MatchString(name) {
+		return fmt.Errorf("invalid identifier '%s' in query", name)
+	}
+	return nil
+}
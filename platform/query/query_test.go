@@ -0,0 +1,162 @@
+package query
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLiteral(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{"plain", "'plain'"},
+		{"o'brien", "'o''brien'"},
+		{"''", "''''''"},
+		{"'; DROP TABLE Server; --", "'''; DROP TABLE Server; --'"},
+		{`back\slash`, `'back\slash'`},
+		{nil, "NULL"},
+		{true, "1"},
+		{false, "0"},
+		{42, "42"},
+		{int64(-7), "-7"},
+		{uint32(7), "7"},
+		{1.5, "1.5"},
+		{time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("", 3600)), "'2020-01-02T02:04:05Z'"},
+	}
+	for _, tt := range tests {
+		got, err := Literal(tt.value)
+		if err != nil {
+			t.Errorf("Literal(%#v): %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Literal(%#v) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+
+	if _, err := Literal(struct{}{}); err == nil {
+		t.Error("expected error for unsupported type")
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"plain", "plain"},
+		{"50%", `50\%`},
+		{"a_b", `a\_b`},
+		{`back\slash`, `back\\slash`},
+		{`\%_`, `\\\%\_`},
+	}
+	for _, tt := range tests {
+		if got := EscapeLike(tt.s); got != tt.want {
+			t.Errorf("EscapeLike(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name string
+		q    *Builder
+		want string
+	}{
+		{
+			"all columns",
+			Select("Server"),
+			"SELECT * FROM Server",
+		},
+		{
+			"quote",
+			Select("VaultAccount", "ID", "User").Where(Eq("User", "o'brien")),
+			"SELECT ID, User FROM VaultAccount WHERE User = 'o''brien'",
+		},
+		{
+			"null",
+			Select("VaultAccount").Where(Eq("Host", nil), NotEq("DomainID", nil)),
+			"SELECT * FROM VaultAccount WHERE Host IS NULL AND DomainID IS NOT NULL",
+		},
+		{
+			"contains wildcards",
+			Select("Server").Where(Contains("Name", `100%_a\b`)),
+			`SELECT * FROM Server WHERE Name LIKE '%100\%\_a\\b%' ESCAPE '\'`,
+		},
+		{
+			"starts and ends with quote",
+			Select("Server").Where(StartsWith("Name", "it's"), EndsWith("Name", "_x")),
+			`SELECT * FROM Server WHERE Name LIKE 'it''s%' ESCAPE '\' AND Name LIKE '%\_x' ESCAPE '\'`,
+		},
+		{
+			"in",
+			Select("Server").Where(In("ID", "a", "b'c")),
+			"SELECT * FROM Server WHERE ID IN ('a', 'b''c')",
+		},
+		{
+			"in empty list",
+			Select("Server").Where(In("ID")),
+			"SELECT * FROM Server WHERE 1=0",
+		},
+		{
+			"in strings empty list",
+			Select("Server").Where(InStrings("ID", nil)),
+			"SELECT * FROM Server WHERE 1=0",
+		},
+		{
+			"in query",
+			Select("VaultAccount").Where(InQuery("ID", Select("CollectionMembers", "Key").Where(Eq("CollectionID", "x")))),
+			"SELECT * FROM VaultAccount WHERE ID IN (SELECT Key FROM CollectionMembers WHERE CollectionID = 'x')",
+		},
+		{
+			"or and not",
+			Select("Server").Where(Or(Eq("A", 1), Not(Eq("B", true))), And()),
+			"SELECT * FROM Server WHERE (A = 1 OR NOT (B = 1)) AND 1=1",
+		},
+		{
+			"order and limit",
+			Select("Server").OrderBy("Name", false).OrderBy("ID", true).Limit(10),
+			"SELECT * FROM Server ORDER BY Name ASC, ID DESC LIMIT 10",
+		},
+		{
+			"compare",
+			Select("Event").Where(Gt("A", 1), Ge("B", 2), Lt("C", 3), Le("D", 4)),
+			"SELECT * FROM Event WHERE A > 1 AND B >= 2 AND C < 3 AND D <= 4",
+		},
+	}
+	for _, tt := range tests {
+		got, err := tt.q.Build()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildInvalidIdentifier(t *testing.T) {
+	tests := []*Builder{
+		Select("Server; DROP TABLE Server"),
+		Select("Server", "Name, Password"),
+		Select("Server").Where(Eq("Name = 'x' OR 1", 1)),
+		Select("Server").Where(In("ID)", "a")),
+		Select("Server").OrderBy("Name; --", false),
+		Select("Server").Where(InQuery("ID", Select("bad table"))),
+		Select("Server").Where(Eq("Name", struct{}{})),
+	}
+	for i, q := range tests {
+		if s, err := q.Build(); err == nil {
+			t.Errorf("%d: expected error, got %s", i, s)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	got := FilterAnd(FilterLike("SystemName", `a"b`), FilterEq("ObjectType", "User")).String()
+	want := `{"_and":[{"SystemName":{"_like":"a\"b"}},{"ObjectType":"User"}]}`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,575 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// PlanAction is what apply does to an object
+type PlanAction string
+
+// Plan actions
+const (
+	PlanCreate PlanAction = "create"
+	PlanUpdate PlanAction = "update"
+	PlanDelete PlanAction = "delete"
+	PlanNoop   PlanAction = "no-op"
+)
+
+// planSymbols are printed in front of each change
+var planSymbols = map[PlanAction]string{
+	PlanCreate: "+",
+	PlanUpdate: "~",
+	PlanDelete: "-",
+	PlanNoop:   " ",
+}
+
+// Dependency order of object types. Lower rank is created first and deleted last
+var reconcileRanks = map[string]int{
+	"PasswordProfile":       0,
+	"AuthenticationProfile": 0,
+	"Connector":             0,
+	"Role":                  1,
+	"User":                  1,
+	"Domain":                2,
+	"CloudProvider":         2,
+	"SecretFolder":          2,
+	"System":                3,
+	"Database":              3,
+	"SSHKey":                3,
+	"Account":               4,
+	"Secret":                4,
+	"MultiplexedAccount":    5,
+	"Service":               6,
+	"ManualSet":             7,
+	"DynamicSet":            7,
+	"DesktopApp":            7,
+	"GenericWebApp":         7,
+	"SamlWebApp":            7,
+	"OauthWebApp":           7,
+	"OidcWebApp":            7,
+	"Policy":                8,
+}
+
+// membershipRank places set membership after every object type
+const membershipRank = 9
+
+// FieldChange is difference of a single schema attribute
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old,omitempty"`
+	New   interface{} `json:"new,omitempty"`
+}
+
+// Change is planned action for one object or set membership
+type Change struct {
+	Kind   string        `json:"kind"`
+	Name   string        `json:"name"`
+	Action PlanAction    `json:"action"`
+	Fields []FieldChange `json:"fields,omitempty"`
+
+	item    *desiredItem
+	current Object // Object read from tenant. nil if it doesn't exist
+	rank    int
+}
+
+// Plan is ordered list of changes computed by Desired.Plan
+type Plan struct {
+	Changes []*Change `json:"changes"`
+}
+
+// desiredItem is an object or set membership in desired state
+type desiredItem struct {
+	object Object
+	absent bool
+	set    *ManualSet // Set of membership item
+	links  []link
+	fields map[string]bool // Managed attributes named by AddFields or AddSchemaMap. nil means non-empty attributes
+}
+
+// link sets reference to parent object once parent ID is known
+type link struct {
+	parent Object
+	set    func(parentID string)
+}
+
+// Desired is desired state of tenant. Objects are matched with tenant by name
+//
+//	d := platform.NewDesired()
+//	d.Add(system)
+//	d.Add(account).Link(account, system, func(id string) { account.Host = id })
+//	d.AddMember(set, system)
+//	plan, err := d.Plan()
+//	fmt.Print(plan)
+//	err = plan.Apply()
+type Desired struct {
+	items []*desiredItem
+	index map[Object]*desiredItem
+}
+
+// NewDesired creates empty desired state
+func NewDesired() *Desired {
+	return &Desired{index: make(map[Object]*desiredItem)}
+}
+
+// Add declares that object must exist with the given attributes. Only attributes with non-empty value are managed and
+// the others are left unchanged. Use AddFields or AddSchemaMap to manage attributes whose desired value is empty or false
+func (d *Desired) Add(o Object) *Desired {
+	if _, ok := d.index[o]; !ok {
+		d.addItem(&desiredItem{object: o})
+	}
+	return d
+}
+
+// AddFields declares that object must exist and that only the named schema attributes are managed. Named attributes
+// are compared and applied even if they have zero value, e.g.
+//
+//	system.AllowRemote = false
+//	d.AddFields(system, "name", "fqdn", "computer_class", "allow_remote_access")
+func (d *Desired) AddFields(o Object, fields ...string) *Desired {
+	item, ok := d.index[o]
+	if !ok {
+		item = d.addItem(&desiredItem{object: o})
+	}
+	if item.fields == nil {
+		item.fields = make(map[string]bool)
+	}
+	for _, f := range fields {
+		item.fields[f] = true
+	}
+	return d
+}
+
+// AddSchemaMap fills object from attributes keyed by schema tags, such as an object of bundle or YAML file, and declares
+// that it must exist with them. Only attributes present in m are managed
+func (d *Desired) AddSchemaMap(o Object, m map[string]interface{}) error {
+	err := fillFromSchemaMap(o, m)
+	if err != nil {
+		return err
+	}
+	fields := []string{}
+	for k := range m {
+		if k != "id" && k != "uuid" && k != setMembersAttribute {
+			fields = append(fields, k)
+		}
+	}
+	d.AddFields(o, fields...)
+	return nil
+}
+
+// Remove declares that object must not exist
+func (d *Desired) Remove(o Object) *Desired {
+	d.addItem(&desiredItem{object: o, absent: true})
+	return d
+}
+
+// Link declares that child refers to parent, such as account to its system. set is called with ID of parent
+// once parent is found or created so that child can be looked up and created
+func (d *Desired) Link(child Object, parent Object, set func(parentID string)) *Desired {
+	item, ok := d.index[child]
+	if !ok {
+		item = d.addItem(&desiredItem{object: child})
+	}
+	item.links = append(item.links, link{parent: parent, set: set})
+	return d
+}
+
+// AddMember declares that objects must be members of set
+func (d *Desired) AddMember(set *ManualSet, members ...Object) *Desired {
+	for _, m := range members {
+		d.items = append(d.items, &desiredItem{object: m, set: set})
+	}
+	return d
+}
+
+func (d *Desired) addItem(item *desiredItem) *desiredItem {
+	d.items = append(d.items, item)
+	d.index[item.object] = item
+	return item
+}
+
+// Plan reads current state of every desired object and computes changes in dependency order.
+// Creates and updates come first with lower ranked types first, followed by deletes in reverse order
+func (d *Desired) Plan() (*Plan, error) {
+	plan := &Plan{}
+	var creates, deletes []*Change
+	for _, item := range d.items {
+		c := &Change{Kind: GetVarType(item.object), Name: item.object.GetName(), item: item}
+		if item.set != nil {
+			c.Kind = "SetMember"
+			c.Name = fmt.Sprintf("%s/%s", item.set.Name, item.object.GetName())
+			c.rank = membershipRank
+		} else {
+			rank, ok := reconcileRanks[c.Kind]
+			if !ok {
+				return nil, fmt.Errorf("%s isn't supported by reconcile", c.Kind)
+			}
+			c.rank = rank
+		}
+		if item.absent {
+			deletes = append(deletes, c)
+		} else {
+			creates = append(creates, c)
+		}
+	}
+	sort.SliceStable(creates, func(i, j int) bool { return creates[i].rank < creates[j].rank })
+	sort.SliceStable(deletes, func(i, j int) bool { return deletes[i].rank > deletes[j].rank })
+	plan.Changes = append(creates, deletes...)
+
+	// Objects that will be created in this plan. Their dependents can't exist yet
+	pending := make(map[Object]bool)
+	for _, c := range plan.Changes {
+		var err error
+		switch {
+		case c.item.set != nil:
+			err = c.planMembership(pending)
+		default:
+			err = c.planObject(pending)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed to plan %s '%s'. %v", c.Kind, c.Name, err)
+		}
+		if c.Action == PlanCreate && c.item.set == nil {
+			pending[c.item.object] = true
+		}
+	}
+
+	return plan, nil
+}
+
+func (c *Change) planObject(pending map[Object]bool) error {
+	o := c.item.object
+	for _, l := range c.item.links {
+		if pending[l.parent] {
+			// Parent doesn't exist yet so neither does child
+			c.Action = PlanCreate
+			return c.diff(nil)
+		}
+		l.set(l.parent.GetID())
+	}
+
+	current, err := readRemote(o)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		if c.item.absent {
+			c.Action = PlanNoop
+			return nil
+		}
+		c.Action = PlanCreate
+		return c.diff(nil)
+	}
+
+	c.current = current
+	o.(idSetter).setID(current.GetID())
+	switch {
+	case c.item.absent:
+		c.Action = PlanDelete
+	default:
+		err = c.diff(current)
+		c.Action = PlanNoop
+		if len(c.Fields) > 0 {
+			c.Action = PlanUpdate
+		}
+	}
+	return err
+}
+
+func (c *Change) diff(current Object) error {
+	want, err := c.item.attributes()
+	if err != nil {
+		return err
+	}
+	c.Fields, err = diffSchema(current, want)
+	return err
+}
+
+func (c *Change) planMembership(pending map[Object]bool) error {
+	if pending[c.item.set] || pending[c.item.object] {
+		c.Action = PlanCreate
+		return nil
+	}
+	if c.item.set.ID == "" || c.item.object.GetID() == "" {
+		return fmt.Errorf("Set and member must be looked up or created before membership")
+	}
+	members, err := getSetMemberIDs(c.item.set.client, c.item.set.ID)
+	if err != nil {
+		return err
+	}
+	c.Action = PlanCreate
+	for _, id := range members {
+		if id == c.item.object.GetID() {
+			c.Action = PlanNoop
+			break
+		}
+	}
+	return nil
+}
+
+// cloneObject returns deep copy of o
+func cloneObject(o Object) Object {
+	v := reflect.ValueOf(o).Elem()
+	n := reflect.New(v.Type())
+	n.Elem().Set(v)
+	deepCopyFields(n.Elem())
+	return n.Interface().(Object)
+}
+
+// deepCopyFields replaces exported slices, maps and pointers of struct v with copies so that reading into v
+// doesn't change the original
+func deepCopyFields(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.Struct:
+			deepCopyFields(f)
+			continue
+		case reflect.Slice, reflect.Map, reflect.Ptr:
+		default:
+			continue
+		}
+		if !f.CanSet() || f.IsNil() {
+			continue
+		}
+		data, err := json.Marshal(f.Interface())
+		if err != nil {
+			continue
+		}
+		c := reflect.New(f.Type())
+		if json.Unmarshal(data, c.Interface()) == nil {
+			f.Set(c.Elem())
+		}
+	}
+}
+
+// attributes returns managed schema attributes of desired object. Attributes named by AddFields are returned even if
+// they have zero value. Otherwise only attributes with non-empty value are returned
+func (item *desiredItem) attributes() (map[string]interface{}, error) {
+	if item.fields == nil {
+		m, err := GenerateSchemaMap(item.object)
+		if err != nil {
+			return nil, err
+		}
+		return nonEmptyAttributes(m), nil
+	}
+
+	all, err := schemaAttributes(item.object)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]interface{})
+	for f := range item.fields {
+		v, ok := all[f]
+		if !ok {
+			return nil, fmt.Errorf("%s has no attribute '%s'", GetVarType(item.object), f)
+		}
+		m[f] = v
+	}
+	return m, nil
+}
+
+// nonEmptyAttributes returns copy of m without attributes whose value is empty, including nested ones
+func nonEmptyAttributes(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	for k, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok {
+			nested = nonEmptyAttributes(nested)
+			if len(nested) > 0 {
+				out[k] = nested
+			}
+			continue
+		}
+		if normalizeDriftValue(v) != nil {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// schemaAttributes returns every top level schema attribute of o, including zero values that GenerateSchemaMap omits.
+// Attributes of embedded structs are overridden by attributes of the same name in outer struct
+func schemaAttributes(o interface{}) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	err := collectSchemaAttributes(reflect.Indirect(reflect.ValueOf(o)), m)
+	return m, err
+}
+
+func collectSchemaAttributes(v reflect.Value, m map[string]interface{}) error {
+	schemaJSON := jsoniter.Config{TagKey: "schema", OnlyTaggedField: true}.Froze()
+	t := v.Type()
+	var embedded []reflect.Value
+	for i := 0; i < v.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("schema")
+		if !ok {
+			if sf.Anonymous && v.Field(i).Kind() == reflect.Struct {
+				embedded = append(embedded, v.Field(i))
+			}
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "" || name == "-" || sf.PkgPath != "" {
+			continue
+		}
+		data, err := schemaJSON.Marshal(v.Field(i).Interface())
+		if err != nil {
+			return err
+		}
+		var value interface{}
+		err = schemaJSON.Unmarshal(data, &value)
+		if err != nil {
+			return err
+		}
+		m[name] = value
+	}
+
+	for _, e := range embedded {
+		inner := make(map[string]interface{})
+		err := collectSchemaAttributes(e, inner)
+		if err != nil {
+			return err
+		}
+		for k, value := range inner {
+			if _, ok := m[k]; !ok {
+				m[k] = value
+			}
+		}
+	}
+	return nil
+}
+
+// diffSchema compares managed attributes in want with current. nil current means all attributes are new.
+// Zero values compare equal to absent ones, the same as drift detection does
+func diffSchema(current Object, want map[string]interface{}) ([]FieldChange, error) {
+	have := map[string]interface{}{}
+	if current != nil {
+		var err error
+		have, err = GenerateSchemaMap(current)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var fields []FieldChange
+	for k, v := range want {
+		if k == "id" || (current != nil && secretAttributes[k]) {
+			continue
+		}
+		if !reflect.DeepEqual(normalizeDriftValue(have[k]), normalizeDriftValue(v)) {
+			fields = append(fields, FieldChange{Field: k, Old: have[k], New: v})
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return fields, nil
+}
+
+// HasChanges returns true if applying plan changes anything
+func (p *Plan) HasChanges() bool {
+	for _, c := range p.Changes {
+		if c.Action != PlanNoop {
+			return true
+		}
+	}
+	return false
+}
+
+// String prints plan as human readable diff. Each changed object is printed on its own line prefixed with
+// "+" for create, "~" for update or "-" for delete, followed by changed attributes as "old => new"
+func (p *Plan) String() string {
+	var buf bytes.Buffer
+	counts := make(map[PlanAction]int)
+	for _, c := range p.Changes {
+		counts[c.Action]++
+		if c.Action == PlanNoop {
+			continue
+		}
+		fmt.Fprintf(&buf, "%s %s %q\n", planSymbols[c.Action], c.Kind, c.Name)
+		for _, f := range c.Fields {
+			if c.Action == PlanCreate {
+				fmt.Fprintf(&buf, "      %s: %s\n", f.Field, planValue(f.New))
+			} else {
+				fmt.Fprintf(&buf, "      %s: %s => %s\n", f.Field, planValue(f.Old), planValue(f.New))
+			}
+		}
+	}
+	if buf.Len() > 0 {
+		buf.WriteString("\n")
+	}
+	fmt.Fprintf(&buf, "Plan: %d to create, %d to update, %d to delete, %d unchanged\n",
+		counts[PlanCreate], counts[PlanUpdate], counts[PlanDelete], counts[PlanNoop])
+	return buf.String()
+}
+
+func planValue(v interface{}) string {
+	if v == nil {
+		return "(none)"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+// Apply executes changes in plan order and stops at first failure
+func (p *Plan) Apply() error {
+	for _, c := range p.Changes {
+		err := c.apply()
+		if err != nil {
+			return fmt.Errorf("Failed to %s %s '%s'. %v", c.Action, c.Kind, c.Name, err)
+		}
+		if c.Action != PlanNoop {
+			logOf(c.item.object).Infof("Applied %s %s '%s'", c.Action, c.Kind, c.Name)
+		}
+	}
+	return nil
+}
+
+func (c *Change) apply() error {
+	o := c.item.object
+	if c.item.set != nil {
+		if c.Action != PlanCreate {
+			return nil
+		}
+		_, err := c.item.set.UpdateSetMembers([]string{o.GetID()}, "add")
+		return err
+	}
+
+	switch c.Action {
+	case PlanCreate:
+		for _, l := range c.item.links {
+			l.set(l.parent.GetID())
+		}
+		if err := o.Validate(); err != nil {
+			return err
+		}
+		_, err := o.Create()
+		return err
+	case PlanUpdate:
+		// Apply managed attributes on top of current state so that the others are kept
+		want, err := c.item.attributes()
+		if err != nil {
+			return err
+		}
+		err = fillFromSchemaMap(c.current, want)
+		if err != nil {
+			return err
+		}
+		if err = c.current.Validate(); err != nil {
+			return err
+		}
+		_, err = c.current.Update()
+		return err
+	case PlanDelete:
+		_, err := c.current.Delete()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package platform
+
+import (
+	"reflect"
+	"testing"
+)
+
+func currentSystem() *System {
+	s := NewSystem(nil)
+	s.ID = "sys1"
+	s.Name = "web01"
+	s.FQDN = "web01.example.com"
+	s.ComputerClass = "Unix"
+	s.Description = "Old"
+	s.ProxyUser = "proxy"
+	s.ProxyUserIsManaged = true
+	s.AllowRemote = true
+	return s
+}
+
+func fieldNames(fields []FieldChange) []string {
+	names := []string{}
+	for _, f := range fields {
+		names = append(names, f.Field)
+	}
+	return names
+}
+
+func TestReconcileKeepsUnsetAttributes(t *testing.T) {
+	desired := NewSystem(nil)
+	desired.Name = "web01"
+	desired.Description = "New"
+	d := NewDesired().Add(desired)
+
+	c := &Change{item: d.items[0], current: currentSystem()}
+	err := c.diff(c.current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fieldNames(c.Fields); !reflect.DeepEqual(got, []string{"description"}) {
+		t.Errorf("changed fields = %v, want [description]", got)
+	}
+
+	want, err := c.item.attributes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fillFromSchemaMap(c.current, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := c.current.(*System)
+	if s.Description != "New" {
+		t.Errorf("description = %q, want New", s.Description)
+	}
+	if s.ProxyUser != "proxy" || !s.ProxyUserIsManaged || !s.AllowRemote || s.FQDN != "web01.example.com" {
+		t.Errorf("attributes that aren't managed were changed: %+v", s)
+	}
+}
+
+func TestReconcileExplicitZeroValue(t *testing.T) {
+	desired := NewSystem(nil)
+	desired.Name = "web01"
+	d := NewDesired().AddFields(desired, "name", "allow_remote_access", "proxyuser_managed")
+
+	c := &Change{item: d.items[0], current: currentSystem()}
+	err := c.diff(c.current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fieldNames(c.Fields); !reflect.DeepEqual(got, []string{"allow_remote_access", "proxyuser_managed"}) {
+		t.Errorf("changed fields = %v, want [allow_remote_access proxyuser_managed]", got)
+	}
+
+	want, err := c.item.attributes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fillFromSchemaMap(c.current, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := c.current.(*System)
+	if s.AllowRemote || s.ProxyUserIsManaged {
+		t.Errorf("explicit false wasn't applied: %+v", s)
+	}
+	if s.ProxyUser != "proxy" || s.Description != "Old" {
+		t.Errorf("attributes that aren't managed were changed: %+v", s)
+	}
+}
+
+func TestReconcileSchemaMap(t *testing.T) {
+	desired := NewSystem(nil)
+	d := NewDesired()
+	err := d.AddSchemaMap(desired, map[string]interface{}{"id": "old", "name": "web01", "allow_remote_access": false})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Change{item: d.items[0], current: currentSystem()}
+	err = c.diff(c.current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fieldNames(c.Fields); !reflect.DeepEqual(got, []string{"allow_remote_access"}) {
+		t.Errorf("changed fields = %v, want [allow_remote_access]", got)
+	}
+}
+
+func TestReconcileUnknownField(t *testing.T) {
+	d := NewDesired().AddFields(NewSystem(nil), "no_such_attribute")
+	if _, err := d.items[0].attributes(); err == nil {
+		t.Error("expected error for unknown attribute")
+	}
+}
+
+func TestSchemaAttributes(t *testing.T) {
+	s := NewDynamicSet(nil)
+	s.Name = "Prod"
+	m, err := schemaAttributes(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"name", "description", "type", "collection_type", "query"} {
+		if _, ok := m[k]; !ok {
+			t.Errorf("missing attribute %s in %v", k, m)
+		}
+	}
+	if m["name"] != "Prod" || m["collection_type"] != "SqlDynamic" {
+		t.Errorf("unexpected attributes %v", m)
+	}
+}
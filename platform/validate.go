@@ -0,0 +1,125 @@
+package platform
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxInt32 is the largest value tenant accepts for numeric attributes
+const maxInt32 = 2147483647
+
+// Violation is a single invalid attribute found by Validate
+type Violation struct {
+	Field   string // Attribute path in schema names such as "challenge_rule.rule[0].authentication_profile_id"
+	Message string
+}
+
+// ValidationError is returned by Validate and contains every violation found
+type ValidationError struct {
+	Object     string
+	Name       string
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Field + ": " + v.Message
+	}
+	return fmt.Sprintf("%s '%s' is invalid. %s", e.Object, e.Name, strings.Join(msgs, "; "))
+}
+
+// validator collects violations so that all of them are reported at once
+type validator struct {
+	violations []Violation
+}
+
+func (v *validator) add(field string, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// check adds violation if ok is false
+func (v *validator) check(ok bool, field string, format string, args ...interface{}) {
+	if !ok {
+		v.add(field, format, args...)
+	}
+}
+
+func (v *validator) required(field string, value string) {
+	if value == "" {
+		v.add(field, "is required")
+	}
+}
+
+// oneOf checks value against valid values ignoring case. Empty value is valid
+func (v *validator) oneOf(field string, value string, values []string) {
+	if value == "" {
+		return
+	}
+	for _, valid := range values {
+		if strings.EqualFold(value, valid) {
+			return
+		}
+	}
+	v.add(field, "'%s' isn't one of %s", value, strings.Join(values, ", "))
+}
+
+// inRange checks numeric attribute. Zero means attribute isn't set so it is valid
+func (v *validator) inRange(field string, value int, min int, max int) {
+	if value == 0 {
+		return
+	}
+	if value < min || value > max {
+		v.add(field, "%d isn't between %d and %d", value, min, max)
+	}
+}
+
+// challengeRules checks each rule has conditions and authentication profile
+func (v *validator) challengeRules(field string, r *ChallengeRules) {
+	if r == nil {
+		return
+	}
+	for i, rule := range r.Rules {
+		path := fmt.Sprintf("%s.rule[%d]", field, i)
+		v.check(len(rule.ChallengeCondition) > 0, path+".rule", "at least one condition is required")
+		v.required(path+".authentication_profile_id", rule.AuthProfileID)
+		for j, c := range rule.ChallengeCondition {
+			cpath := fmt.Sprintf("%s.rule[%d]", path, j)
+			v.required(cpath+".filter", c.Filter)
+			v.required(cpath+".condition", c.Condition)
+		}
+	}
+}
+
+// approvers checks workflow approvers. enabled means workflow is turned on so at least one approver is required
+func (v *validator) approvers(field string, enabled bool, approvers []WorkflowApprover) {
+	if enabled && len(approvers) == 0 {
+		v.add(field, "at least one approver is required when workflow is enabled")
+	}
+	for i, a := range approvers {
+		path := fmt.Sprintf("%s[%d]", field, i)
+		v.oneOf(path+".type", a.Type, []string{"User", "Role", "Manager"})
+		if a.Type == "Manager" {
+			v.oneOf(path+".no_manager_action", a.NoManagerAction, []string{"approve", "deny", "useBackup"})
+			v.check(a.NoManagerAction != "useBackup" || a.BackupApprover != nil, path+".backup_approver", "is required when no_manager_action is useBackup")
+		} else {
+			v.check(a.Guid != "" || a.Name != "", path+".name", "name or guid is required")
+		}
+	}
+}
+
+// passwordSettings checks settings shared by system, database and domain
+func (v *validator) passwordSettings(checkout, rotateInterval, minAge, cleanupDuration int) {
+	v.inRange("checkout_lifetime", checkout, 15, maxInt32)
+	v.inRange("password_rotate_interval", rotateInterval, 1, maxInt32)
+	v.inRange("minimum_password_age", minAge, 0, maxInt32)
+	v.inRange("password_historycleanup_duration", cleanupDuration, 90, maxInt32)
+}
+
+// err returns ValidationError of object o if there is any violation
+func (v *validator) err(o interface{}, name string) error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Object: GetVarType(o), Name: name, Violations: v.violations}
+}
@@ -0,0 +1,522 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/marcozj/golang-sdk/enum/workflowtype"
+	"github.com/marcozj/golang-sdk/restapi"
+)
+
+// States of access request
+const (
+	AccessRequestPending  = "Pending"
+	AccessRequestApproved = "Approved"
+	AccessRequestRejected = "Rejected"
+	AccessRequestCanceled = "Canceled"
+	AccessRequestExpired  = "Expired"
+)
+
+// DefaultAccessRequestPollInterval is how often Wait checks state of request when PollInterval isn't set
+const DefaultAccessRequestPollInterval = 10 * time.Second
+
+// AccessRequest is a request for access that must be approved through workflow, such as account checkout
+type AccessRequest struct {
+	client    *restapi.RestClient
+	apiSubmit string
+	apiRead   string
+	apiCancel string
+	apiDecide string
+
+	ID            string    `json:"ID,omitempty"`
+	Type          string    `json:"WorkflowType,omitempty"` // wf, agentAuthWorkflow, secretsWorkflow, privilegeElevationWorkflow
+	ObjectID      string    `json:"ObjectID,omitempty"`     // ID of account, secret or system
+	ObjectName    string    `json:"ObjectName,omitempty"`
+	Reason        string    `json:"Reason,omitempty"`
+	GrantMin      int       `json:"GrantMin,omitempty"` // Requested duration of access in minutes
+	State         string    `json:"State,omitempty"`    // Pending, Approved, Rejected, Canceled or Expired
+	RequesterID   string    `json:"RequesterID,omitempty"`
+	RequesterName string    `json:"RequesterName,omitempty"`
+	ApproverName  string    `json:"ApproverName,omitempty"`
+	Comment       string    `json:"Comment,omitempty"` // Comment of approver
+	RequestedAt   time.Time `json:"-"`
+	GrantedUntil  time.Time `json:"-"` // Known once request is approved
+
+	PollInterval time.Duration `json:"-"` // How often Wait checks state. Defaults to DefaultAccessRequestPollInterval
+}
+
+// NewAccessRequest is an AccessRequest constructor. wfType is one of workflowtype values
+func NewAccessRequest(c *restapi.RestClient, wfType string) (*AccessRequest, error) {
+	if wfType != workflowtype.AccountWorkflow.String() && wfType != workflowtype.AgentAuthWorkflow.String() &&
+		wfType != workflowtype.SecretsWorkflow.String() && wfType != workflowtype.PrivilegeElevationWorkflow.String() {
+		errormsg := fmt.Sprintf("invalid workflow type %s", wfType)
+		c.Log().Errorf(errormsg)
+		return nil, fmt.Errorf(errormsg)
+	}
+	s := AccessRequest{}
+	s.client = c
+	s.Type = wfType
+	s.apiSubmit = "/JobFlow/StartJob"
+	s.apiRead = "/JobFlow/GetJob"
+	s.apiCancel = "/JobFlow/CancelJob"
+	s.apiDecide = "/JobFlow/UpdateJob"
+
+	return &s, nil
+}
+
+// Submit sends the request to approvers
+func (o *AccessRequest) Submit() error {
+	if o.ObjectID == "" {
+		errormsg := fmt.Sprintf("Missing object ID for %s", GetVarType(o))
+		o.client.Log().Errorf(errormsg)
+		return fmt.Errorf(errormsg)
+	}
+	if o.Reason == "" {
+		return fmt.Errorf("Reason of access request must be provided")
+	}
+	var args = make(map[string]interface{})
+	args["WorkflowType"] = o.Type
+	args["ObjectID"] = o.ObjectID
+	args["Reason"] = o.Reason
+	if o.GrantMin > 0 {
+		args["GrantMin"] = o.GrantMin
+	}
+	var queryArg = make(map[string]interface{})
+	queryArg["jobName"] = "WorkflowRequest"
+	queryArg["args"] = args
+
+	o.client.Log().Debugf("Generated Map for Submit(): %+v", queryArg)
+	resp, err := o.client.CallStringAPI(o.apiSubmit, queryArg)
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return err
+	}
+	if !resp.Success {
+		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
+		o.client.Log().Errorf(errmsg)
+		return fmt.Errorf(errmsg)
+	}
+	o.ID = resp.Result
+	o.State = AccessRequestPending
+	o.RequestedAt = time.Now().UTC()
+
+	return nil
+}
+
+// Read function fetches current state of the request
+func (o *AccessRequest) Read() error {
+	_, err := o.read()
+	return err
+}
+
+// read fetches request from tenant. transient is true if read failed in transport or with server error,
+// which may go away on retry, and false if tenant rejected the call
+func (o *AccessRequest) read() (transient bool, err error) {
+	if o.ID == "" {
+		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(o))
+		o.client.Log().Errorf(errormsg)
+		return false, fmt.Errorf(errormsg)
+	}
+	var queryArg = make(map[string]interface{})
+	queryArg["jobid"] = o.ID
+
+	resp, err := o.client.CallGenericMapAPI(o.apiRead, queryArg)
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		if httpErr, ok := err.(*restapi.HttpError); ok {
+			return httpErr.StatusCode >= 500, err
+		}
+		return true, err
+	}
+	if !resp.Success {
+		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
+		o.client.Log().Errorf(errmsg)
+		return false, fmt.Errorf(errmsg)
+	}
+	fillAccessRequest(o, resp.Result)
+
+	return false, nil
+}
+
+// Cancel withdraws pending request
+func (o *AccessRequest) Cancel() error {
+	if o.ID == "" {
+		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(o))
+		o.client.Log().Errorf(errormsg)
+		return fmt.Errorf(errormsg)
+	}
+	var queryArg = make(map[string]interface{})
+	queryArg["jobid"] = o.ID
+
+	resp, err := o.client.CallBoolAPI(o.apiCancel, queryArg)
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return err
+	}
+	if !resp.Success {
+		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
+		o.client.Log().Errorf(errmsg)
+		return fmt.Errorf(errmsg)
+	}
+	o.State = AccessRequestCanceled
+
+	return nil
+}
+
+// Wait waits until request is approved. Returns error if request is rejected, canceled or expired, or
+// timeout passes first. Zero timeout waits until request is decided
+func (o *AccessRequest) Wait(timeout time.Duration) error {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return o.WaitContext(ctx)
+}
+
+// WaitContext is same as Wait but stops waiting when ctx is done. Reads that fail in transport or with server error
+// are retried at next poll since they are often transient, and the last one is reported if ctx is done first.
+// Error returned by the API, such as unknown request, is returned right away
+func (o *AccessRequest) WaitContext(ctx context.Context) error {
+	if o.ID == "" {
+		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(o))
+		o.client.Log().Errorf(errormsg)
+		return fmt.Errorf(errormsg)
+	}
+	interval := o.PollInterval
+	if interval <= 0 {
+		interval = DefaultAccessRequestPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastErr error
+	for {
+		var transient bool
+		transient, lastErr = o.read()
+		if lastErr != nil {
+			if !transient {
+				return lastErr
+			}
+			o.client.Log().Warnf("Failed to read access request %s, retrying: %v", o.ID, lastErr)
+		} else {
+			switch o.State {
+			case AccessRequestApproved:
+				return nil
+			case AccessRequestRejected, AccessRequestCanceled, AccessRequestExpired:
+				errmsg := fmt.Sprintf("Access request %s is %s", o.ID, o.State)
+				if o.Comment != "" {
+					errmsg = fmt.Sprintf("%s: %s", errmsg, o.Comment)
+				}
+				return fmt.Errorf(errmsg)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("Failed to read access request %s before %v: %v", o.ID, ctx.Err(), lastErr)
+			}
+			return fmt.Errorf("Access request %s is still %s: %v", o.ID, o.State, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// ListPendingAccessRequests returns pending requests that current user can approve, either directly or through
+// one of their roles
+func ListPendingAccessRequests(c *restapi.RestClient) ([]*AccessRequest, error) {
+	var queryArg = make(map[string]interface{})
+	queryArg["State"] = AccessRequestPending
+	queryArg["Args"] = subArgs
+
+	resp, err := c.CallGenericMapAPI("/JobFlow/GetMyApprovals", queryArg)
+	if err != nil {
+		c.Log().Errorf(err.Error())
+		return nil, err
+	}
+	if !resp.Success {
+		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
+		c.Log().Errorf(errmsg)
+		return nil, fmt.Errorf(errmsg)
+	}
+
+	var requests []*AccessRequest
+	results, _ := resp.Result["Results"].([]interface{})
+	for _, v := range results {
+		result, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		row, ok := result["Row"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		wfType, _ := row["WorkflowType"].(string)
+		r, err := NewAccessRequest(c, wfType)
+		if err != nil {
+			// Skip requests of workflow types that SDK doesn't know about
+			continue
+		}
+		fillAccessRequest(r, row)
+		requests = append(requests, r)
+	}
+
+	return requests, nil
+}
+
+// Approve approves the request with comment. duration is how long access is granted and overrides requested
+// duration and WorkflowDefaultOptions.GrantMin of the object. Zero duration keeps requested duration. Access is
+// granted in whole minutes so duration must be at least a minute and is truncated to minutes
+func (o *AccessRequest) Approve(comment string, duration time.Duration) error {
+	return o.decide(true, comment, duration)
+}
+
+// Reject rejects the request with comment, which is shown to requester
+func (o *AccessRequest) Reject(comment string) error {
+	return o.decide(false, comment, 0)
+}
+
+func (o *AccessRequest) decide(approve bool, comment string, duration time.Duration) error {
+	if o.ID == "" {
+		errormsg := fmt.Sprintf("Missing ID for %s", GetVarType(o))
+		o.client.Log().Errorf(errormsg)
+		return fmt.Errorf(errormsg)
+	}
+	if approve && duration != 0 && duration < time.Minute {
+		errormsg := fmt.Sprintf("Grant duration %s is shorter than a minute", duration)
+		o.client.Log().Errorf(errormsg)
+		return fmt.Errorf(errormsg)
+	}
+	var queryArg = make(map[string]interface{})
+	queryArg["jobid"] = o.ID
+	queryArg["Approved"] = approve
+	queryArg["Comment"] = comment
+	if approve && duration > 0 {
+		queryArg["GrantMin"] = int(duration / time.Minute)
+	}
+
+	o.client.Log().Debugf("Generated Map for decide(): %+v", queryArg)
+	resp, err := o.client.CallBoolAPI(o.apiDecide, queryArg)
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return err
+	}
+	if !resp.Success {
+		errmsg := fmt.Sprintf("%s %s", resp.Message, resp.Exception)
+		o.client.Log().Errorf(errmsg)
+		return fmt.Errorf(errmsg)
+	}
+
+	o.Comment = comment
+	if approve {
+		o.State = AccessRequestApproved
+		if duration > 0 {
+			o.GrantMin = int(duration / time.Minute)
+		}
+	} else {
+		o.State = AccessRequestRejected
+	}
+	return nil
+}
+
+// fillAccessRequest fills request with job returned by tenant
+func fillAccessRequest(o *AccessRequest, m map[string]interface{}) {
+	mapToStruct(o, m)
+	o.RequestedAt = parseDate(m["RequestedAt"])
+	o.GrantedUntil = parseDate(m["GrantedUntil"])
+}
+
+// submitAccessRequest creates and submits request for object. Access is requested in whole minutes so non-zero
+// duration must be at least a minute and is truncated to minutes
+func submitAccessRequest(c *restapi.RestClient, wfType string, id string, name string, reason string, duration time.Duration) (*AccessRequest, error) {
+	if duration != 0 && duration < time.Minute {
+		errormsg := fmt.Sprintf("Requested duration %s is shorter than a minute", duration)
+		c.Log().Errorf(errormsg)
+		return nil, fmt.Errorf(errormsg)
+	}
+	r, err := NewAccessRequest(c, wfType)
+	if err != nil {
+		return nil, err
+	}
+	r.ObjectID = id
+	r.ObjectName = name
+	r.Reason = reason
+	r.GrantMin = int(duration / time.Minute)
+	err = r.Submit()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// RequestCheckout requests approval to check out account password for duration
+func (o *Account) RequestCheckout(reason string, duration time.Duration) (*AccessRequest, error) {
+	err := o.resolveCheckoutID()
+	if err != nil {
+		return nil, err
+	}
+	return submitAccessRequest(o.client, workflowtype.AccountWorkflow.String(), o.ID, o.User, reason, duration)
+}
+
+// CheckoutPasswordWithApproval requests approval, waits up to timeout for it and checks out password once approved
+func (o *Account) CheckoutPasswordWithApproval(reason string, duration time.Duration, timeout time.Duration, checkin bool) (string, error) {
+	r, err := o.RequestCheckout(reason, duration)
+	if err != nil {
+		return "", err
+	}
+	err = r.Wait(timeout)
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return "", err
+	}
+	return o.CheckoutPassword(checkin)
+}
+
+// RequestRetrieve requests approval to retrieve secret for duration
+func (o *Secret) RequestRetrieve(reason string, duration time.Duration) (*AccessRequest, error) {
+	if o.ID == "" {
+		_, err := o.GetIDByName()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return submitAccessRequest(o.client, workflowtype.SecretsWorkflow.String(), o.ID, o.SecretName, reason, duration)
+}
+
+// CheckoutSecretWithApproval requests approval, waits up to timeout for it and retrieves secret once approved
+func (o *Secret) CheckoutSecretWithApproval(reason string, duration time.Duration, timeout time.Duration) (string, error) {
+	r, err := o.RequestRetrieve(reason, duration)
+	if err != nil {
+		return "", err
+	}
+	err = r.Wait(timeout)
+	if err != nil {
+		o.client.Log().Errorf(err.Error())
+		return "", err
+	}
+	return o.CheckoutSecret()
+}
+
+// RequestAgentAuth requests approval to log in to system with agent authentication for duration
+func (o *System) RequestAgentAuth(reason string, duration time.Duration) (*AccessRequest, error) {
+	return o.requestAccess(workflowtype.AgentAuthWorkflow.String(), reason, duration)
+}
+
+// RequestPrivilegeElevation requests approval to use privilege elevation on system for duration
+func (o *System) RequestPrivilegeElevation(reason string, duration time.Duration) (*AccessRequest, error) {
+	return o.requestAccess(workflowtype.PrivilegeElevationWorkflow.String(), reason, duration)
+}
+
+func (o *System) requestAccess(wfType string, reason string, duration time.Duration) (*AccessRequest, error) {
+	if o.ID == "" {
+		_, err := o.GetIDByName()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return submitAccessRequest(o.client, wfType, o.ID, o.Name, reason, duration)
+}
+
+/*
+	API to request access through workflow
+
+	Submit request
+
+		Request body format
+		{
+			"jobName": "WorkflowRequest",
+			"args": {
+				"WorkflowType": "wf",
+				"ObjectID": "xxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxx",
+				"Reason": "Patching database",
+				"GrantMin": 60
+			}
+		}
+
+		Respond result
+		{
+			"success": true,
+			"Result": "xxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxx",
+			"Message": null,
+			"Exception": null
+		}
+
+	Get request
+
+		Request body format
+		{
+			"jobid": "xxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxx"
+		}
+
+		Respond result
+		{
+			"success": true,
+			"Result": {
+				"ID": "xxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxx",
+				"WorkflowType": "wf",
+				"ObjectID": "xxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxx",
+				"ObjectName": "root",
+				"Reason": "Patching database",
+				"GrantMin": 60,
+				"State": "Approved",
+				"RequesterID": "xxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxx",
+				"RequesterName": "user@example.com",
+				"ApproverName": "admin@example.com",
+				"Comment": "",
+				"RequestedAt": "/Date(1588075662725)/",
+				"GrantedUntil": "/Date(1588079262725)/"
+			},
+			"Message": null,
+			"Exception": null
+		}
+
+	Cancel request
+
+		Request body format
+		{
+			"jobid": "xxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxx"
+		}
+
+	List pending requests of approver
+
+		Request body format
+		{
+			"State": "Pending",
+			"Args": {...}
+		}
+
+		Respond result
+		{
+			"success": true,
+			"Result": {
+				"Count": 1,
+				"Results": [
+					{
+						"Row": {
+							"ID": "xxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxx",
+							"WorkflowType": "secretsWorkflow",
+							"ObjectID": "xxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxx",
+							"ObjectName": "db-credentials",
+							"Reason": "Incident 1234",
+							"GrantMin": 30,
+							"State": "Pending",
+							"RequesterName": "user@example.com",
+							"RequestedAt": "/Date(1588075662725)/"
+						}
+					}
+				]
+			}
+		}
+
+	Approve or reject request
+
+		Request body format
+		{
+			"jobid": "xxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxx",
+			"Approved": true,
+			"Comment": "Approved for maintenance window",
+			"GrantMin": 30
+		}
+*/
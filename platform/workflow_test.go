@@ -0,0 +1,80 @@
+package platform
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitRetriesTransientErrors(t *testing.T) {
+	replies := []interface{}{
+		testHTTPStatus(503),
+		map[string]interface{}{"ID": "job1", "State": AccessRequestPending},
+		map[string]interface{}{"ID": "job1", "State": AccessRequestApproved},
+	}
+	calls := 0
+	c, srv := newTestClient(t, func(path string, args map[string]interface{}) interface{} {
+		if path != "/JobFlow/GetJob" {
+			t.Errorf("unexpected call to %s", path)
+			return nil
+		}
+		reply := replies[calls]
+		calls++
+		return reply
+	})
+	defer srv.Close()
+
+	r, err := NewAccessRequest(c, "wf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.ID = "job1"
+	r.PollInterval = 10 * time.Millisecond
+	err = r.Wait(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("got %d reads, want 3", calls)
+	}
+}
+
+func TestWaitReturnsAPIError(t *testing.T) {
+	for _, reply := range []interface{}{testAPIError("Job not found"), testHTTPStatus(403)} {
+		calls := 0
+		c, srv := newTestClient(t, func(path string, args map[string]interface{}) interface{} {
+			calls++
+			return reply
+		})
+
+		r, err := NewAccessRequest(c, "wf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.ID = "job1"
+		r.PollInterval = 10 * time.Millisecond
+		err = r.Wait(5 * time.Second)
+		srv.Close()
+		if err == nil {
+			t.Errorf("%v: expected error", reply)
+		}
+		if calls != 1 {
+			t.Errorf("%v: got %d reads, want 1", reply, calls)
+		}
+	}
+}
+
+func TestRequestShorterThanMinute(t *testing.T) {
+	c, srv := newTestClient(t, func(path string, args map[string]interface{}) interface{} {
+		t.Errorf("unexpected call to %s", path)
+		return nil
+	})
+	defer srv.Close()
+
+	a := NewAccount(c)
+	a.ID = "acct1"
+	_, err := a.RequestCheckout("deploy", 30*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "shorter than a minute") {
+		t.Errorf("got %v, want error for duration shorter than a minute", err)
+	}
+}
@@ -0,0 +1,193 @@
+package restapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// readOnlyPrefixes are prefixes of API method names that only read from tenant, such as GetChallenges
+// or CanDeleteAccount. They are sent even in dry-run mode so that objects can be looked up
+var readOnlyPrefixes = []string{"get", "can"}
+
+// readOnlyAPIs are other API methods that only read from tenant
+var readOnlyAPIs = map[string]bool{
+	"/redrock/query":                  true,
+	"/usermgmt/directoryservicequery": true,
+	"/security/startauthentication":   true,
+	"/security/advanceauthentication": true,
+}
+
+// dryRunRedacted are payload attributes that carry credentials. Their values are never kept in captured calls.
+// Names are matched regardless of case
+var dryRunRedacted = map[string]bool{
+	"password":                        true,
+	"newpassword":                     true,
+	"confirmpassword":                 true,
+	"proxyuserpassword":               true,
+	"adminaccountpassword":            true,
+	"/core/passwordreset/adadminpass": true,
+	"privatekey":                      true,
+	"passphrase":                      true,
+	"secrettext":                      true,
+	"secretaccesskey":                 true,
+	"clientsecret":                    true,
+}
+
+// IsMutatingAPI returns true if API method such as "/ServerManage/AddResource" may change tenant. Only known
+// read-only methods are treated as not mutating, everything else is captured in dry-run mode
+func IsMutatingAPI(method string) bool {
+	lower := strings.ToLower(method)
+	if readOnlyAPIs[lower] {
+		return false
+	}
+	name := lower[strings.LastIndex(lower, "/")+1:]
+	for _, prefix := range readOnlyPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// isRedacted returns true if value of payload attribute must not be kept
+func isRedacted(attr string) bool {
+	return dryRunRedacted[strings.ToLower(attr)]
+}
+
+// DryRunCall is a mutating API call captured in dry-run mode
+type DryRunCall struct {
+	Seq      int             `json:"seq"`
+	Time     time.Time       `json:"time"`
+	Method   string          `json:"method"`
+	Payload  json.RawMessage `json:"payload,omitempty"` // Request body with credentials redacted
+	ResultID string          `json:"result_id"`         // Synthetic ID returned to caller in place of the real one
+}
+
+// DryRun captures mutating API calls instead of sending them to tenant and answers them with synthetic success.
+// Read calls are still sent so that objects can be looked up. Set RestClient.DryRun to enable it
+type DryRun struct {
+	mu    sync.Mutex
+	calls []DryRunCall
+}
+
+// NewDryRun creates empty dry-run recorder
+func NewDryRun() *DryRun {
+	return &DryRun{}
+}
+
+// Calls returns captured calls in the order they were made
+func (d *DryRun) Calls() []DryRunCall {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	calls := make([]DryRunCall, len(d.calls))
+	copy(calls, d.calls)
+	return calls
+}
+
+// Reset removes all captured calls
+func (d *DryRun) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.calls = nil
+}
+
+// WriteJSON writes captured calls in JSON format
+func (d *DryRun) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d.Calls())
+}
+
+// WriteChangeLog writes captured calls as human readable change log, one call with its payload per entry
+func (d *DryRun) WriteChangeLog(w io.Writer) error {
+	_, err := io.WriteString(w, d.String())
+	return err
+}
+
+func (d *DryRun) String() string {
+	var buf bytes.Buffer
+	calls := d.Calls()
+	for _, c := range calls {
+		fmt.Fprintf(&buf, "%d. POST %s\n", c.Seq, c.Method)
+		if len(c.Payload) > 0 {
+			var indented bytes.Buffer
+			if json.Indent(&indented, c.Payload, "   ", "  ") == nil {
+				fmt.Fprintf(&buf, "   %s\n", indented.String())
+			}
+		}
+	}
+	fmt.Fprintf(&buf, "Dry run: %d calls captured\n", len(calls))
+	return buf.String()
+}
+
+// capture records call and returns synthetic ID of it
+func (d *DryRun) capture(method string, payload interface{}) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	seq := len(d.calls) + 1
+	call := DryRunCall{
+		Seq:      seq,
+		Time:     time.Now().UTC(),
+		Method:   method,
+		ResultID: fmt.Sprintf("dryrun-%d", seq),
+	}
+	// Keep copy of payload as it is now since caller may change the map after the call
+	if raw, err := json.Marshal(payload); err == nil {
+		var v interface{}
+		if json.Unmarshal(raw, &v) == nil && v != nil {
+			call.Payload, _ = json.Marshal(redactPayload(v))
+		}
+	}
+	d.calls = append(d.calls, call)
+
+	return call.ResultID
+}
+
+func redactPayload(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			if isRedacted(k) {
+				if item != "" && item != nil {
+					v[k] = "********"
+				}
+				continue
+			}
+			v[k] = redactPayload(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactPayload(item)
+		}
+	}
+	return v
+}
+
+// captureDryRun records call if client is in dry-run mode and method changes tenant.
+// Returns synthetic ID to be used as result of the call
+func (r *RestClient) captureDryRun(method string, payload interface{}) (string, bool) {
+	if r.DryRun == nil || !IsMutatingAPI(method) {
+		return "", false
+	}
+	id := r.DryRun.capture(method, payload)
+	r.Log().Info("Dry run: call captured instead of sent", "method", method, "id", id)
+	return id, true
+}
+
+// dryRunResult is synthetic map result. Create APIs return new ID in one of these keys
+func dryRunResult(id string) map[string]interface{} {
+	return map[string]interface{}{"ID": id, "Uuid": id, "_RowKey": id}
+}
+
+func dryRunBase(result interface{}) BaseAPIResponse {
+	raw, _ := json.Marshal(result)
+	return BaseAPIResponse{Success: true, Result: raw}
+}
@@ -0,0 +1,65 @@
+package restapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsRedacted(t *testing.T) {
+	tests := []struct {
+		attr string
+		want bool
+	}{
+		{"Password", true},
+		{"password", true},
+		{"newPassword", true},
+		{"confirmPassword", true},
+		{"ProxyUserPassword", true},
+		{"AdminAccountPassword", true},
+		{"/Core/PasswordReset/ADAdminPass", true},
+		{"PrivateKey", true},
+		{"Passphrase", true},
+		{"SecretText", true},
+		{"SecretAccessKey", true},
+		{"ClientSecret", true},
+		{"clientSecret", true},
+
+		{"_RowKey", false},
+		{"RowKey", false},
+		{"Key", false},
+		{"key", false},
+		{"KeyPairType", false},
+		{"KeyFormat", false},
+		{"AccessKeyId", false},
+		{"PasswordProfileID", false},
+		{"PasswordRotateDuration", false},
+		{"AllowPasswordRotation", false},
+		{"SecretName", false},
+		{"SecretFileName", false},
+		{"SshKeyId", false},
+		{"Name", false},
+	}
+	for _, tt := range tests {
+		if got := isRedacted(tt.attr); got != tt.want {
+			t.Errorf("isRedacted(%q) = %v, want %v", tt.attr, got, tt.want)
+		}
+	}
+}
+
+func TestRedactPayload(t *testing.T) {
+	payload := map[string]interface{}{
+		"SecretName": "db",
+		"SecretText": "hunter2",
+		"Password":   "",
+		"Settings":   []interface{}{map[string]interface{}{"PrivateKey": "-----BEGIN", "KeyPairType": "PrivateKey"}},
+	}
+	want := map[string]interface{}{
+		"SecretName": "db",
+		"SecretText": "********",
+		"Password":   "",
+		"Settings":   []interface{}{map[string]interface{}{"PrivateKey": "********", "KeyPairType": "PrivateKey"}},
+	}
+	if got := redactPayload(payload); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
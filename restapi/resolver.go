@@ -0,0 +1,125 @@
+package restapi
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultResolverTTL is how long resolved IDs are kept when NewResolverCache is given zero TTL
+const DefaultResolverTTL = 5 * time.Minute
+
+// resolverKey identifies an object by type and name. Scope distinguishes objects that share the same name,
+// such as accounts with the same user name on different systems
+type resolverKey struct {
+	kind  string
+	name  string
+	scope string
+}
+
+type resolverEntry struct {
+	id      string
+	expires time.Time
+}
+
+// ResolverCache caches name to ID lookups so that bulk jobs don't query the same object repeatedly.
+// Methods are safe to call on nil cache, which never finds anything
+type ResolverCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[resolverKey]resolverEntry
+	hits    int
+	misses  int
+}
+
+// NewResolverCache creates cache whose entries expire after ttl
+func NewResolverCache(ttl time.Duration) *ResolverCache {
+	if ttl <= 0 {
+		ttl = DefaultResolverTTL
+	}
+	return &ResolverCache{ttl: ttl, entries: make(map[resolverKey]resolverEntry)}
+}
+
+// Get returns cached ID of object
+func (r *ResolverCache) Get(kind, name, scope string) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := resolverKey{kind: kind, name: name, scope: scope}
+	entry, ok := r.entries[key]
+	if ok && time.Now().After(entry.expires) {
+		delete(r.entries, key)
+		ok = false
+	}
+	if !ok {
+		r.misses++
+		return "", false
+	}
+	r.hits++
+	return entry.id, true
+}
+
+// Set caches ID of object
+func (r *ResolverCache) Set(kind, name, scope, id string) {
+	if r == nil || id == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[resolverKey{kind: kind, name: name, scope: scope}] = resolverEntry{id: id, expires: time.Now().Add(r.ttl)}
+}
+
+// Invalidate removes every cached object of the type with the name regardless of scope
+func (r *ResolverCache) Invalidate(kind, name string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for k := range r.entries {
+		if k.kind == kind && k.name == name {
+			delete(r.entries, k)
+		}
+	}
+}
+
+// InvalidateID removes every cached name that resolves to id. It is used when object is renamed or deleted
+func (r *ResolverCache) InvalidateID(id string) {
+	if r == nil || id == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for k, v := range r.entries {
+		if v.id == id {
+			delete(r.entries, k)
+		}
+	}
+}
+
+// Clear removes all cached objects
+func (r *ResolverCache) Clear() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = make(map[resolverKey]resolverEntry)
+}
+
+// Stats returns number of cache hits and misses
+func (r *ResolverCache) Stats() (hits int, misses int) {
+	if r == nil {
+		return 0, 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.hits, r.misses
+}
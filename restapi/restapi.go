@@ -1,6 +1,7 @@
 package restapi
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,7 +11,10 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/marcozj/golang-sdk/audit"
 	logger "github.com/marcozj/golang-sdk/logging"
 )
 
@@ -51,6 +55,35 @@ type SliceResponse struct {
 	Result []interface{}
 }
 
+// ToBase returns the response as BaseAPIResponse with Result encoded as raw json
+func (r *StringResponse) ToBase() *BaseAPIResponse {
+	return toBase(r.BaseAPIResponse, r.Result)
+}
+
+// ToBase returns the response as BaseAPIResponse with Result encoded as raw json
+func (r *BoolResponse) ToBase() *BaseAPIResponse {
+	return toBase(r.BaseAPIResponse, r.Result)
+}
+
+// ToBase returns the response as BaseAPIResponse with Result encoded as raw json
+func (r *GenericMapResponse) ToBase() *BaseAPIResponse {
+	return toBase(r.BaseAPIResponse, r.Result)
+}
+
+// ToBase returns the response as BaseAPIResponse with Result encoded as raw json
+func (r *SliceResponse) ToBase() *BaseAPIResponse {
+	return toBase(r.BaseAPIResponse, r.Result)
+}
+
+func toBase(base BaseAPIResponse, result interface{}) *BaseAPIResponse {
+	// Result of embedded BaseAPIResponse is shadowed during decoding so fill it from typed result
+	raw, err := json.Marshal(result)
+	if err == nil {
+		base.Result = raw
+	}
+	return &base
+}
+
 type HttpError struct {
 	error          // error type
 	StatusCode int // HTTP status
@@ -65,6 +98,15 @@ type RestClient struct {
 	Headers         map[string]string
 	SourceHeader    string
 	ResponseHeaders http.Header
+	Logger          logger.Handler         // Logger of this client. logger.Default() is used if it is nil
+	Audit           audit.Sink             // Optional sink that records credential access made through this client
+	Principal       string                 // User or client this client is authenticated as. Used in audit events
+	Resolver        *ResolverCache         // Optional cache of name to ID lookups shared by all objects using this client
+	DryRun          *DryRun                // Optional. When set, mutating calls are captured instead of sent. See NewDryRun
+	TokenSource     func() (string, error) // Optional. Called before every request for bearer token so that token renewed by its source is always used
+	InvalidateToken func()                 // Optional. Called when token from TokenSource is rejected with 401, after which the request is sent once more
+
+	mu sync.Mutex // Guards ResponseHeaders so that the client can be shared by goroutines
 }
 
 // GetNewRestClient creates a new RestClient for the specified endpoint.  If a factory for creating
@@ -98,10 +140,17 @@ func GetNewRestClient(service string, httpFactory HttpClientFactory) (*RestClien
 }
 
 func (r *RestClient) CallRawAPI(method string, args map[string]interface{}) ([]byte, error) {
+	if id, ok := r.captureDryRun(method, args); ok {
+		return json.Marshal(dryRunBase(dryRunResult(id)))
+	}
 	return r.postAndGetBody(method, args)
 }
 
 func (r *RestClient) CallBaseAPI(method string, args map[string]interface{}) (*BaseAPIResponse, error) {
+	if id, ok := r.captureDryRun(method, args); ok {
+		resp := dryRunBase(dryRunResult(id))
+		return &resp, nil
+	}
 	body, err := r.postAndGetBody(method, args)
 	if err != nil {
 		return nil, err
@@ -110,6 +159,9 @@ func (r *RestClient) CallBaseAPI(method string, args map[string]interface{}) (*B
 }
 
 func (r *RestClient) CallGenericMapAPI(method string, args map[string]interface{}) (*GenericMapResponse, error) {
+	if id, ok := r.captureDryRun(method, args); ok {
+		return &GenericMapResponse{BaseAPIResponse: dryRunBase(nil), Result: dryRunResult(id)}, nil
+	}
 	body, err := r.postAndGetBody(method, args)
 	if err != nil {
 		return nil, err
@@ -118,6 +170,9 @@ func (r *RestClient) CallGenericMapAPI(method string, args map[string]interface{
 }
 
 func (r *RestClient) CallStringAPI(method string, args map[string]interface{}) (*StringResponse, error) {
+	if id, ok := r.captureDryRun(method, args); ok {
+		return &StringResponse{BaseAPIResponse: dryRunBase(nil), Result: id}, nil
+	}
 	body, err := r.postAndGetBody(method, args)
 	if err != nil {
 		return nil, err
@@ -126,6 +181,9 @@ func (r *RestClient) CallStringAPI(method string, args map[string]interface{}) (
 }
 
 func (r *RestClient) CallBoolAPI(method string, args map[string]interface{}) (*BoolResponse, error) {
+	if _, ok := r.captureDryRun(method, args); ok {
+		return &BoolResponse{BaseAPIResponse: dryRunBase(nil), Result: true}, nil
+	}
 	body, err := r.postAndGetBody(method, args)
 	if err != nil {
 		return nil, err
@@ -134,6 +192,9 @@ func (r *RestClient) CallBoolAPI(method string, args map[string]interface{}) (*B
 }
 
 func (r *RestClient) CallSliceAPI(method string, args map[string]interface{}) (*SliceResponse, error) {
+	if id, ok := r.captureDryRun(method, args); ok {
+		return &SliceResponse{BaseAPIResponse: dryRunBase(nil), Result: []interface{}{dryRunResult(id)}}, nil
+	}
 	body, err := r.postAndGetBody(method, args)
 	if err != nil {
 		return nil, err
@@ -144,20 +205,22 @@ func (r *RestClient) CallSliceAPI(method string, args map[string]interface{}) (*
 func (r *RestClient) postAndGetBody(method string, args map[string]interface{}) ([]byte, error) {
 	postreq, err := r.formHttpRequest(method, args)
 	if err != nil {
-		logger.ErrorTracef(err.Error())
+		r.Log().Error("Failed to form request", "method", method, "error", err)
 		return nil, err
 	}
 
-	httpresp, err := r.Client.Do(postreq)
+	httpresp, err := r.do(postreq, func() (*http.Request, error) {
+		return r.formHttpRequest(method, args)
+	})
 	if err != nil {
-		r.ResponseHeaders = nil
-		logger.ErrorTracef(err.Error())
+		r.setResponseHeaders(nil)
+		r.Log().Error("Request failed", "method", method, "error", err)
 		return nil, err
 	}
 	defer httpresp.Body.Close()
 
 	// save response heasder
-	r.ResponseHeaders = httpresp.Header
+	r.setResponseHeaders(httpresp.Header)
 
 	if httpresp.StatusCode == 200 {
 		body, err := ioutil.ReadAll(httpresp.Body)
@@ -168,11 +231,71 @@ func (r *RestClient) postAndGetBody(method string, args map[string]interface{})
 	return nil, &HttpError{error: fmt.Errorf("POST to %s failed with code %d, body: %s", method, httpresp.StatusCode, body), StatusCode: httpresp.StatusCode}
 }
 
-// GetLastResponseHeaders returns the response headers from last REST call
+// Log returns logger of this client. It is safe to call on nil client, in which case logger.Default() is returned
+func (r *RestClient) Log() logger.Printer {
+	if r != nil && r.Logger != nil {
+		return logger.Printer{Handler: r.Logger}
+	}
+	return logger.Printer{Handler: logger.Default()}
+}
+
+// RecordAudit sends event to audit sink of this client. Principal, tenant and time are filled in.
+// Failure to record is logged but not returned so that it doesn't break the actual operation
+func (r *RestClient) RecordAudit(e audit.Event) {
+	if r.Audit == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if e.Principal == "" {
+		e.Principal = r.Principal
+	}
+	if e.Tenant == "" {
+		e.Tenant = r.Service
+	}
+	if err := r.Audit.Record(e); err != nil {
+		r.Log().Error("Failed to record audit event", "action", e.Action, "objectid", e.ObjectID, "error", err)
+	}
+}
+
+// PrincipalFromToken returns unique_name or sub claim of a JWT token. Empty string is returned if token can't be decoded
+func PrincipalFromToken(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return ""
+	}
+	var claims struct {
+		UniqueName string `json:"unique_name"`
+		Sub        string `json:"sub"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	if claims.UniqueName != "" {
+		return claims.UniqueName
+	}
+	return claims.Sub
+}
+
+// GetLastResponseHeaders returns the response headers from last REST call. When the client is shared by goroutines,
+// last call is the one that finished last
 func (r *RestClient) GetLastResponseHeaders() http.Header {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	return r.ResponseHeaders
 }
 
+func (r *RestClient) setResponseHeaders(h http.Header) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ResponseHeaders = h
+}
+
 // This function converts a map[string]interface{} into json string
 func payloadFromMap(input map[string]interface{}) string {
 	if input != nil {
@@ -247,6 +370,9 @@ func payloadFromList(input []map[string]interface{}) string {
 
 // CallGenericMapListAPI is currently used by admin right assignment and removal for Role
 func (r *RestClient) CallGenericMapListAPI(method string, args []map[string]interface{}) (*GenericMapResponse, error) {
+	if id, ok := r.captureDryRun(method, args); ok {
+		return &GenericMapResponse{BaseAPIResponse: dryRunBase(nil), Result: dryRunResult(id)}, nil
+	}
 	body, err := r.postAndGetBodyList(method, args)
 	if err != nil {
 		return nil, err
@@ -257,34 +383,44 @@ func (r *RestClient) CallGenericMapListAPI(method string, args []map[string]inte
 func (r *RestClient) postAndGetBodyList(method string, args []map[string]interface{}) ([]byte, error) {
 	service := strings.TrimSuffix(r.Service, "/")
 	method = strings.TrimPrefix(method, "/")
-	postdata := strings.NewReader(payloadFromList(args))
-	logger.Debugf("Post url: %s", service+"/"+method)
-	logger.Debugf("Post json: %+v", postdata)
-	postreq, err := http.NewRequest("POST", service+"/"+method, postdata)
+	payload := payloadFromList(args)
+	r.Log().Debug("Post request", "url", service+"/"+method, "payload", payload)
+	newRequest := func() (*http.Request, error) {
+		postreq, err := http.NewRequest("POST", service+"/"+method, strings.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+
+		postreq.Header.Add("Content-Type", "application/json")
+		postreq.Header.Add("X-CENTRIFY-NATIVE-CLIENT", "Yes")
+		postreq.Header.Add("X-CFY-SRC", r.SourceHeader)
 
+		for k, v := range r.Headers {
+			postreq.Header.Add(k, v)
+		}
+		err = r.setBearerToken(postreq)
+		if err != nil {
+			r.Log().Error("Failed to get token", "method", method, "error", err)
+			return nil, err
+		}
+		return postreq, nil
+	}
+	postreq, err := newRequest()
 	if err != nil {
 		return nil, err
 	}
 
-	postreq.Header.Add("Content-Type", "application/json")
-	postreq.Header.Add("X-CENTRIFY-NATIVE-CLIENT", "Yes")
-	postreq.Header.Add("X-CFY-SRC", r.SourceHeader)
-
-	for k, v := range r.Headers {
-		postreq.Header.Add(k, v)
-	}
-
-	httpresp, err := r.Client.Do(postreq)
+	httpresp, err := r.do(postreq, newRequest)
 	if err != nil {
-		r.ResponseHeaders = nil
-		logger.ErrorTracef(err.Error())
+		r.setResponseHeaders(nil)
+		r.Log().Error("Request failed", "method", method, "error", err)
 		return nil, err
 	}
 
 	defer httpresp.Body.Close()
 
 	// save response heasder
-	r.ResponseHeaders = httpresp.Header
+	r.setResponseHeaders(httpresp.Header)
 
 	if httpresp.StatusCode == 200 {
 		return ioutil.ReadAll(httpresp.Body)
@@ -297,20 +433,22 @@ func (r *RestClient) postAndGetBodyList(method string, args []map[string]interfa
 func (r *RestClient) DownloadFile(method string, args map[string]interface{}, filepath string) error {
 	postreq, err := r.formHttpRequest(method, args)
 	if err != nil {
-		logger.ErrorTracef(err.Error())
+		r.Log().Error("Failed to form request", "method", method, "error", err)
 		return err
 	}
 
-	httpresp, err := r.Client.Do(postreq)
+	httpresp, err := r.do(postreq, func() (*http.Request, error) {
+		return r.formHttpRequest(method, args)
+	})
 	if err != nil {
-		r.ResponseHeaders = nil
-		logger.ErrorTracef(err.Error())
+		r.setResponseHeaders(nil)
+		r.Log().Error("Request failed", "method", method, "error", err)
 		return err
 	}
 	defer httpresp.Body.Close()
 
 	// save response heasder
-	r.ResponseHeaders = httpresp.Header
+	r.setResponseHeaders(httpresp.Header)
 
 	if httpresp.StatusCode == 200 {
 		// Create the file
@@ -333,13 +471,12 @@ func (r *RestClient) DownloadFile(method string, args map[string]interface{}, fi
 func (r *RestClient) formHttpRequest(method string, args map[string]interface{}) (*http.Request, error) {
 	service := strings.TrimSuffix(r.Service, "/")
 	method = strings.TrimPrefix(method, "/")
-	postdata := strings.NewReader(payloadFromMap(args))
-	logger.Debugf("Post url: %s", service+"/"+method)
-	logger.Debugf("Post json: %+v", postdata)
+	payload := payloadFromMap(args)
+	r.Log().Debug("Post request", "url", service+"/"+method, "payload", payload)
+	postdata := strings.NewReader(payload)
 	postreq, err := http.NewRequest("POST", service+"/"+method, postdata)
 
 	if err != nil {
-		logger.ErrorTracef(err.Error())
 		return nil, err
 	}
 
@@ -350,6 +487,41 @@ func (r *RestClient) formHttpRequest(method string, args map[string]interface{})
 	for k, v := range r.Headers {
 		postreq.Header.Add(k, v)
 	}
+	err = r.setBearerToken(postreq)
+	if err != nil {
+		return nil, err
+	}
 
 	return postreq, nil
 }
+
+// do sends request. If token from TokenSource is rejected with 401, it is invalidated and request built by
+// newRequest is sent once more with a new token
+func (r *RestClient) do(req *http.Request, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	resp, err := r.Client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || r.TokenSource == nil || r.InvalidateToken == nil {
+		return resp, err
+	}
+	resp.Body.Close()
+	r.Log().Debug("Token is rejected, retrying with new token", "url", req.URL.String())
+	r.InvalidateToken()
+
+	req, err = newRequest()
+	if err != nil {
+		return nil, err
+	}
+	return r.Client.Do(req)
+}
+
+// setBearerToken replaces Authorization header with token from TokenSource
+func (r *RestClient) setBearerToken(req *http.Request) error {
+	if r.TokenSource == nil {
+		return nil
+	}
+	token, err := r.TokenSource()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
@@ -0,0 +1,17 @@
+// +build !windows
+
+package sshagent
+
+// This file is for non Windows platform
+import (
+	"fmt"
+	"net"
+)
+
+// dial connects to unix socket of ssh-agent
+func dial(socket string) (net.Conn, error) {
+	if socket == "" {
+		return nil, fmt.Errorf("SSH_AUTH_SOCK isn't set. Start ssh-agent first")
+	}
+	return net.Dial("unix", socket)
+}
@@ -0,0 +1,23 @@
+// +build windows
+
+package sshagent
+
+// This file is for Windows platform
+import (
+	"net"
+
+	// npipe package only works on Windows
+	"gopkg.in/natefinch/npipe.v2"
+)
+
+// defaultNamedPipe is the named pipe OpenSSH for Windows agent listens on
+const defaultNamedPipe = `\\.\pipe\openssh-ssh-agent`
+
+// dial connects to named pipe of ssh-agent. OpenSSH for Windows doesn't set SSH_AUTH_SOCK so its default
+// named pipe is used when socket is empty
+func dial(socket string) (net.Conn, error) {
+	if socket == "" {
+		socket = defaultNamedPipe
+	}
+	return npipe.Dial(socket)
+}
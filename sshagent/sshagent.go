@@ -0,0 +1,348 @@
+// Package sshagent loads SSH keys retrieved from vault straight into ssh-agent so that private key is never
+// written to disk. Account keys are tied to a checkout lease of the account. They are added with a lifetime that
+// matches the lease and removed when it is checked in.
+package sshagent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	logger "github.com/marcozj/golang-sdk/logging"
+	"github.com/marcozj/golang-sdk/platform"
+	"github.com/marcozj/golang-sdk/sshkey"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Options controls how key is held in agent
+type Options struct {
+	Context          context.Context // Optional. Key is removed as soon as context is done
+	Lease            *platform.Lease // Optional. Key is removed when lease is closed
+	Lifetime         time.Duration   // How long agent keeps the key. Defaults to and is capped at time left of lease, otherwise defaults to checkout lifetime
+	Comment          string          // Comment shown by ssh-add -l. Defaults to comment of key
+	ConfirmBeforeUse bool            // Ask agent to confirm every use of the key
+}
+
+// Agent is connection to ssh-agent or an in-process keyring
+type Agent struct {
+	agent agent.Agent
+	conn  io.Closer
+
+	mu   sync.Mutex
+	keys map[*Key]bool
+}
+
+// Connect connects to ssh-agent listening on SSH_AUTH_SOCK. On Windows, named pipe of OpenSSH agent is used
+// if SSH_AUTH_SOCK isn't set
+func Connect() (*Agent, error) {
+	return ConnectTo(os.Getenv("SSH_AUTH_SOCK"))
+}
+
+// ConnectTo connects to ssh-agent listening on socket, which is a named pipe on Windows
+func ConnectTo(socket string) (*Agent, error) {
+	conn, err := dial(socket)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to ssh-agent %s: %v", socket, err)
+	}
+
+	return &Agent{agent: agent.NewClient(conn), conn: conn, keys: make(map[*Key]bool)}, nil
+}
+
+// NewKeyring returns agent that keeps keys in memory of current process. Use it with Signers or Agent when
+// there is no ssh-agent, e.g. for golang.org/x/crypto/ssh client in the same process
+func NewKeyring() *Agent {
+	return &Agent{agent: agent.NewKeyring(), keys: make(map[*Key]bool)}
+}
+
+// New connects to ssh-agent. In-process keyring is returned if SSH_AUTH_SOCK isn't set and there is no agent
+func New() (*Agent, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		conn, err := dial(socket)
+		if err != nil {
+			return NewKeyring(), nil
+		}
+		return &Agent{agent: agent.NewClient(conn), conn: conn, keys: make(map[*Key]bool)}, nil
+	}
+	return ConnectTo(socket)
+}
+
+// Agent returns underlying agent, e.g. for agent.ForwardToAgent
+func (a *Agent) Agent() agent.Agent {
+	return a.agent
+}
+
+// Signers returns signers of every key in agent, e.g. for ssh.PublicKeysCallback
+func (a *Agent) Signers() ([]ssh.Signer, error) {
+	return a.agent.Signers()
+}
+
+// InProcess returns true if agent is in-process keyring
+func (a *Agent) InProcess() bool {
+	return a.conn == nil
+}
+
+// Add loads private key into agent. Lifetime of opts defaults to and is capped at time left of lease if there is one,
+// otherwise it defaults to platform.DefaultCheckoutLifetime
+func (a *Agent) Add(k *sshkey.KeyPair, opts Options) (*Key, error) {
+	log := logger.Printer{Handler: logger.Default()}
+	if opts.Lease != nil {
+		log = opts.Lease.Account().Log()
+	}
+	return a.add(k, opts, false, log)
+}
+
+// add loads private key into agent. Key that owns lease checks it in when it is removed. log is logger of the client
+// that key is retrieved with
+func (a *Agent) add(k *sshkey.KeyPair, opts Options, ownsLease bool, log logger.Printer) (*Key, error) {
+	if opts.Lease != nil {
+		if opts.Lease.Closed() {
+			return nil, fmt.Errorf("Lease of account %s is closed", opts.Lease.Account().User)
+		}
+		// Key must not outlive checkout since password may be rotated once it is checked in
+		left := time.Until(opts.Lease.Expires())
+		if left <= 0 {
+			return nil, fmt.Errorf("Lease of account %s has expired", opts.Lease.Account().User)
+		}
+		if opts.Lifetime <= 0 || opts.Lifetime > left {
+			opts.Lifetime = left
+		}
+	}
+	if opts.Lifetime <= 0 {
+		opts.Lifetime = platform.DefaultCheckoutLifetime
+	}
+	if opts.Comment == "" {
+		opts.Comment = k.Comment
+	}
+	pub, err := k.PublicKey()
+	if err != nil {
+		log.Errorf(err.Error())
+		return nil, err
+	}
+
+	err = a.agent.Add(agent.AddedKey{
+		PrivateKey:       k.PrivateKey,
+		Comment:          opts.Comment,
+		LifetimeSecs:     uint32((opts.Lifetime + time.Second - 1) / time.Second),
+		ConfirmBeforeUse: opts.ConfirmBeforeUse,
+	})
+	if err != nil {
+		log.Errorf(err.Error())
+		return nil, fmt.Errorf("Failed to add key to ssh-agent: %v", err)
+	}
+
+	key := &Key{
+		PublicKey: pub,
+		Comment:   opts.Comment,
+		agent:     a,
+		lease:     opts.Lease,
+		ownsLease: ownsLease,
+		log:       log,
+		expires:   time.Now().Add(opts.Lifetime),
+		done:      make(chan struct{}),
+	}
+	a.mu.Lock()
+	a.keys[key] = true
+	a.mu.Unlock()
+	if opts.Lease != nil {
+		opts.Lease.OnClose(func() { key.Remove() })
+	}
+	if opts.Context != nil {
+		go key.watch(opts.Context)
+	}
+	log.Debugf("Added key %s to ssh-agent until %s", ssh.FingerprintSHA256(pub), key.expires)
+
+	return key, nil
+}
+
+// AddAccountKey retrieves SSH key of account and loads it into agent. Passphrase unlocks the key if it is
+// encrypted. Key is tied to Lease of opts, which must be lease of acct. If there is none, account is checked out
+// with Lifetime of opts, which defaults to checkout lifetime of account, and removing the key checks it in
+func (a *Agent) AddAccountKey(acct *platform.Account, passphrase string, opts Options) (*Key, error) {
+	ownsLease := opts.Lease == nil
+	if ownsLease {
+		lease, err := acct.CheckoutLease(platform.LeaseOptions{Lifetime: opts.Lifetime})
+		if err != nil {
+			return nil, err
+		}
+		opts.Lease = lease
+	} else if acct.ID != "" && opts.Lease.Account().ID != acct.ID {
+		return nil, fmt.Errorf("Lease is for account %s, not %s", opts.Lease.Account().User, acct.User)
+	}
+
+	k, err := acct.RetrieveKeyPair(passphrase)
+	if err != nil {
+		a.releaseLease(opts.Lease, ownsLease)
+		return nil, err
+	}
+	if opts.Comment == "" {
+		opts.Comment = accountComment(acct)
+	}
+	key, err := a.add(k, opts, ownsLease, acct.Log())
+	if err != nil {
+		a.releaseLease(opts.Lease, ownsLease)
+		return nil, err
+	}
+	return key, nil
+}
+
+// releaseLease checks in lease that AddAccountKey checked out when key can't be added
+func (a *Agent) releaseLease(lease *platform.Lease, owned bool) {
+	if !owned {
+		return
+	}
+	if err := lease.Close(); err != nil {
+		lease.Account().Log().Errorf(err.Error())
+	}
+}
+
+// AddSSHKey retrieves private key of SSHKey and loads it into agent. Passphrase of SSHKey unlocks the key if it
+// is encrypted
+func (a *Agent) AddSSHKey(obj *platform.SSHKey, opts Options) (*Key, error) {
+	k, err := obj.RetrieveKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Comment == "" {
+		opts.Comment = obj.Name
+	}
+
+	return a.add(k, opts, false, obj.Log())
+}
+
+// Keys returns keys loaded through this Agent that aren't removed yet
+func (a *Agent) Keys() []*Key {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var keys []*Key
+	for k := range a.keys {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// RemoveAll removes every key loaded through this Agent. Keys added by others are left in ssh-agent
+func (a *Agent) RemoveAll() error {
+	var msgs []string
+	for _, k := range a.Keys() {
+		if err := k.Remove(); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("%s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// Close closes connection to ssh-agent. Loaded keys stay in ssh-agent until their lifetime ends, call RemoveAll
+// first to check them in
+func (a *Agent) Close() error {
+	if a.conn == nil {
+		return nil
+	}
+	return a.conn.Close()
+}
+
+// Key is a key loaded into agent. Remove deletes it and checks in the lease that AddAccountKey checked out for it
+type Key struct {
+	PublicKey ssh.PublicKey
+	Comment   string
+
+	agent     *Agent
+	lease     *platform.Lease
+	ownsLease bool
+	log       logger.Printer
+	mu        sync.Mutex
+	expires   time.Time
+	removed   bool
+	err       error
+	done      chan struct{}
+}
+
+// Fingerprint returns SHA256 fingerprint of the key
+func (k *Key) Fingerprint() string {
+	return ssh.FingerprintSHA256(k.PublicKey)
+}
+
+// Expires returns time when agent drops the key
+func (k *Key) Expires() time.Time {
+	return k.expires
+}
+
+// Done returns channel that is closed when key is removed
+func (k *Key) Done() <-chan struct{} {
+	return k.done
+}
+
+// Lease returns lease that key is tied to, nil if there is none
+func (k *Key) Lease() *platform.Lease {
+	return k.lease
+}
+
+// Remove deletes key from agent. It is safe to call Remove more than once, later calls return result of the first one.
+// Key that agent has already dropped because its lifetime ended isn't an error. Lease checked out by AddAccountKey is
+// checked in afterwards. If that fails, lease stays in its registry so that CloseAll can try again
+func (k *Key) Remove() error {
+	first, err := k.remove()
+	if !first || !k.ownsLease {
+		return err
+	}
+
+	// Lease is closed without lock of key since closing it calls Remove again
+	if cerr := k.lease.Close(); cerr != nil {
+		k.log.Errorf(cerr.Error())
+		k.mu.Lock()
+		if k.err == nil {
+			k.err = cerr
+		}
+		err = k.err
+		k.mu.Unlock()
+	}
+	return err
+}
+
+// remove deletes key from agent. first is false if key was already removed
+func (k *Key) remove() (first bool, err error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.removed {
+		return false, k.err
+	}
+	k.removed = true
+	close(k.done)
+	k.agent.mu.Lock()
+	delete(k.agent.keys, k)
+	k.agent.mu.Unlock()
+
+	err = k.agent.agent.Remove(k.PublicKey)
+	if err != nil && time.Now().Before(k.expires) {
+		k.log.Errorf(err.Error())
+		k.err = fmt.Errorf("Failed to remove key %s from ssh-agent: %v", k.Fingerprint(), err)
+	}
+	return true, k.err
+}
+
+// watch removes key when context is done
+func (k *Key) watch(ctx context.Context) {
+	select {
+	case <-k.done:
+	case <-ctx.Done():
+		k.Remove()
+	}
+}
+
+// accountComment returns user@resource of account so that the key can be recognized in ssh-add -l
+func accountComment(acct *platform.Account) string {
+	if acct.ResourceName != "" {
+		return acct.User + "@" + acct.ResourceName
+	}
+	return acct.User
+}
@@ -0,0 +1,62 @@
+package sshagent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/marcozj/golang-sdk/platform"
+	"github.com/marcozj/golang-sdk/restapi"
+	"github.com/marcozj/golang-sdk/sshkey"
+)
+
+func TestLifetimeCappedToLease(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result interface{} = true
+		if r.URL.Path == "/ServerManage/CheckoutPassword" {
+			result = map[string]interface{}{"COID": "co1", "Password": "secret"}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "Result": result})
+	}))
+	defer srv.Close()
+	c, err := restapi.GetNewRestClient(srv.URL, func() *http.Client { return srv.Client() })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acct := platform.NewAccount(c)
+	acct.ID = "acct1"
+	acct.DefaultCheckoutTime = 10
+	lease, err := acct.CheckoutLease(platform.LeaseOptions{Registry: platform.NewLeaseRegistry()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Close()
+	k, err := sshkey.Generate(sshkey.Options{Type: sshkey.Ed25519})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewKeyring()
+	for _, tt := range []struct {
+		lifetime time.Duration
+		want     time.Time
+	}{
+		{0, lease.Expires()},
+		{2 * time.Hour, lease.Expires()},
+		{5 * time.Minute, time.Now().Add(5 * time.Minute)},
+	} {
+		key, err := a.Add(k, Options{Lease: lease, Lifetime: tt.lifetime})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := key.Expires().Sub(tt.want); d < -time.Second || d > time.Second {
+			t.Errorf("lifetime %v: key expires at %v, want %v", tt.lifetime, key.Expires(), tt.want)
+		}
+		if err := key.Remove(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package sshkey
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	opensshMagic  = "openssh-key-v1\x00"
+	opensshCipher = "aes256-ctr"
+	opensshKDF    = "bcrypt"
+	opensshRounds = 16 // Default of ssh-keygen
+)
+
+// marshalOpenSSH writes key in openssh-key-v1 format. Encrypted key uses aes256-ctr with bcrypt KDF like ssh-keygen
+func (k *KeyPair) marshalOpenSSH(passphrase string) ([]byte, error) {
+	pub, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var check uint32
+	if err := binary.Read(rand.Reader, binary.BigEndian, &check); err != nil {
+		return nil, err
+	}
+	priv := struct {
+		Check1  uint32
+		Check2  uint32
+		Keytype string
+	}{check, check, pub.Type()}
+	block := ssh.Marshal(priv)
+
+	switch key := k.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		block = append(block, ssh.Marshal(struct {
+			N    *big.Int
+			E    *big.Int
+			D    *big.Int
+			Iqmp *big.Int
+			P    *big.Int
+			Q    *big.Int
+		}{key.N, big.NewInt(int64(key.E)), key.D, rsaIqmp(key), key.Primes[0], key.Primes[1]})...)
+	case *ecdsa.PrivateKey:
+		block = append(block, ssh.Marshal(struct {
+			Curve string
+			Pub   []byte
+			D     *big.Int
+		}{curveName(key.Curve), elliptic.Marshal(key.Curve, key.X, key.Y), key.D})...)
+	case ed25519.PrivateKey:
+		block = append(block, ssh.Marshal(struct {
+			Pub  []byte
+			Priv []byte
+		}{key.Public().(ed25519.PublicKey), key})...)
+	default:
+		return nil, fmt.Errorf("Unsupported private key type %T", k.PrivateKey)
+	}
+	block = append(block, ssh.Marshal(struct{ Comment string }{k.Comment})...)
+
+	cipherName, kdfName, kdfOpts, blockSize := "none", "none", "", 8
+	if passphrase != "" {
+		cipherName, kdfName, blockSize = opensshCipher, opensshKDF, aes.BlockSize
+	}
+	for i := 1; len(block)%blockSize != 0; i++ {
+		block = append(block, byte(i))
+	}
+
+	if passphrase != "" {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		kdfOpts = string(ssh.Marshal(struct {
+			Salt   string
+			Rounds uint32
+		}{string(salt), opensshRounds}))
+		key, err := bcryptPBKDF([]byte(passphrase), salt, opensshRounds, 32+aes.BlockSize)
+		if err != nil {
+			return nil, err
+		}
+		c, err := aes.NewCipher(key[:32])
+		if err != nil {
+			return nil, err
+		}
+		cipher.NewCTR(c, key[32:]).XORKeyStream(block, block)
+	}
+
+	w := struct {
+		CipherName   string
+		KdfName      string
+		KdfOpts      string
+		NumKeys      uint32
+		PubKey       []byte
+		PrivKeyBlock []byte
+	}{cipherName, kdfName, kdfOpts, 1, pub.Marshal(), block}
+	data := append([]byte(opensshMagic), ssh.Marshal(w)...)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: data}), nil
+}
+
+// parseOpenSSH reads openssh-key-v1 key, which is the body of OPENSSH PRIVATE KEY PEM block. Unlike
+// ssh.ParseRawPrivateKey, comment of the key is kept. Encrypted key must use aes256-ctr or aes256-cbc with bcrypt KDF
+func parseOpenSSH(data []byte, passphrase string) (*KeyPair, error) {
+	if !bytes.HasPrefix(data, []byte(opensshMagic)) {
+		return nil, fmt.Errorf("Invalid OpenSSH private key")
+	}
+	var w struct {
+		CipherName   string
+		KdfName      string
+		KdfOpts      string
+		NumKeys      uint32
+		PubKey       []byte
+		PrivKeyBlock []byte
+	}
+	if err := ssh.Unmarshal(data[len(opensshMagic):], &w); err != nil {
+		return nil, fmt.Errorf("Invalid OpenSSH private key: %v", err)
+	}
+	if w.NumKeys != 1 {
+		return nil, fmt.Errorf("OpenSSH private key file with %d keys isn't supported", w.NumKeys)
+	}
+
+	block := w.PrivKeyBlock
+	encrypted := w.CipherName != "none"
+	if encrypted {
+		if passphrase == "" {
+			return nil, fmt.Errorf("Private key is encrypted but no passphrase is provided")
+		}
+		var err error
+		block, err = opensshDecrypt(w.CipherName, w.KdfName, w.KdfOpts, passphrase, block)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var priv struct {
+		Check1  uint32
+		Check2  uint32
+		Keytype string
+		Rest    []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(block, &priv); err != nil || priv.Check1 != priv.Check2 {
+		if encrypted {
+			return nil, fmt.Errorf("Wrong passphrase or corrupted OpenSSH private key")
+		}
+		return nil, fmt.Errorf("Corrupted OpenSSH private key")
+	}
+
+	var raw interface{}
+	var comment string
+	var pad []byte
+	switch priv.Keytype {
+	case ssh.KeyAlgoRSA:
+		var k struct {
+			N       *big.Int
+			E       *big.Int
+			D       *big.Int
+			Iqmp    *big.Int
+			P       *big.Int
+			Q       *big.Int
+			Comment string
+			Pad     []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(priv.Rest, &k); err != nil {
+			return nil, err
+		}
+		key := &rsa.PrivateKey{PublicKey: rsa.PublicKey{N: k.N, E: int(k.E.Int64())}, D: k.D, Primes: []*big.Int{k.P, k.Q}}
+		if err := key.Validate(); err != nil {
+			return nil, err
+		}
+		key.Precompute()
+		raw, comment, pad = key, k.Comment, k.Pad
+	case ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521:
+		var k struct {
+			Curve   string
+			Pub     []byte
+			D       *big.Int
+			Comment string
+			Pad     []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(priv.Rest, &k); err != nil {
+			return nil, err
+		}
+		curve, err := curveByName(k.Curve)
+		if err != nil {
+			return nil, err
+		}
+		x, y := elliptic.Unmarshal(curve, k.Pub)
+		if x == nil || k.D.Cmp(curve.Params().N) >= 0 {
+			return nil, fmt.Errorf("Invalid ECDSA key in OpenSSH private key")
+		}
+		if dx, dy := curve.ScalarBaseMult(k.D.Bytes()); dx.Cmp(x) != 0 || dy.Cmp(y) != 0 {
+			return nil, fmt.Errorf("OpenSSH private key doesn't match public key")
+		}
+		raw = &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y}, D: k.D}
+		comment, pad = k.Comment, k.Pad
+	case ssh.KeyAlgoED25519:
+		var k struct {
+			Pub     []byte
+			Priv    []byte
+			Comment string
+			Pad     []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(priv.Rest, &k); err != nil {
+			return nil, err
+		}
+		if len(k.Priv) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("Invalid Ed25519 key in OpenSSH private key")
+		}
+		key := ed25519.NewKeyFromSeed(k.Priv[:ed25519.SeedSize])
+		if !bytes.Equal(key, k.Priv) {
+			return nil, fmt.Errorf("OpenSSH private key doesn't match public key")
+		}
+		raw, comment, pad = key, k.Comment, k.Pad
+	default:
+		return nil, fmt.Errorf("Unsupported OpenSSH key type %s", priv.Keytype)
+	}
+	for i, b := range pad {
+		if int(b) != i+1 {
+			return nil, fmt.Errorf("Invalid padding of OpenSSH private key")
+		}
+	}
+
+	kp, err := newKeyPair(raw, comment)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := kp.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(pub.Marshal(), w.PubKey) {
+		return nil, fmt.Errorf("OpenSSH private key doesn't match public key")
+	}
+	return kp, nil
+}
+
+// opensshDecrypt decrypts private key block of openssh-key-v1 key
+func opensshDecrypt(cipherName, kdfName, kdfOpts, passphrase string, block []byte) ([]byte, error) {
+	if kdfName != opensshKDF {
+		return nil, fmt.Errorf("Unsupported OpenSSH key derivation %q", kdfName)
+	}
+	var opts struct {
+		Salt   string
+		Rounds uint32
+	}
+	if err := ssh.Unmarshal([]byte(kdfOpts), &opts); err != nil {
+		return nil, fmt.Errorf("Invalid OpenSSH key derivation options: %v", err)
+	}
+	key, err := bcryptPBKDF([]byte(passphrase), []byte(opts.Salt), int(opts.Rounds), 32+aes.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+	c, err := aes.NewCipher(key[:32])
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, len(block))
+	switch cipherName {
+	case opensshCipher:
+		cipher.NewCTR(c, key[32:]).XORKeyStream(out, block)
+	case "aes256-cbc":
+		if len(block)%aes.BlockSize != 0 {
+			return nil, fmt.Errorf("Invalid length of encrypted OpenSSH private key")
+		}
+		cipher.NewCBCDecrypter(c, key[32:]).CryptBlocks(out, block)
+	default:
+		return nil, fmt.Errorf("Unsupported OpenSSH cipher %q. It must be %s or aes256-cbc", cipherName, opensshCipher)
+	}
+	return out, nil
+}
+
+// curveByName returns NIST curve of SSH name
+func curveByName(name string) (elliptic.Curve, error) {
+	switch name {
+	case "nistp256":
+		return elliptic.P256(), nil
+	case "nistp384":
+		return elliptic.P384(), nil
+	case "nistp521":
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("Unsupported elliptic curve %s", name)
+	}
+}
+
+// curveName returns SSH name of NIST curve
+func curveName(curve elliptic.Curve) string {
+	switch curve.Params().BitSize {
+	case 384:
+		return "nistp384"
+	case 521:
+		return "nistp521"
+	default:
+		return "nistp256"
+	}
+}
@@ -0,0 +1,339 @@
+package sshkey
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	ppkHeader    = "PuTTY-User-Key-File-"
+	ppkCipher    = "aes256-cbc"
+	ppkMACKey    = "putty-private-key-file-mac-key"
+	ppkLineWidth = 64
+)
+
+// marshalPPK writes key in PuTTY private key file version 2, which every PuTTY release and tools such as WinSCP read
+func (k *KeyPair) marshalPPK(passphrase string) ([]byte, error) {
+	pub, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var priv []byte
+	switch key := k.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		priv = ssh.Marshal(struct {
+			D    *big.Int
+			P    *big.Int
+			Q    *big.Int
+			Iqmp *big.Int
+		}{key.D, key.Primes[0], key.Primes[1], rsaIqmp(key)})
+	case *ecdsa.PrivateKey:
+		priv = ssh.Marshal(struct{ D *big.Int }{key.D})
+	case ed25519.PrivateKey:
+		priv = ssh.Marshal(struct{ Seed []byte }{key.Seed()})
+	default:
+		return nil, fmt.Errorf("Unsupported private key type %T", k.PrivateKey)
+	}
+
+	encryption := "none"
+	if passphrase != "" {
+		encryption = ppkCipher
+		// Pad with leading bytes of SHA-1 of private blob, the same as PuTTY does
+		if pad := aes.BlockSize - len(priv)%aes.BlockSize; pad < aes.BlockSize {
+			sum := sha1.Sum(priv)
+			priv = append(priv, sum[:pad]...)
+		}
+	}
+
+	mac := hmac.New(sha1.New, ppkMACKeyV2(passphrase))
+	mac.Write(ppkMACData(pub.Type(), encryption, k.Comment, pub.Marshal(), priv))
+
+	if passphrase != "" {
+		c, err := aes.NewCipher(ppkCipherKeyV2(passphrase))
+		if err != nil {
+			return nil, err
+		}
+		cipher.NewCBCEncrypter(c, make([]byte, aes.BlockSize)).CryptBlocks(priv, priv)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s2: %s\n", ppkHeader, pub.Type())
+	fmt.Fprintf(&buf, "Encryption: %s\n", encryption)
+	fmt.Fprintf(&buf, "Comment: %s\n", k.Comment)
+	writePPKLines(&buf, "Public-Lines", pub.Marshal())
+	writePPKLines(&buf, "Private-Lines", priv)
+	fmt.Fprintf(&buf, "Private-MAC: %s\n", hex.EncodeToString(mac.Sum(nil)))
+
+	return buf.Bytes(), nil
+}
+
+func writePPKLines(buf *bytes.Buffer, name string, data []byte) {
+	s := base64.StdEncoding.EncodeToString(data)
+	var lines []string
+	for len(s) > ppkLineWidth {
+		lines = append(lines, s[:ppkLineWidth])
+		s = s[ppkLineWidth:]
+	}
+	lines = append(lines, s)
+	fmt.Fprintf(buf, "%s: %d\n", name, len(lines))
+	for _, l := range lines {
+		buf.WriteString(l + "\n")
+	}
+}
+
+// parsePPK reads PuTTY private key file version 2 or 3. Version 3 key must be derived with Argon2id or Argon2i
+func parsePPK(data []byte, passphrase string) (*KeyPair, error) {
+	var version, algo string
+	var public, private []byte
+	headers := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("Invalid PPK line %q", line)
+		}
+		name, value := line[:i], strings.TrimPrefix(line[i+1:], " ")
+		switch {
+		case strings.HasPrefix(name, ppkHeader):
+			version, algo = strings.TrimPrefix(name, ppkHeader), value
+		case name == "Public-Lines" || name == "Private-Lines":
+			blob, err := readPPKLines(scanner, value)
+			if err != nil {
+				return nil, err
+			}
+			if name == "Public-Lines" {
+				public = blob
+			} else {
+				private = blob
+			}
+		default:
+			headers[name] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if version != "2" && version != "3" {
+		return nil, fmt.Errorf("Unsupported PPK version %q", version)
+	}
+	if public == nil || private == nil {
+		return nil, fmt.Errorf("PPK file doesn't contain key")
+	}
+
+	encryption, comment := headers["Encryption"], headers["Comment"]
+	var macKey []byte
+	var newMAC func() hash.Hash
+	switch encryption {
+	case "none":
+	case ppkCipher:
+		if passphrase == "" {
+			return nil, fmt.Errorf("Private key is encrypted but no passphrase is provided")
+		}
+		if len(private)%aes.BlockSize != 0 {
+			return nil, fmt.Errorf("Invalid length of encrypted PPK private key")
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported PPK encryption %q", encryption)
+	}
+
+	if version == "2" {
+		newMAC, macKey = sha1.New, ppkMACKeyV2("")
+		if encryption != "none" {
+			macKey = ppkMACKeyV2(passphrase)
+			c, err := aes.NewCipher(ppkCipherKeyV2(passphrase))
+			if err != nil {
+				return nil, err
+			}
+			cipher.NewCBCDecrypter(c, make([]byte, aes.BlockSize)).CryptBlocks(private, private)
+		}
+	} else {
+		newMAC = sha256.New
+		if encryption != "none" {
+			key, err := ppkArgon2(headers, passphrase)
+			if err != nil {
+				return nil, err
+			}
+			macKey = key[48:]
+			c, err := aes.NewCipher(key[:32])
+			if err != nil {
+				return nil, err
+			}
+			cipher.NewCBCDecrypter(c, key[32:48]).CryptBlocks(private, private)
+		}
+	}
+
+	mac := hmac.New(newMAC, macKey)
+	mac.Write(ppkMACData(algo, encryption, comment, public, private))
+	want, err := hex.DecodeString(headers["Private-MAC"])
+	if err != nil || !hmac.Equal(mac.Sum(nil), want) {
+		if encryption != "none" {
+			return nil, fmt.Errorf("Wrong passphrase or corrupted PPK file")
+		}
+		return nil, fmt.Errorf("PPK file MAC doesn't match")
+	}
+
+	return ppkPrivateKey(algo, public, private, comment)
+}
+
+func readPPKLines(scanner *bufio.Scanner, count string) ([]byte, error) {
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("Invalid PPK line count %q", count)
+	}
+	var s string
+	for i := 0; i < n; i++ {
+		if !scanner.Scan() {
+			return nil, fmt.Errorf("PPK file is truncated")
+		}
+		s += strings.TrimSpace(scanner.Text())
+	}
+	return base64.StdEncoding.DecodeString(s)
+}
+
+// ppkPrivateKey combines public and decrypted private blob of PPK file into a key pair
+func ppkPrivateKey(algo string, public, private []byte, comment string) (*KeyPair, error) {
+	pub, err := ssh.ParsePublicKey(public)
+	if err != nil {
+		return nil, err
+	}
+	if pub.Type() != algo {
+		return nil, fmt.Errorf("PPK key type %s doesn't match public key type %s", algo, pub.Type())
+	}
+	cryptoPub := pub.(ssh.CryptoPublicKey).CryptoPublicKey()
+
+	switch p := cryptoPub.(type) {
+	case *rsa.PublicKey:
+		var w struct {
+			D    *big.Int
+			P    *big.Int
+			Q    *big.Int
+			Iqmp *big.Int
+			Rest []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(private, &w); err != nil {
+			return nil, err
+		}
+		key := &rsa.PrivateKey{PublicKey: *p, D: w.D, Primes: []*big.Int{w.P, w.Q}}
+		if err := key.Validate(); err != nil {
+			return nil, err
+		}
+		key.Precompute()
+		return newKeyPair(key, comment)
+	case *ecdsa.PublicKey:
+		var w struct {
+			D    *big.Int
+			Rest []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(private, &w); err != nil {
+			return nil, err
+		}
+		x, y := p.Curve.ScalarBaseMult(w.D.Bytes())
+		if x.Cmp(p.X) != 0 || y.Cmp(p.Y) != 0 {
+			return nil, fmt.Errorf("PPK private key doesn't match public key")
+		}
+		return newKeyPair(&ecdsa.PrivateKey{PublicKey: *p, D: w.D}, comment)
+	case ed25519.PublicKey:
+		var w struct {
+			Seed []byte
+			Rest []byte `ssh:"rest"`
+		}
+		if err := ssh.Unmarshal(private, &w); err != nil {
+			return nil, err
+		}
+		if len(w.Seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("Invalid Ed25519 private key in PPK file")
+		}
+		key := ed25519.NewKeyFromSeed(w.Seed)
+		if !bytes.Equal(key.Public().(ed25519.PublicKey), p) {
+			return nil, fmt.Errorf("PPK private key doesn't match public key")
+		}
+		return newKeyPair(key, comment)
+	default:
+		return nil, fmt.Errorf("Unsupported PPK key type %s", algo)
+	}
+}
+
+// ppkMACData returns data that Private-MAC covers
+func ppkMACData(algo, encryption, comment string, public, private []byte) []byte {
+	return ssh.Marshal(struct {
+		Algo       string
+		Encryption string
+		Comment    string
+		Public     []byte
+		Private    []byte
+	}{algo, encryption, comment, public, private})
+}
+
+func ppkCipherKeyV2(passphrase string) []byte {
+	var key []byte
+	for _, seq := range []byte{0, 1} {
+		h := sha1.New()
+		h.Write([]byte{0, 0, 0, seq})
+		h.Write([]byte(passphrase))
+		key = h.Sum(key)
+	}
+	return key[:32]
+}
+
+func ppkMACKeyV2(passphrase string) []byte {
+	sum := sha1.Sum([]byte(ppkMACKey + passphrase))
+	return sum[:]
+}
+
+// ppkArgon2 derives cipher key, IV and MAC key of PPK version 3
+func ppkArgon2(headers map[string]string, passphrase string) ([]byte, error) {
+	var params [3]uint64
+	for i, name := range []string{"Argon2-Memory", "Argon2-Passes", "Argon2-Parallelism"} {
+		v, err := strconv.ParseUint(headers[name], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid PPK %s %q", name, headers[name])
+		}
+		params[i] = v
+	}
+	if params[2] == 0 || params[2] > 255 {
+		return nil, fmt.Errorf("Invalid PPK Argon2-Parallelism %d", params[2])
+	}
+	salt, err := hex.DecodeString(headers["Argon2-Salt"])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid PPK Argon2-Salt")
+	}
+
+	memory, passes, threads := uint32(params[0]), uint32(params[1]), uint8(params[2])
+	switch headers["Key-Derivation"] {
+	case "Argon2id":
+		return argon2.IDKey([]byte(passphrase), salt, passes, memory, threads, 80), nil
+	case "Argon2i":
+		return argon2.Key([]byte(passphrase), salt, passes, memory, threads, 80), nil
+	default:
+		return nil, fmt.Errorf("Unsupported PPK key derivation %q", headers["Key-Derivation"])
+	}
+}
+
+// rsaIqmp returns inverse of q mod p
+func rsaIqmp(key *rsa.PrivateKey) *big.Int {
+	return new(big.Int).ModInverse(key.Primes[1], key.Primes[0])
+}
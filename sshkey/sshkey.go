@@ -0,0 +1,256 @@
+// Package sshkey generates SSH key pairs and converts private keys between PEM, OpenSSH and PuTTY PPK formats.
+// Everything is done locally so no call to tenant is needed.
+package sshkey
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/marcozj/golang-sdk/enum/keypairtype"
+	"golang.org/x/crypto/ssh"
+)
+
+// Algorithm is type of key pair
+type Algorithm string
+
+// Supported key algorithms
+const (
+	RSA     Algorithm = "RSA"
+	ECDSA   Algorithm = "ECDSA"
+	Ed25519 Algorithm = "Ed25519"
+)
+
+// Format is encoding of private key
+type Format string
+
+// Supported private key formats
+const (
+	PEM     Format = "PEM"     // PKCS#1 for RSA and SEC 1 for ECDSA. Ed25519 and encrypted key are written in OpenSSH format
+	OpenSSH Format = "OpenSSH" // openssh-key-v1 format written by ssh-keygen
+	PPK     Format = "PPK"     // PuTTY private key file version 2
+)
+
+// Options controls key generation
+type Options struct {
+	Type    Algorithm // RSA, ECDSA or Ed25519. Defaults to RSA
+	Bits    int       // Key size. Defaults to 2048 for RSA and 256 for ECDSA. Ignored for Ed25519
+	Comment string    // Comment of public key and PPK file
+}
+
+// KeyPair is a private key together with its comment
+type KeyPair struct {
+	PrivateKey crypto.Signer // *rsa.PrivateKey, *ecdsa.PrivateKey or ed25519.PrivateKey
+	Comment    string
+}
+
+// Generate creates a new key pair
+func Generate(opts Options) (*KeyPair, error) {
+	var key crypto.Signer
+	var err error
+	switch opts.Type {
+	case RSA, "":
+		bits := opts.Bits
+		if bits == 0 {
+			bits = 2048
+		}
+		if bits < 2048 {
+			return nil, fmt.Errorf("RSA key must be at least 2048 bits")
+		}
+		key, err = rsa.GenerateKey(rand.Reader, bits)
+	case ECDSA:
+		var curve elliptic.Curve
+		switch opts.Bits {
+		case 256, 0:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("ECDSA key must be 256, 384 or 521 bits")
+		}
+		key, err = ecdsa.GenerateKey(curve, rand.Reader)
+	case Ed25519:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("Unsupported key type %s. It must be RSA, ECDSA or Ed25519", opts.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyPair{PrivateKey: key, Comment: opts.Comment}, nil
+}
+
+// Parse reads private key in PEM, OpenSSH or PPK format. Passphrase is only used if the key is encrypted. Comment is
+// kept for OpenSSH and PPK formats, PEM has none
+func Parse(data []byte, passphrase string) (*KeyPair, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(ppkHeader)) {
+		return parsePPK(data, passphrase)
+	}
+	if block, _ := pem.Decode(data); block != nil && block.Type == "OPENSSH PRIVATE KEY" {
+		return parseOpenSSH(block.Bytes, passphrase)
+	}
+
+	raw, err := ssh.ParseRawPrivateKey(data)
+	if _, ok := err.(*ssh.PassphraseMissingError); ok {
+		if passphrase == "" {
+			return nil, fmt.Errorf("Private key is encrypted but no passphrase is provided")
+		}
+		raw, err = ssh.ParseRawPrivateKeyWithPassphrase(data, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse private key: %v", err)
+	}
+
+	return newKeyPair(raw, "")
+}
+
+// newKeyPair normalizes key returned by x509 and ssh packages
+func newKeyPair(raw interface{}, comment string) (*KeyPair, error) {
+	switch k := raw.(type) {
+	case *rsa.PrivateKey:
+		if len(k.Primes) != 2 {
+			return nil, fmt.Errorf("Multi-prime RSA key isn't supported")
+		}
+		return &KeyPair{PrivateKey: k, Comment: comment}, nil
+	case *ecdsa.PrivateKey:
+		return &KeyPair{PrivateKey: k, Comment: comment}, nil
+	case ed25519.PrivateKey:
+		return &KeyPair{PrivateKey: k, Comment: comment}, nil
+	case *ed25519.PrivateKey:
+		return &KeyPair{PrivateKey: *k, Comment: comment}, nil
+	default:
+		return nil, fmt.Errorf("Unsupported private key type %T", raw)
+	}
+}
+
+// Type returns algorithm of key pair
+func (k *KeyPair) Type() Algorithm {
+	switch k.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		return RSA
+	case *ecdsa.PrivateKey:
+		return ECDSA
+	default:
+		return Ed25519
+	}
+}
+
+// Bits returns key size
+func (k *KeyPair) Bits() int {
+	switch key := k.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		return key.N.BitLen()
+	case *ecdsa.PrivateKey:
+		return key.Curve.Params().BitSize
+	default:
+		return 256
+	}
+}
+
+// PublicKey returns public key in SSH format
+func (k *KeyPair) PublicKey() (ssh.PublicKey, error) {
+	return ssh.NewPublicKey(k.PrivateKey.Public())
+}
+
+// AuthorizedKey returns public key as a line of authorized_keys file, followed by comment if there is one
+func (k *KeyPair) AuthorizedKey() (string, error) {
+	pub, err := k.PublicKey()
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+	if k.Comment != "" {
+		line += " " + k.Comment
+	}
+	return line, nil
+}
+
+// Fingerprint returns SHA256 fingerprint of public key, as printed by ssh-keygen -l
+func (k *KeyPair) Fingerprint() (string, error) {
+	pub, err := k.PublicKey()
+	if err != nil {
+		return "", err
+	}
+	return ssh.FingerprintSHA256(pub), nil
+}
+
+// Marshal encodes private key in format. Key is encrypted if passphrase isn't empty
+func (k *KeyPair) Marshal(format Format, passphrase string) ([]byte, error) {
+	switch format {
+	case PEM, "":
+		return k.marshalPEM(passphrase)
+	case OpenSSH:
+		return k.marshalOpenSSH(passphrase)
+	case PPK:
+		return k.marshalPPK(passphrase)
+	default:
+		return nil, fmt.Errorf("Unsupported key format %s. It must be PEM, OpenSSH or PPK", format)
+	}
+}
+
+func (k *KeyPair) marshalPEM(passphrase string) ([]byte, error) {
+	// Legacy PEM encryption uses MD5 to derive key and can't be authenticated. Like ssh-keygen, encrypted key is
+	// written in OpenSSH format instead
+	if passphrase != "" {
+		return k.marshalOpenSSH(passphrase)
+	}
+
+	var block *pem.Block
+	switch key := k.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+	case ed25519.PrivateKey:
+		// Like ssh-keygen, Ed25519 key is always written in OpenSSH format since OpenSSH can't read it from PKCS#8
+		return k.marshalOpenSSH(passphrase)
+	default:
+		return nil, fmt.Errorf("Unsupported private key type %T", k.PrivateKey)
+	}
+
+	return pem.EncodeToMemory(block), nil
+}
+
+// Convert reads private key in any supported format and writes it in format. Passphrase unlocks the key and
+// newPassphrase protects converted key
+func Convert(data []byte, passphrase string, format Format, newPassphrase string) ([]byte, error) {
+	k, err := Parse(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return k.Marshal(format, newPassphrase)
+}
+
+// Export returns the part of key pair that keypairtype selects, the same as SSHKey retrieval does in tenant:
+// authorized_keys line for PublicKey, PEM for PrivateKey and PPK for PuTTY
+func (k *KeyPair) Export(keytype keypairtype.KeyPairType, passphrase string) (string, error) {
+	var data []byte
+	var err error
+	switch keytype {
+	case keypairtype.PublicKey:
+		return k.AuthorizedKey()
+	case keypairtype.PuTTY:
+		data, err = k.Marshal(PPK, passphrase)
+	default:
+		data, err = k.Marshal(PEM, passphrase)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
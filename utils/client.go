@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/marcozj/golang-sdk/audit"
 	"github.com/marcozj/golang-sdk/dmc"
 	"github.com/marcozj/golang-sdk/enum/authenticationtype"
+	logger "github.com/marcozj/golang-sdk/logging"
 	"github.com/marcozj/golang-sdk/oauth"
 	"github.com/marcozj/golang-sdk/restapi"
 	"github.com/marcozj/golang-sdk/webcookie"
@@ -23,12 +25,25 @@ type VaultClient struct {
 	Password string              // Password for user (or OAuth2 client secret if requesting a token)
 	Skipcert bool                // Whether to skip certificate validation
 	Debug    bool
+
+	Logger     logger.Handler         // Logger of the authenticated REST client. logger.Default() is used if it is nil
+	Audit      audit.Sink             // Optional sink that records credential access such as password checkout
+	Resolver   *restapi.ResolverCache // Optional cache of name to ID lookups. See restapi.NewResolverCache
+	DryRun     *restapi.DryRun        // Optional. Capture mutating calls instead of sending them. See restapi.NewDryRun
+	Profile    string                 // Named profile in configuration file. Empty means CENTRIFY_PROFILE or "default"
+	ConfigFile string                 // Configuration file path. Empty means CENTRIFY_CONFIG_FILE or ~/.centrify/config
+	DMCCache   string                 // Optional file to share cached DMC tokens between processes. See dmc.TokenCache
 }
 
 // authenticate authenticates to tenant and save reset client
 func (c *VaultClient) authenticate() error {
+	// Fill in settings that aren't set explicitly from environment, profile, etc.
+	err := c.ResolveSettings()
+	if err != nil {
+		return err
+	}
+
 	var restClient *restapi.RestClient
-	var err error
 	switch strings.ToLower(c.AuthType) {
 	case authenticationtype.OAuth2.String():
 		call := oauth.OauthClient{
@@ -39,6 +54,7 @@ func (c *VaultClient) authenticate() error {
 			ClientID:       c.User,
 			ClientSecret:   c.Password,
 			SkipCertVerify: c.Skipcert,
+			Logger:         c.Logger,
 		}
 		restClient, err = call.GetClient()
 		if err != nil {
@@ -50,6 +66,7 @@ func (c *VaultClient) authenticate() error {
 		call.ClientID = c.User
 		call.ClientSecret = c.Password
 		call.SkipCertVerify = c.Skipcert
+		call.Logger = c.Logger
 
 		restClient, err = call.GetClient()
 		if err != nil {
@@ -61,6 +78,8 @@ func (c *VaultClient) authenticate() error {
 		call.Scope = c.Scope
 		call.Token = c.Token
 		call.SkipCertVerify = c.Skipcert
+		call.CacheFile = c.DMCCache
+		call.Logger = c.Logger
 
 		restClient, err = call.GetClient()
 		if err != nil {
@@ -69,6 +88,9 @@ func (c *VaultClient) authenticate() error {
 	default:
 		return fmt.Errorf("Invalid authentication type: %v", c.AuthType)
 	}
+	restClient.Audit = c.Audit
+	restClient.Resolver = c.Resolver
+	restClient.DryRun = c.DryRun
 	c.client = restClient
 	return nil
 }
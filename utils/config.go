@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// DefaultProfile is the profile name used when none is specified
+	DefaultProfile = "default"
+	// EnvConfigFile overrides location of configuration file
+	EnvConfigFile = "CENTRIFY_CONFIG_FILE"
+	// EnvProfile selects profile in configuration file
+	EnvProfile = "CENTRIFY_PROFILE"
+)
+
+// Profile represents a named set of connection settings stored in configuration file
+type Profile struct {
+	URL      string `json:"url,omitempty"`      // Tenant URL
+	AuthType string `json:"auth,omitempty"`     // Authentication type <oauth|unpw|dmc>
+	AppID    string `json:"appid,omitempty"`    // OAuth2 application id
+	Scope    string `json:"scope,omitempty"`    // OAuth2 or DMC scope definition name
+	User     string `json:"user,omitempty"`     // User or OAuth2 client id
+	Skipcert bool   `json:"skipcert,omitempty"` // Whether to skip certificate validation
+	DMCCache string `json:"dmccache,omitempty"` // File to share cached DMC tokens between processes
+}
+
+// Config represents content of configuration file such as ~/.centrify/config
+//
+//	{
+//		"profiles": {
+//			"default": {
+//				"url": "https://tenant.my.centrify.net",
+//				"auth": "oauth",
+//				"appid": "CentrifyCLI",
+//				"scope": "all",
+//				"user": "client@example.com"
+//			},
+//			"onprem": {
+//				"url": "https://pas.example.com",
+//				"auth": "dmc",
+//				"scope": "cli",
+//				"dmccache": "/home/user/.centrify/dmctokens",
+//				"skipcert": true
+//			}
+//		}
+//	}
+type Config struct {
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// DefaultConfigPath returns configuration file path. CENTRIFY_CONFIG_FILE takes precedence over ~/.centrify/config
+func DefaultConfigPath() string {
+	if path, found := os.LookupEnv(EnvConfigFile); found {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".centrify", "config")
+}
+
+// LoadConfig reads configuration file. Empty path means DefaultConfigPath(). Missing file results in empty config
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{Profiles: make(map[string]Profile)}
+	if path == "" {
+		path = DefaultConfigPath()
+	}
+	if path == "" {
+		return config, nil
+	}
+
+	fileBytes, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading configuration from path '%s': %s", path, err)
+	}
+
+	err = json.Unmarshal(fileBytes, config)
+	if err != nil {
+		return nil, fmt.Errorf("error demarshalling configuration from path '%s': %s", path, err)
+	}
+
+	return config, nil
+}
+
+// GetProfile returns named profile. Empty name means CENTRIFY_PROFILE or "default"
+func (c *Config) GetProfile(name string) (*Profile, error) {
+	if name == "" {
+		name = os.Getenv(EnvProfile)
+	}
+	if name == "" {
+		name = DefaultProfile
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile '%s' not found", name)
+	}
+
+	return &p, nil
+}
@@ -0,0 +1,276 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/marcozj/golang-sdk/dmc"
+	"github.com/marcozj/golang-sdk/enum/authenticationtype"
+	logger "github.com/marcozj/golang-sdk/logging"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Environment variables read by EnvProvider
+const (
+	EnvURL      = "CENTRIFY_URL"
+	EnvAuthType = "CENTRIFY_AUTH"
+	EnvAppID    = "CENTRIFY_APPID"
+	EnvScope    = "CENTRIFY_SCOPE"
+	EnvToken    = "CENTRIFY_TOKEN"
+	EnvUser     = "CENTRIFY_USER"
+	EnvPassword = "CENTRIFY_PASSWORD"
+	EnvSkipcert = "CENTRIFY_SKIPCERT"
+	EnvDMCCache = "CENTRIFY_DMC_CACHE"
+)
+
+// SettingsProvider fills in VaultClient settings that haven't been set yet.
+// A provider must never overwrite a setting that is already set.
+type SettingsProvider interface {
+	Name() string
+	Provide(c *VaultClient) error
+}
+
+// EnvProvider reads settings from CENTRIFY_* environment variables
+type EnvProvider struct{}
+
+// ProfileProvider reads settings from a named profile in configuration file
+type ProfileProvider struct {
+	ConfigFile string // Empty means DefaultConfigPath()
+	Profile    string // Empty means CENTRIFY_PROFILE or "default"
+}
+
+// DMCProvider selects DMC authentication when Centrify Client is installed, no other authentication type is chosen
+// and no OAuth2 or user credential is given
+type DMCProvider struct{}
+
+// PromptProvider interactively prompts for whatever is still missing. It does nothing if stdin isn't a terminal
+type PromptProvider struct{}
+
+// DefaultProviderChain returns providers in the order of explicit settings, environment, profile, DMC and interactive prompt
+func (c *VaultClient) DefaultProviderChain() []SettingsProvider {
+	return []SettingsProvider{
+		&EnvProvider{},
+		&ProfileProvider{ConfigFile: c.ConfigFile, Profile: c.Profile},
+		&DMCProvider{},
+		&PromptProvider{},
+	}
+}
+
+// ResolveSettings completes settings that aren't set explicitly by walking through providers in order.
+// DefaultProviderChain() is used if no provider is given. It stops as soon as settings are complete.
+func (c *VaultClient) ResolveSettings(providers ...SettingsProvider) error {
+	if len(providers) == 0 {
+		providers = c.DefaultProviderChain()
+	}
+	for _, p := range providers {
+		if c.isComplete() {
+			break
+		}
+		err := p.Provide(c)
+		if err != nil {
+			c.log().Errorf("Settings provider %s failed: %v", p.Name(), err)
+			return fmt.Errorf("Settings provider %s failed: %v", p.Name(), err)
+		}
+		c.log().Debugf("Applied settings provider %s", p.Name())
+	}
+	// OAuth2 has always been the default authentication type
+	setIfEmpty(&c.AuthType, authenticationtype.OAuth2.String())
+
+	return c.validate()
+}
+
+// log returns printer of Logger, or of logger.Default() if it is nil
+func (c *VaultClient) log() logger.Printer {
+	if c.Logger != nil {
+		return logger.Printer{Handler: c.Logger}
+	}
+	return logger.Printer{Handler: logger.Default()}
+}
+
+// isComplete checks whether there are enough settings to authenticate
+func (c *VaultClient) isComplete() bool {
+	return c.URL != "" && c.AuthType != "" && c.validate() == nil
+}
+
+// validate checks required settings of each authentication type
+func (c *VaultClient) validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("Tenant URL is not set")
+	}
+	switch strings.ToLower(c.AuthType) {
+	case authenticationtype.OAuth2.String():
+		if c.Token != "" {
+			return nil
+		}
+		if c.AppID == "" || c.Scope == "" {
+			return fmt.Errorf("OAuth2 application ID and scope must be set if token isn't provided")
+		}
+		if c.User == "" || c.Password == "" {
+			return fmt.Errorf("OAuth2 client ID and secret must be set if token isn't provided")
+		}
+	case authenticationtype.UsernamePassword.String():
+		// Password and MFA challenges are answered interactively during authentication
+		if c.User == "" {
+			return fmt.Errorf("User must be set")
+		}
+	case authenticationtype.DelegatedMachineCredential.String():
+		if c.Token == "" && c.Scope == "" {
+			return fmt.Errorf("DMC scope must be set if token isn't provided")
+		}
+	case "":
+		return fmt.Errorf("Authentication type is not set")
+	default:
+		return fmt.Errorf("Invalid authentication type: %v", c.AuthType)
+	}
+
+	return nil
+}
+
+// Name returns provider name
+func (p *EnvProvider) Name() string {
+	return "environment"
+}
+
+// Provide reads settings from environment variables
+func (p *EnvProvider) Provide(c *VaultClient) error {
+	setIfEmpty(&c.URL, os.Getenv(EnvURL))
+	setIfEmpty(&c.AuthType, os.Getenv(EnvAuthType))
+	setIfEmpty(&c.AppID, os.Getenv(EnvAppID))
+	setIfEmpty(&c.Scope, os.Getenv(EnvScope))
+	setIfEmpty(&c.Token, os.Getenv(EnvToken))
+	setIfEmpty(&c.User, os.Getenv(EnvUser))
+	setIfEmpty(&c.Password, os.Getenv(EnvPassword))
+	setIfEmpty(&c.DMCCache, os.Getenv(EnvDMCCache))
+	if v, found := os.LookupEnv(EnvSkipcert); found && !c.Skipcert {
+		skip, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s value '%s'", EnvSkipcert, v)
+		}
+		c.Skipcert = skip
+	}
+
+	return nil
+}
+
+// Name returns provider name
+func (p *ProfileProvider) Name() string {
+	return "profile"
+}
+
+// Provide reads settings from profile. A missing configuration file or default profile isn't an error,
+// but a profile that is explicitly asked for must exist
+func (p *ProfileProvider) Provide(c *VaultClient) error {
+	config, err := LoadConfig(p.ConfigFile)
+	if err != nil {
+		return err
+	}
+	profile, err := config.GetProfile(p.Profile)
+	if err != nil {
+		if p.Profile == "" && os.Getenv(EnvProfile) == "" {
+			return nil
+		}
+		return err
+	}
+
+	setIfEmpty(&c.URL, profile.URL)
+	setIfEmpty(&c.AuthType, profile.AuthType)
+	setIfEmpty(&c.AppID, profile.AppID)
+	setIfEmpty(&c.Scope, profile.Scope)
+	setIfEmpty(&c.User, profile.User)
+	setIfEmpty(&c.DMCCache, profile.DMCCache)
+	if !c.Skipcert {
+		c.Skipcert = profile.Skipcert
+	}
+
+	return nil
+}
+
+// Name returns provider name
+func (p *DMCProvider) Name() string {
+	return "dmc"
+}
+
+// Provide chooses DMC authentication if Centrify Client service is running on this machine. Settings that only
+// make sense for OAuth2 or username/password such as application ID, user or password keep the default
+func (p *DMCProvider) Provide(c *VaultClient) error {
+	if c.AuthType != "" || c.AppID != "" || c.User != "" || c.Password != "" {
+		return nil
+	}
+	if c.Scope != "" && dmc.IsAvailable() {
+		c.AuthType = authenticationtype.DelegatedMachineCredential.String()
+	}
+
+	return nil
+}
+
+// Name returns provider name
+func (p *PromptProvider) Name() string {
+	return "prompt"
+}
+
+// Provide prompts for missing settings
+func (p *PromptProvider) Provide(c *VaultClient) error {
+	if !terminal.IsTerminal(int(syscall.Stdin)) {
+		return nil
+	}
+
+	// Apply default here as well so that the right settings are prompted for
+	setIfEmpty(&c.AuthType, authenticationtype.OAuth2.String())
+
+	reader := bufio.NewReader(os.Stdin)
+	if c.URL == "" {
+		c.URL = promptString(reader, "Enter Tenant URL: ")
+	}
+
+	switch strings.ToLower(c.AuthType) {
+	case authenticationtype.OAuth2.String():
+		if c.Token != "" {
+			return nil
+		}
+		if c.AppID == "" {
+			c.AppID = promptString(reader, "Enter OAuth2 Application ID: ")
+		}
+		if c.Scope == "" {
+			c.Scope = promptString(reader, "Enter OAuth2 Scope: ")
+		}
+		if c.User == "" {
+			c.User = promptString(reader, "Enter User: ")
+		}
+		if c.Password == "" {
+			c.Password = promptPassword("Enter Password: ")
+		}
+	case authenticationtype.UsernamePassword.String():
+		if c.User == "" {
+			c.User = promptString(reader, "Enter User: ")
+		}
+	case authenticationtype.DelegatedMachineCredential.String():
+		if c.Token == "" && c.Scope == "" {
+			c.Scope = promptString(reader, "Enter DMC Scope: ")
+		}
+	}
+
+	return nil
+}
+
+func setIfEmpty(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+func promptString(reader *bufio.Reader, prompt string) string {
+	fmt.Print(prompt)
+	input, _ := reader.ReadString('\n')
+	return strings.TrimSpace(input)
+}
+
+func promptPassword(prompt string) string {
+	fmt.Print(prompt)
+	bytePassword, _ := terminal.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	return strings.TrimSpace(string(bytePassword))
+}
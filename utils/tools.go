@@ -4,20 +4,17 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"strings"
-	"syscall"
-
-	"github.com/marcozj/golang-sdk/enum/authenticationtype"
-	"golang.org/x/crypto/ssh/terminal"
 )
 
 // GetCmdParms parse command line argument
 func (c *VaultClient) GetCmdParms() {
 	// Common arguments
-	authTypePtr := flag.String("auth", "oauth", "Authentication type <oauth|unpw|dmc>")
-	urlPtr := flag.String("url", "", "Centrify tenant URL (Required)")
+	authTypePtr := flag.String("auth", "", "Authentication type <oauth|unpw|dmc>. Defaults to oauth if it isn't set in environment or profile")
+	urlPtr := flag.String("url", "", "Centrify tenant URL (Required unless it is set in environment or profile)")
 	skipCertPtr := flag.Bool("skipcert", false, "Ignore certification verification")
 	debugPtr := flag.Bool("debug", false, "Trun on debug logging")
+	profilePtr := flag.String("profile", "", "Named profile in configuration file ~/.centrify/config")
+	configPtr := flag.String("config", "", "Path of configuration file. Defaults to ~/.centrify/config")
 
 	// Other arguments
 	appIDPtr := flag.String("appid", "", "OAuth2 application ID. Required if auth = oauth")
@@ -25,73 +22,18 @@ func (c *VaultClient) GetCmdParms() {
 	tokenPtr := flag.String("token", "", "OAuth2 or DMC token. Optional if auth = oauth or dmc")
 	usernamePtr := flag.String("user", "", "Authorized user to login to tenant. Required if auth = unpw. Optional if auth = oauth")
 	passwordPtr := flag.String("password", "", "User password. You will be prompted to enter password if this isn't provided")
+	dmcCachePtr := flag.String("dmccache", "", "File to share cached DMC tokens between processes. Optional if auth = dmc")
 
 	prgname := os.Args[0]
 	flag.Usage = func() {
 		fmt.Printf("Usage: %s -auth oauth -url https://<tenant>.my.centrify.net -user user@company.com \n", prgname)
+		fmt.Printf("Usage: %s -profile <profile name>\n", prgname)
 		flag.PrintDefaults()
 	}
 
 	flag.Parse()
 
-	// Verify command argument length
-	if len(os.Args) < 2 {
-		flag.Usage()
-		os.Exit(1)
-	}
-
-	// Verify authTypePtr value
-	authChoices := map[string]bool{"oauth": true, "unpw": true, "dmc": true}
-	if _, validChoice := authChoices[*authTypePtr]; !validChoice {
-		flag.Usage()
-		os.Exit(1)
-	}
-	// Check required argument that do not have default value
-	if *urlPtr == "" {
-		flag.Usage()
-		os.Exit(1)
-	}
-
-	switch strings.ToLower(*authTypePtr) {
-	case authenticationtype.OAuth2.String():
-		if *appIDPtr == "" || *scopePtr == "" {
-			flag.Usage()
-			os.Exit(1)
-		}
-		// Either token or username must be provided
-		if *tokenPtr == "" && *usernamePtr == "" {
-			flag.Usage()
-			os.Exit(1)
-		}
-		// If password isn't provided, prompt for it
-		if *passwordPtr == "" {
-			fmt.Print("Enter Password: ")
-			bytePassword, _ := terminal.ReadPassword(int(syscall.Stdin))
-			password := strings.TrimSpace(string(bytePassword))
-			*passwordPtr = password
-			fmt.Println()
-		}
-	case authenticationtype.UsernamePassword.String():
-		if *urlPtr == "" || *usernamePtr == "" {
-			flag.Usage()
-			os.Exit(1)
-		}
-		// If password isn't provided, prompt for it
-		if *passwordPtr == "" {
-			fmt.Print("Enter Password: ")
-			bytePassword, _ := terminal.ReadPassword(int(syscall.Stdin))
-			password := strings.TrimSpace(string(bytePassword))
-			*passwordPtr = password
-			fmt.Println()
-		}
-	case authenticationtype.DelegatedMachineCredential.String():
-		if *tokenPtr == "" && *scopePtr == "" {
-			flag.Usage()
-			os.Exit(1)
-		}
-	}
-
-	// Assign argument values to struct
+	// Assign explicit argument values to struct
 	c.AuthType = *authTypePtr
 	c.URL = *urlPtr
 	c.AppID = *appIDPtr
@@ -101,4 +43,15 @@ func (c *VaultClient) GetCmdParms() {
 	c.Password = *passwordPtr
 	c.Skipcert = *skipCertPtr
 	c.Debug = *debugPtr
+	c.Profile = *profilePtr
+	c.ConfigFile = *configPtr
+	c.DMCCache = *dmcCachePtr
+
+	// Fill in the rest from environment, profile, DMC or prompt
+	err := c.ResolveSettings()
+	if err != nil {
+		fmt.Println(err)
+		flag.Usage()
+		os.Exit(1)
+	}
 }
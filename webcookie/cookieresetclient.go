@@ -71,5 +71,7 @@ func (c *WebCookie) GetClient() (*restapi.RestClient, error) {
 	}
 
 	restClient.Headers["Authorization"] = "Bearer " + token
+	restClient.Logger = c.Logger
+	restClient.Principal = c.ClientID
 	return restClient, nil
 }